@@ -0,0 +1,84 @@
+// Command airdrop freezes an airdrop snapshot from the command line, the same
+// way POST /api/admin/airdrops does:
+//
+//	airdrop -name "season 1" -units-per-point 1000000000000000000 [-at 2026-01-01T00:00:00Z]
+package main
+
+import (
+	"context"
+	"denet/config"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/service"
+	"denet/internal/store"
+	pg "denet/internal/store/postgresql"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	name := flag.String("name", "", "unique airdrop name")
+	unitsPerPoint := flag.String("units-per-point", "", "token base units paid per point")
+	at := flag.String("at", "", "snapshot time in RFC 3339 (default now)")
+	flag.Parse()
+
+	if *name == "" || *unitsPerPoint == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	req := &model.CreateAirdropRequest{Name: *name, UnitsPerPoint: *unitsPerPoint}
+	if *at != "" {
+		snapshotAt, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid -at:", err)
+			os.Exit(2)
+		}
+		req.SnapshotAt = &snapshotAt
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to init logger:", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+
+	db := pg.NewPostgresDatabase(conf.Database.URL)
+	if err := db.Connect(context.Background()); err != nil {
+		logger.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	isolation, err := store.ParseIsolationLevel(conf.Database.TxIsolation)
+	if err != nil {
+		logger.Fatal("Invalid transaction isolation level", zap.Error(err))
+	}
+	uow := repository.NewPostgresUnitOfWork(db, repository.TxConfig{
+		Isolation:  isolation,
+		MaxRetries: conf.Database.TxMaxRetries,
+		RetryDelay: conf.Database.TxRetryDelay,
+	})
+
+	airdrop, err := service.NewAirdropService(uow).CreateSnapshot(context.Background(), req)
+	if err != nil {
+		logger.Fatal("Failed to create airdrop snapshot", zap.Error(err))
+	}
+	logger.Info("Airdrop snapshot created",
+		zap.String("airdrop_id", airdrop.ID),
+		zap.String("name", airdrop.Name),
+		zap.Time("snapshot_at", airdrop.SnapshotAt),
+		zap.String("merkle_root", airdrop.MerkleRoot),
+		zap.String("total_amount", airdrop.TotalAmount),
+		zap.Int("recipients", airdrop.Recipients),
+	)
+	fmt.Println(airdrop.MerkleRoot)
+}
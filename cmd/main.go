@@ -1,33 +1,75 @@
 package main
 
 import (
+	"context"
 	"denet/config"
 	"denet/internal/app"
-	
-	"log"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"go.uber.org/zap"
 )
 
 func main() {
-	logger, err := zap.NewProduction()
+	os.Exit(run())
+}
 
+// run starts the service and blocks until SIGINT or SIGTERM, returning the
+// process exit code: non-zero if startup, serving or shutdown failed.
+func run() int {
+	logger, err := zap.NewProduction()
 	if err != nil {
-		log.Fatal("failed to init logger:", err)
+		fmt.Fprintln(os.Stderr, "failed to init logger:", err)
+		return 1
 	}
 	defer logger.Sync()
+	// Code running outside a request logs through the global logger.
+	defer zap.ReplaceGlobals(logger)()
 
 	logger.Info("Starting user service", zap.String("Version", "1.0.0"))
 
 	conf, err := config.LoadConfig()
 	if err != nil {
-		return
+		logger.Error("Failed to load config", zap.Error(err))
+		return 1
 	}
 
-	err = app.New(conf, logger)
-
+	application, err := app.New(conf, logger)
 	if err != nil {
-		logger.Fatal("Failed to create app", zap.Error(err))
+		logger.Error("Failed to create app", zap.Error(err))
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := application.Start(); err != nil {
+		logger.Error("Failed to start server", zap.Error(err))
+		application.Shutdown(context.Background())
+		return 1
 	}
 
+	code := 0
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutdown signal received, draining connections",
+			zap.Duration("timeout", conf.Server.ShutdownTimeout),
+		)
+	case err := <-application.Errors():
+		logger.Error("Server stopped unexpectedly", zap.Error(err))
+		code = 1
+	}
+	// A second signal falls back to the default action and kills the process.
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), conf.Server.ShutdownTimeout)
+	defer cancel()
+	if err := application.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Shutdown incomplete", zap.Error(err))
+		return 1
+	}
+	logger.Info("Server stopped")
+	return code
 }
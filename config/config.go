@@ -1,7 +1,7 @@
 package config
 
 import (
-	"errors"
+	"fmt"
 	"time"
 
 	"github.com/caarlos0/env/v11"
@@ -9,14 +9,38 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	JWT         JWTConfig
+	RBAC        RBACConfig
+	Leaderboard LeaderboardConfig
+	Redis       RedisConfig
+	Idempotency IdempotencyConfig
+	Social      SocialConfig
+	Wallet      WalletConfig
+	Health      HealthConfig
+	Tracing     TracingConfig
+	RateLimit   RateLimitConfig
+	Lockout     LockoutConfig
 }
 
 type ServerConfig struct {
-	Port string `env:"PORT" default:"8080"`
-	Host string `env:"HOST" default:"localhost"`
+	Port              string        `env:"PORT" default:"8080"`
+	Host              string        `env:"HOST" default:"localhost"`
+	ReadTimeout       time.Duration `env:"SERVER_READ_TIMEOUT" default:"15s"`
+	ReadHeaderTimeout time.Duration `env:"SERVER_READ_HEADER_TIMEOUT" default:"5s"`
+	WriteTimeout      time.Duration `env:"SERVER_WRITE_TIMEOUT" default:"30s"`
+	IdleTimeout       time.Duration `env:"SERVER_IDLE_TIMEOUT" default:"120s"`
+	// ShutdownTimeout bounds draining in-flight requests and stopping the
+	// workers on SIGTERM. Keep it below the orchestrator's kill grace period.
+	ShutdownTimeout time.Duration `env:"SERVER_SHUTDOWN_TIMEOUT" default:"15s"`
+	// TrustedProxies lists the proxy addresses or CIDRs whose
+	// X-Forwarded-For is believed when telling the client IP. Empty trusts
+	// none, so that clients cannot pick the IP they are rate limited by.
+	TrustedProxies []string `env:"SERVER_TRUSTED_PROXIES"`
+	// InternalPort serves the metrics scrape and the readiness of every
+	// dependency on Host. Keep it unreachable from outside.
+	InternalPort string `env:"INTERNAL_PORT" default:"9090"`
 }
 
 type DatabaseConfig struct {
@@ -25,19 +49,131 @@ type DatabaseConfig struct {
 	MaxOpenConns   int           `env:"DB_MAX_OPEN_CONNS" default:"15"`
 	MaxIdleConns   int           `env:"DB_MAX_IDLE_CONNS" default:"10"`
 	ConnMaxExpired time.Duration `env:"DB_CONN_MAX_EXPIRED" default:"5m"`
+	TxIsolation    string        `env:"DB_TX_ISOLATION" default:"read committed"`
+	TxMaxRetries   int           `env:"DB_TX_MAX_RETRIES" default:"3"`
+	TxRetryDelay   time.Duration `env:"DB_TX_RETRY_DELAY" default:"20ms"`
 }
 
 type JWTConfig struct {
-	SecretKey  string        `env:"JWT_SECRET_KEY" default:"your-super-secret-jwt-key"`
-	ExpireTime time.Duration `env:"JWT_EXPIRE_TIME" default:"24h"`
+	SigningAlgorithm  string        `env:"JWT_SIGNING_ALG" default:"RS256"`
+	KeysDir           string        `env:"JWT_KEYS_DIR" default:"keys"`
+	RotationInterval  time.Duration `env:"JWT_KEY_ROTATION_INTERVAL" default:"720h"`
+	KeyRetention      time.Duration `env:"JWT_KEY_RETENTION" default:"24h"`
+	ExpireTime        time.Duration `env:"JWT_EXPIRE_TIME" default:"15m"`
+	RefreshExpireTime time.Duration `env:"JWT_REFRESH_EXPIRE_TIME" default:"720h"`
+}
+
+type RBACConfig struct {
+	// BootstrapAdminIDs are user IDs granted the admin role at startup.
+	BootstrapAdminIDs []string `env:"RBAC_BOOTSTRAP_ADMIN_IDS"`
+}
+
+type LeaderboardConfig struct {
+	// SeasonArchiveInterval is how often ended seasons are checked for archival.
+	SeasonArchiveInterval time.Duration `env:"SEASON_ARCHIVE_INTERVAL" default:"1m"`
+	// Backend holds the lifetime leaderboard index: "memory" or "redis".
+	Backend     string `env:"LEADERBOARD_BACKEND" default:"memory"`
+	RedisPrefix string `env:"LEADERBOARD_REDIS_PREFIX" default:"leaderboard"`
+	// ResyncInterval is how often the index is rebuilt from the database to
+	// repair any write-through that failed.
+	ResyncInterval time.Duration `env:"LEADERBOARD_RESYNC_INTERVAL" default:"15m"`
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long a stored response is replayed for its key.
+	TTL           time.Duration `env:"IDEMPOTENCY_TTL" default:"24h"`
+	PurgeInterval time.Duration `env:"IDEMPOTENCY_PURGE_INTERVAL" default:"1h"`
+}
+
+// SocialConfig holds the platform credentials social task verifiers use. A
+// platform without credentials falls back to manual review.
+type SocialConfig struct {
+	TelegramBotToken   string `env:"TELEGRAM_BOT_TOKEN"`
+	TelegramAPIURL     string `env:"TELEGRAM_API_URL" default:"https://api.telegram.org"`
+	TwitterBearerToken string `env:"TWITTER_BEARER_TOKEN"`
+	TwitterAPIURL      string `env:"TWITTER_API_URL" default:"https://api.twitter.com"`
+	DiscordBotToken    string `env:"DISCORD_BOT_TOKEN"`
+	DiscordAPIURL      string `env:"DISCORD_API_URL" default:"https://discord.com/api/v10"`
+	// RequestTimeout bounds each call to a platform.
+	RequestTimeout time.Duration `env:"SOCIAL_REQUEST_TIMEOUT" default:"10s"`
+	// CacheTTL is how long a membership is remembered; NegativeCacheTTL the
+	// same for a missing one, kept short so that users who just joined can
+	// resubmit.
+	CacheTTL         time.Duration `env:"SOCIAL_VERIFY_CACHE_TTL" default:"10m"`
+	NegativeCacheTTL time.Duration `env:"SOCIAL_VERIFY_NEGATIVE_CACHE_TTL" default:"30s"`
+	// TelegramAuthMaxAge is how old a Telegram Login Widget payload may be.
+	// The widget must be set up for the bot of TelegramBotToken.
+	TelegramAuthMaxAge time.Duration `env:"TELEGRAM_AUTH_MAX_AGE" default:"24h"`
+}
+
+// WalletConfig holds the Sign-In with Ethereum settings. Domain must match
+// the host the frontend serves the signing page from.
+type WalletConfig struct {
+	Domain string `env:"SIWE_DOMAIN" default:"localhost:8080"`
+	// ChainIDs lists the accepted chains; empty accepts any.
+	ChainIDs []int64       `env:"SIWE_CHAIN_IDS"`
+	NonceTTL time.Duration `env:"SIWE_NONCE_TTL" default:"10m"`
+}
+
+type HealthConfig struct {
+	// CheckTimeout bounds each readiness check; CacheTTL is how long a
+	// readiness report is reused.
+	CheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+	CacheTTL     time.Duration `env:"HEALTH_CACHE_TTL" default:"2s"`
+	// ShutdownDelay is how long readiness fails before connections are
+	// drained, so that load balancers stop sending new requests first. It
+	// counts towards SERVER_SHUTDOWN_TIMEOUT.
+	ShutdownDelay time.Duration `env:"HEALTH_SHUTDOWN_DELAY" default:"5s"`
+}
+
+type TracingConfig struct {
+	// Exporter is "none", "stdout" or "otlp". Incoming traceparent headers
+	// are propagated and logged even with "none".
+	Exporter    string `env:"TRACING_EXPORTER" default:"none"`
+	ServiceName string `env:"TRACING_SERVICE_NAME" default:"denet"`
+	// OTLPEndpoint is the host:port of the OTLP/HTTP collector.
+	OTLPEndpoint string  `env:"TRACING_OTLP_ENDPOINT" default:"localhost:4318"`
+	OTLPInsecure bool    `env:"TRACING_OTLP_INSECURE" default:"false"`
+	SampleRatio  float64 `env:"TRACING_SAMPLE_RATIO" default:"1"`
+}
+
+// RateLimitConfig holds token bucket limits written as "<burst>/<period>",
+// as in "10/1m"; empty or a zero burst disables one.
+type RateLimitConfig struct {
+	// Backend holds the buckets: "memory" or "redis". Use "redis" when
+	// several instances serve the API, as each counts on its own otherwise.
+	Backend     string `env:"RATE_LIMIT_BACKEND" default:"memory"`
+	RedisPrefix string `env:"RATE_LIMIT_REDIS_PREFIX" default:"ratelimit"`
+	// PerIP applies to every request, PerUser to every authenticated one.
+	PerIP   string `env:"RATE_LIMIT_PER_IP" default:"300/1m"`
+	PerUser string `env:"RATE_LIMIT_PER_USER" default:"300/1m"`
+	// Auth applies per IP to each login, registration and refresh route.
+	Auth string `env:"RATE_LIMIT_AUTH" default:"10/1m"`
+}
+
+// LockoutConfig locks an account on the password login after MaxAttempts
+// failed ones, each less than Window after the one before. Locks start at
+// BaseDuration and double with every further one up to MaxDuration. Zero
+// attempts disables locking.
+type LockoutConfig struct {
+	MaxAttempts  int           `env:"LOCKOUT_MAX_ATTEMPTS" default:"5"`
+	Window       time.Duration `env:"LOCKOUT_WINDOW" default:"15m"`
+	BaseDuration time.Duration `env:"LOCKOUT_BASE_DURATION" default:"1m"`
+	MaxDuration  time.Duration `env:"LOCKOUT_MAX_DURATION" default:"1h"`
+}
+
+type RedisConfig struct {
+	Addr     string `env:"REDIS_ADDR" default:"localhost:6379"`
+	Password string `env:"REDIS_PASSWORD"`
+	DB       int    `env:"REDIS_DB" default:"0"`
 }
 
 func LoadConfig() (*Config, error) {
 	_ = godotenv.Load()
 
 	var conf Config
-	if err := env.Parse(&conf); err != nil {
-		return nil, errors.New("don't parse .env")
+	if err := env.ParseWithOptions(&conf, env.Options{DefaultValueTagName: "default"}); err != nil {
+		return nil, fmt.Errorf("don't parse .env: %w", err)
 	}
 
 	return &conf, nil
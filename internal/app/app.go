@@ -4,56 +4,517 @@ import (
 	"context"
 	"denet/config"
 	"errors"
+	"fmt"
+	"net"
+	nethttp "net/http"
+	"time"
 
 	"denet/internal/handler"
+	"denet/internal/health"
 	"denet/internal/http"
+	"denet/internal/jwks"
+	"denet/internal/leaderboard"
+	"denet/internal/metrics"
+	"denet/internal/model"
+	"denet/internal/ratelimit"
 	"denet/internal/repository"
 	"denet/internal/service"
+	"denet/internal/social"
+	"denet/internal/store"
 	pg "denet/internal/store/postgresql"
+	"denet/internal/tracing"
+	"denet/internal/verifier"
+	"denet/internal/worker"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
-func New(conf *config.Config, logger *zap.Logger) error {
+// App is the service with its HTTP server, background workers and the
+// resources they share.
+type App struct {
+	conf         *config.Config
+	logger       *zap.Logger
+	db           store.Database
+	redis        *redis.Client
+	closeTracing func(context.Context) error
+	workers      worker.Group
+	health       *health.Registry
+	server       *nethttp.Server
+	internal     *nethttp.Server
+	serveErr     chan error
+	started      bool
+}
 
-	db := pg.NewPostgresDatabase(conf.Database.URL)
+// New connects to the database, migrates it and wires the service. Nothing
+// is served and no worker runs until Start. On error everything opened so
+// far is closed again.
+func New(conf *config.Config, logger *zap.Logger) (_ *App, err error) {
+	if err := checkIntervals(conf); err != nil {
+		return nil, err
+	}
+	a := &App{
+		conf:         conf,
+		logger:       logger,
+		closeTracing: func(context.Context) error { return nil },
+		serveErr:     make(chan error, 2),
+		health:       health.NewRegistry(conf.Health.CheckTimeout, conf.Health.CacheTTL),
+	}
+	defer func() {
+		if err != nil {
+			a.closeResources()
+			a.closeTracing(context.Background())
+		}
+	}()
+
+	closeTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    conf.Tracing.Exporter,
+		ServiceName: conf.Tracing.ServiceName,
+		Endpoint:    conf.Tracing.OTLPEndpoint,
+		Insecure:    conf.Tracing.OTLPInsecure,
+		SampleRatio: conf.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("set up tracing: %w", err)
+	}
+	a.closeTracing = closeTracing
 
+	db := pg.NewPostgresDatabase(conf.Database.URL)
 	if err := db.Connect(context.Background()); err != nil {
-		logger.Fatal("Failed to connect to database", zap.Error(err))
+		return nil, fmt.Errorf("connect to database: %w", err)
 	}
-	defer db.Close()
+	a.db = db
 
 	if err := db.Ping(context.Background()); err != nil {
-		logger.Fatal("Failed to ping database", zap.Error(err))
+		return nil, fmt.Errorf("ping database: %w", err)
 	}
 
 	if err := db.RunMigrations(); err != nil {
-		logger.Fatal("Failed to run migrations", zap.Error(err))
+		return nil, fmt.Errorf("run migrations: %w", err)
+	}
+	schemaVersion, _, err := db.MigrationVersion(context.Background())
+	if err != nil {
+		return nil, err
 	}
+	a.health.Register("database", true, db.Ping)
+	a.health.Register("migrations", true, func(ctx context.Context) error {
+		return checkMigrations(ctx, db, schemaVersion)
+	})
 
-	uow := repository.NewPostgresUnitOfWork(db)
+	m := metrics.New(prometheus.NewRegistry())
+	if pool, ok := db.(metrics.DBStatser); ok {
+		if err := m.Register(metrics.NewDBStatsCollector(pool, "postgres")); err != nil {
+			return nil, err
+		}
+	}
+
+	isolation, err := store.ParseIsolationLevel(conf.Database.TxIsolation)
+	if err != nil {
+		return nil, err
+	}
 
-	userService := service.NewUserService(uow)
-	authService := service.NewAuthService(uow)
+	uow := repository.NewPostgresUnitOfWork(tracing.NewDatabase(db), repository.TxConfig{
+		Isolation:  isolation,
+		MaxRetries: conf.Database.TxMaxRetries,
+		RetryDelay: conf.Database.TxRetryDelay,
+	})
+
+	board, err := a.newLeaderboardStore()
+	if err != nil {
+		return nil, fmt.Errorf("set up leaderboard index: %w", err)
+	}
+	// Reads fall back to Postgres when the index fails, so it does not
+	// decide readiness.
+	a.health.Register("leaderboard_index", false, func(ctx context.Context) error {
+		_, err := board.Len(ctx)
+		return err
+	})
+
+	users := uow.Users()
+	indexed, err := leaderboard.Warm(context.Background(), users, board)
+	if err != nil {
+		return nil, fmt.Errorf("warm leaderboard index: %w", err)
+	}
+	logger.Info("Leaderboard index warmed",
+		zap.String("backend", conf.Leaderboard.Backend),
+		zap.Int("users", indexed),
+	)
+
+	leaderboardResync := &worker.Periodic{
+		Name:     "leaderboard-resync",
+		Interval: conf.Leaderboard.ResyncInterval,
+		Logger:   logger,
+		Job: func(ctx context.Context) error {
+			_, err := leaderboard.Warm(ctx, users, board)
+			return err
+		},
+	}
+	a.addPeriodic(leaderboardResync)
+
+	uow = leaderboard.NewUnitOfWork(uow, board)
+	uow = metrics.NewUnitOfWork(uow, m)
+
+	keys, err := jwks.LoadOrCreate(jwks.Config{
+		Algorithm:        conf.JWT.SigningAlgorithm,
+		Dir:              conf.JWT.KeysDir,
+		RotationInterval: conf.JWT.RotationInterval,
+		Retention:        conf.JWT.KeyRetention,
+		TokenTTL:         conf.JWT.ExpireTime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load signing keys: %w", err)
+	}
+	a.workers.Add(func(ctx context.Context) { keys.RunRotation(ctx, logger) })
+
+	verifiers := verifier.NewRegistry()
+	registerSocialVerifiers(verifiers, conf.Social, uow.SocialAccounts(), logger)
+
+	referralService := service.NewReferralService(uow)
+	userService := service.NewUserService(uow, referralService, verifiers)
+	idempotencyService := service.NewIdempotencyService(uow, conf.Idempotency.TTL)
+	authService := service.NewAuthService(uow, referralService, idempotencyService, service.TokenConfig{
+		Signer:     keys,
+		AccessTTL:  conf.JWT.ExpireTime,
+		RefreshTTL: conf.JWT.RefreshExpireTime,
+	}, service.TelegramAuthConfig{
+		BotToken: conf.Social.TelegramBotToken,
+		MaxAge:   conf.Social.TelegramAuthMaxAge,
+	}, service.LockoutConfig{
+		MaxAttempts:  conf.Lockout.MaxAttempts,
+		Window:       conf.Lockout.Window,
+		BaseDuration: conf.Lockout.BaseDuration,
+		MaxDuration:  conf.Lockout.MaxDuration,
+	})
+	taskService := service.NewTaskService(uow, verifiers)
+	taskReviewService := service.NewTaskReviewService(uow, referralService)
+	roleService := service.NewRoleService(uow)
+	socialService := service.NewSocialService(uow)
+	walletService := service.NewWalletService(uow, service.WalletConfig{
+		Domain:   conf.Wallet.Domain,
+		ChainIDs: conf.Wallet.ChainIDs,
+		NonceTTL: conf.Wallet.NonceTTL,
+	})
+	leaderboardService := service.NewLeaderboardService(uow)
+	airdropService := service.NewAirdropService(uow)
+	rewardService := service.NewRewardService(uow)
+
+	if err := roleService.EnsureAdmins(context.Background(), conf.RBAC.BootstrapAdminIDs); err != nil {
+		return nil, fmt.Errorf("bootstrap admin roles: %w", err)
+	}
+
+	seasonArchiver := &worker.Periodic{
+		Name:       "season-archiver",
+		Interval:   conf.Leaderboard.SeasonArchiveInterval,
+		Logger:     logger,
+		RunOnStart: true,
+		Job: func(ctx context.Context) error {
+			archived, err := leaderboardService.ArchiveEndedSeasons(ctx)
+			if archived > 0 {
+				logger.Info("Seasons archived", zap.Int("count", archived))
+			}
+			return err
+		},
+	}
+	a.addPeriodic(seasonArchiver)
+
+	idempotencyPurge := &worker.Periodic{
+		Name:     "idempotency-purge",
+		Interval: conf.Idempotency.PurgeInterval,
+		Logger:   logger,
+		Job:      idempotencyService.PurgeExpired,
+	}
+	a.addPeriodic(idempotencyPurge)
+
+	walletNoncePurge := &worker.Periodic{
+		Name:     "wallet-nonce-purge",
+		Interval: conf.Wallet.NonceTTL,
+		Logger:   logger,
+		Job:      walletService.PurgeExpiredNonces,
+	}
+	a.addPeriodic(walletNoncePurge)
 
 	//добавить auth service
 
-	userHandler  := handler.NewUserHandler(userService, logger)
-	authHandler  := handler.NewAuthHandler(authService, conf.JWT.SecretKey, logger)
+	handlers := http.Handlers{
+		User:        handler.NewUserHandler(userService),
+		Auth:        handler.NewAuthHandler(authService, m),
+		Task:        handler.NewTaskHandler(taskService),
+		Role:        handler.NewRoleHandler(roleService),
+		Referral:    handler.NewReferralHandler(referralService),
+		Leaderboard: handler.NewLeaderboardHandler(leaderboardService),
+		TaskReview:  handler.NewTaskReviewHandler(taskReviewService),
+		Social:      handler.NewSocialHandler(socialService),
+		Wallet:      handler.NewWalletHandler(walletService, authService, m),
+		Airdrop:     handler.NewAirdropHandler(airdropService),
+		Reward:      handler.NewRewardHandler(rewardService),
+	}
 
-	r := http.NewRoute(authHandler, userHandler, *conf, logger)
+	limiter, err := a.newRateLimitStore()
+	if err != nil {
+		return nil, fmt.Errorf("set up rate limiter: %w", err)
+	}
+	limits, err := parseRateLimits(conf.RateLimit)
+	if err != nil {
+		return nil, err
+	}
 
-	serverAddr := conf.Server.Host + ":" + conf.Server.Port
-	logger.Info("Server starting",
-		zap.String("address", serverAddr),
+	deps := http.Dependencies{
+		Keys:        keys,
+		Revocations: authService,
+		Idempotency: idempotencyService,
+		Health:      a.health,
+		Metrics:     m,
+		RateLimiter: limiter,
+		RateLimits:  limits,
+	}
+	r := http.NewRoute(handlers, deps, logger)
+	if err := r.SetTrustedProxies(conf.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("set trusted proxies: %w", err)
+	}
+
+	a.server = &nethttp.Server{
+		Addr:              conf.Server.Host + ":" + conf.Server.Port,
+		Handler:           r,
+		ReadTimeout:       conf.Server.ReadTimeout,
+		ReadHeaderTimeout: conf.Server.ReadHeaderTimeout,
+		WriteTimeout:      conf.Server.WriteTimeout,
+		IdleTimeout:       conf.Server.IdleTimeout,
+	}
+	a.internal = &nethttp.Server{
+		Addr:              conf.Server.Host + ":" + conf.Server.InternalPort,
+		Handler:           http.NewInternalRoute(deps),
+		ReadHeaderTimeout: conf.Server.ReadHeaderTimeout,
+		WriteTimeout:      conf.Server.WriteTimeout,
+	}
+	return a, nil
+}
+
+// Start starts the background workers and begins serving the API and the
+// internal endpoints. It returns once both listeners are bound; later server
+// failures are reported on Errors.
+func (a *App) Start() error {
+	listener, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", a.server.Addr, err)
+	}
+	internal, err := net.Listen("tcp", a.internal.Addr)
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("listen on %s: %w", a.internal.Addr, err)
+	}
+	a.workers.Start()
+	a.started = true
+
+	a.logger.Info("Server starting",
+		zap.String("address", a.server.Addr),
+		zap.String("internal_address", a.internal.Addr),
 		zap.String("environment", gin.Mode()),
 	)
+	a.serve(a.server, listener)
+	a.serve(a.internal, internal)
+	return nil
+}
+
+func (a *App) serve(server *nethttp.Server, listener net.Listener) {
+	go func() {
+		if err := server.Serve(listener); !errors.Is(err, nethttp.ErrServerClosed) {
+			a.serveErr <- err
+		}
+	}()
+}
+
+// Errors reports either server stopping on its own.
+func (a *App) Errors() <-chan error {
+	return a.serveErr
+}
+
+// Shutdown fails readiness for the configured delay if the server was
+// started, then stops accepting connections and waits for in-flight
+// requests, stops the internal server and the workers, closes the Redis
+// client and the database and flushes pending spans. Once ctx expires, open connections are dropped and
+// the remaining steps run without waiting. Errors of all steps are joined.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.health.SetShuttingDown()
+	if a.started {
+		select {
+		case <-time.After(a.conf.Health.ShutdownDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	var errs []error
+	if err := a.server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("drain connections: %w", err))
+		a.server.Close()
+	}
+	// The internal endpoints stay up until the API is drained so that the
+	// last requests still show in the metrics.
+	if err := a.internal.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("stop internal server: %w", err))
+		a.internal.Close()
+	}
+	if err := a.workers.Stop(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("stop workers: %w", err))
+	}
+	if err := a.closeResources(); err != nil {
+		errs = append(errs, fmt.Errorf("close database: %w", err))
+	}
+	if err := a.closeTracing(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("flush traces: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// addPeriodic runs p with the other workers and reports it in readiness
+// without letting it decide it.
+func (a *App) addPeriodic(p *worker.Periodic) {
+	a.workers.Add(p.Run)
+	a.health.Register("worker:"+p.Name, false, p.Check)
+}
+
+// checkMigrations fails if the schema is dirty or was rolled back below the
+// version this binary migrated it to.
+func checkMigrations(ctx context.Context, db store.Database, want uint) error {
+	version, dirty, err := db.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migration %d failed halfway", version)
+	}
+	if version < want {
+		return fmt.Errorf("schema is at version %d, want %d", version, want)
+	}
+	return nil
+}
+
+func (a *App) closeResources() error {
+	if a.redis != nil {
+		a.redis.Close()
+	}
+	if a.db == nil {
+		return nil
+	}
+	return a.db.Close()
+}
+
+// registerSocialVerifiers registers a membership verifier for every platform
+// with credentials. Tasks of the other platforms go to manual review.
+func registerSocialVerifiers(verifiers *verifier.Registry, conf config.SocialConfig, accounts verifier.AccountLookup, logger *zap.Logger) {
+	httpClient := &nethttp.Client{Timeout: conf.RequestTimeout}
+	checkers := map[model.SocialProvider]struct {
+		configKey  string
+		configured bool
+		checker    social.MembershipChecker
+	}{
+		model.SocialTelegram: {"chat_id", conf.TelegramBotToken != "",
+			&social.TelegramClient{BaseURL: conf.TelegramAPIURL, Token: conf.TelegramBotToken, HTTP: httpClient}},
+		model.SocialTwitter: {"target_user_id", conf.TwitterBearerToken != "",
+			&social.TwitterClient{BaseURL: conf.TwitterAPIURL, BearerToken: conf.TwitterBearerToken, HTTP: httpClient}},
+		model.SocialDiscord: {"guild_id", conf.DiscordBotToken != "",
+			&social.DiscordClient{BaseURL: conf.DiscordAPIURL, BotToken: conf.DiscordBotToken, HTTP: httpClient}},
+	}
+	for provider, c := range checkers {
+		if !c.configured {
+			logger.Warn("Social verifier not configured, tasks go to manual review", zap.String("provider", string(provider)))
+			verifiers.Register(string(provider), verifier.Manual)
+			continue
+		}
+		verifiers.Register(string(provider), &verifier.Membership{
+			Provider:  provider,
+			ConfigKey: c.configKey,
+			Checker:   social.NewCachedChecker(c.checker, conf.CacheTTL, conf.NegativeCacheTTL),
+			Accounts:  accounts,
+		})
+	}
+}
 
-	if err := r.Run(serverAddr); err != nil {
-		logger.Fatal("Failed to start server", zap.Error(err))
-		return errors.New("Failed to start server")
+// redisClient returns the Redis client shared by the backends configured to
+// use Redis, connecting on first use.
+func (a *App) redisClient() (*redis.Client, error) {
+	if a.redis != nil {
+		return a.redis, nil
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     a.conf.Redis.Addr,
+		Password: a.conf.Redis.Password,
+		DB:       a.conf.Redis.DB,
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
 	}
+	a.redis = client
+	return client, nil
+}
 
+// newLeaderboardStore builds the configured leaderboard backend.
+func (a *App) newLeaderboardStore() (leaderboard.Store, error) {
+	switch a.conf.Leaderboard.Backend {
+	case "memory":
+		return leaderboard.NewMemoryStore(), nil
+	case "redis":
+		client, err := a.redisClient()
+		if err != nil {
+			return nil, err
+		}
+		return leaderboard.NewRedisStore(client, a.conf.Leaderboard.RedisPrefix), nil
+	}
+	return nil, fmt.Errorf("unknown leaderboard backend %q", a.conf.Leaderboard.Backend)
+}
+
+// newRateLimitStore builds the configured rate limit backend.
+func (a *App) newRateLimitStore() (ratelimit.Store, error) {
+	switch a.conf.RateLimit.Backend {
+	case "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "redis":
+		client, err := a.redisClient()
+		if err != nil {
+			return nil, err
+		}
+		return ratelimit.NewRedisStore(client, a.conf.RateLimit.RedisPrefix), nil
+	}
+	return nil, fmt.Errorf("unknown rate limit backend %q", a.conf.RateLimit.Backend)
+}
+
+// checkIntervals rejects worker intervals a ticker cannot run at.
+func checkIntervals(conf *config.Config) error {
+	for _, i := range []struct {
+		env   string
+		value time.Duration
+	}{
+		{"LEADERBOARD_RESYNC_INTERVAL", conf.Leaderboard.ResyncInterval},
+		{"SEASON_ARCHIVE_INTERVAL", conf.Leaderboard.SeasonArchiveInterval},
+		{"IDEMPOTENCY_PURGE_INTERVAL", conf.Idempotency.PurgeInterval},
+		// Expired nonces are purged once per TTL.
+		{"SIWE_NONCE_TTL", conf.Wallet.NonceTTL},
+	} {
+		if i.value <= 0 {
+			return fmt.Errorf("%s: must be positive, got %s", i.env, i.value)
+		}
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+func parseRateLimits(conf config.RateLimitConfig) (http.RateLimits, error) {
+	var limits http.RateLimits
+	for _, l := range []struct {
+		env   string
+		value string
+		limit *ratelimit.Limit
+	}{
+		{"RATE_LIMIT_PER_IP", conf.PerIP, &limits.PerIP},
+		{"RATE_LIMIT_PER_USER", conf.PerUser, &limits.PerUser},
+		{"RATE_LIMIT_AUTH", conf.Auth, &limits.Auth},
+	} {
+		limit, err := ratelimit.ParseLimit(l.value)
+		if err != nil {
+			return http.RateLimits{}, fmt.Errorf("%s: %w", l.env, err)
+		}
+		*l.limit = limit
+	}
+	return limits, nil
+}
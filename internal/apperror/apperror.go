@@ -0,0 +1,112 @@
+// Package apperror defines the errors that cross layer boundaries. Each one
+// carries a stable machine-readable code, the HTTP status it maps to, a
+// message that is safe to show to clients and, optionally, the underlying
+// cause, which is logged but never sent.
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+const (
+	CodeInternal     = "internal_error"
+	CodeBadRequest   = "bad_request"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+)
+
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	Details string
+	Err     error
+}
+
+// New defines an error kind. Declare kinds once as package-level variables
+// and derive request specific values with Wrap and WithDetails.
+func New(code string, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches any error of the same kind, so errors.Is keeps working on
+// wrapped copies.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// WithDetails returns a copy of e carrying client-facing details.
+func (e *Error) WithDetails(details string) *Error {
+	detailed := *e
+	detailed.Details = details
+	return &detailed
+}
+
+// Internal hides err behind a generic server error.
+func Internal(err error) *Error {
+	return &Error{
+		Code:    CodeInternal,
+		Status:  http.StatusInternalServerError,
+		Message: "Internal server error",
+		Err:     err,
+	}
+}
+
+// From finds the *Error in err's chain, treating anything untyped as an
+// internal error.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
+
+// CodeForStatus is the generic code used for errors that have no kind of
+// their own, such as request validation failures.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusUnprocessableEntity:
+		return "unprocessable_entity"
+	case http.StatusTooManyRequests:
+		return "too_many_requests"
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"denet/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// canReadUser reports whether the authenticated caller may read userID's
+// data: either it is their own or they hold users:read.
+func canReadUser(c *gin.Context, userID string) bool {
+	claims, exists := c.Get("user_claims")
+	if !exists {
+		return false
+	}
+	jwtClaims := claims.(*model.JWTClaims)
+	return jwtClaims.UserID == userID || jwtClaims.HasPermission(model.PermUsersRead)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"denet/internal/http/response"
+	"denet/internal/model"
+	"denet/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AirdropHandler interface {
+	GetProof(c *gin.Context)
+	AdminListAirdrops(c *gin.Context)
+	AdminCreateAirdrop(c *gin.Context)
+}
+
+type airdropHandler struct {
+	airdropService service.AirdropService
+}
+
+func NewAirdropHandler(airdropService service.AirdropService) AirdropHandler {
+	return &airdropHandler{
+		airdropService: airdropService,
+	}
+}
+
+func (h *airdropHandler) GetProof(c *gin.Context) {
+	userID := c.Param("id")
+	if !canReadUser(c, userID) {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	claim, err := h.airdropService.GetClaim(c.Request.Context(), userID, c.Query("airdrop_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Airdrop proof retrieved successfully", claim)
+}
+
+func (h *airdropHandler) AdminListAirdrops(c *gin.Context) {
+	airdrops, err := h.airdropService.ListAirdrops(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Airdrops retrieved successfully", airdrops)
+}
+
+func (h *airdropHandler) AdminCreateAirdrop(c *gin.Context) {
+	var req model.CreateAirdropRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid create airdrop request", zap.Error(err))
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	airdrop, err := h.airdropService.CreateSnapshot(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Airdrop snapshot created",
+		zap.String("airdrop_id", airdrop.ID),
+		zap.String("name", airdrop.Name),
+		zap.String("merkle_root", airdrop.MerkleRoot),
+		zap.Int("recipients", airdrop.Recipients),
+	)
+	response.WriteCreated(c, "Airdrop snapshot created successfully", airdrop)
+}
@@ -1,10 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 	"denet/internal/model"
 	"denet/internal/service"
 	"denet/internal/http/response"
+	"denet/internal/handler/middleware"
+	"denet/internal/metrics"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -13,26 +18,31 @@ import (
 type AuthHandler interface {
 	Register(c *gin.Context)
 	Login(c *gin.Context)
+	TelegramLogin(c *gin.Context)
+	LinkTelegram(c *gin.Context)
+	Refresh(c *gin.Context)
+	Logout(c *gin.Context)
+	LogoutAll(c *gin.Context)
+	AdminGetLockout(c *gin.Context)
+	AdminUnlock(c *gin.Context)
 }
 
 type authHandler struct {
 	authService service.AuthService
-	jwtSecret   string
-	logger      *zap.Logger
+	metrics     *metrics.Metrics
 }
 
-func NewAuthHandler(authService service.AuthService, jwtSecret string, logger *zap.Logger) AuthHandler {
+func NewAuthHandler(authService service.AuthService, metrics *metrics.Metrics) AuthHandler {
 	return &authHandler{
 		authService: authService,
-		jwtSecret:   jwtSecret,
-		logger:      logger,
+		metrics:     metrics,
 	}
 }
 
 func (h *authHandler) Register(c *gin.Context) {
 	var req model.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Warn("Invalid register request",
+		requestLogger(c).Warn("Invalid register request",
 			zap.String("username", req.Username),
 			zap.Error(err),
 		)
@@ -40,46 +50,29 @@ func (h *authHandler) Register(c *gin.Context) {
 		return
 	}
 
-	user, err := h.authService.Register(c.Request.Context(), &req)
+	user, err := h.authService.Register(c.Request.Context(), &req, c.GetHeader(middleware.IdempotencyKeyHeader))
 	if err != nil {
-		h.logger.Error("Failed to register user",
-			zap.String("username", req.Username),
-			zap.Error(err),
-		)
-
-		switch err {
-		case service.ErrUserExists:
-			response.WriteError(c, http.StatusConflict, "Username or email already exists")
-		default:
-			response.WriteError(c, http.StatusInternalServerError, "Internal server error")
-		}
+		c.Error(err)
 		return
 	}
 
-	token, err := h.authService.GenerateToken(user, h.jwtSecret)
+	tokens, err := h.authService.IssueTokens(c.Request.Context(), user)
 	if err != nil {
-		h.logger.Error("Failed to generate token",
-			zap.String("user_id", user.ID),
-			zap.Error(err),
-		)
-		response.WriteError(c, http.StatusInternalServerError, "Failed to generate token")
+		c.Error(err)
 		return
 	}
 
-	h.logger.Info("User registered successfully",
+	requestLogger(c).Info("User registered successfully",
 		zap.String("user_id", user.ID),
 		zap.String("username", user.Username),
 	)
-	response.WriteCreated(c, "User registered successfully", model.AuthResponse{
-		Token: token,
-		User:  user,
-	})
+	response.WriteCreated(c, "User registered successfully", authResponse(tokens, user))
 }
 
 func (h *authHandler) Login(c *gin.Context) {
 	var req model.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Warn("Invalid login request",
+		requestLogger(c).Warn("Invalid login request",
 			zap.String("username", req.Username),
 			zap.Error(err),
 		)
@@ -89,30 +82,179 @@ func (h *authHandler) Login(c *gin.Context) {
 
 	user, err := h.authService.Login(c.Request.Context(), &req)
 	if err != nil {
-		h.logger.Warn("Failed login attempt",
-			zap.String("username", req.Username),
-			zap.Error(err),
-		)
-		response.WriteError(c, http.StatusUnauthorized, "Invalid credentials")
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			requestLogger(c).Warn("Failed login attempt", zap.String("username", req.Username))
+		}
+		if until, ok := service.LockedUntil(err); ok {
+			requestLogger(c).Warn("Login attempt on locked account",
+				zap.String("username", req.Username),
+				zap.Time("locked_until", until),
+			)
+			retryAfter := max(int(time.Until(until).Seconds())+1, 1)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		h.metrics.LoginFailed(metrics.MethodPassword)
+		c.Error(err)
+		return
+	}
+	h.metrics.LoginSucceeded(metrics.MethodPassword)
+
+	tokens, err := h.authService.IssueTokens(c.Request.Context(), user)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("User logged in successfully",
+		zap.String("user_id", user.ID),
+		zap.String("username", user.Username),
+	)
+	response.WriteSuccess(c, "Login successful", authResponse(tokens, user))
+}
+
+func (h *authHandler) TelegramLogin(c *gin.Context) {
+	var req model.TelegramAuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid telegram login request", zap.Error(err))
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	user, created, err := h.authService.LoginWithTelegram(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTelegramLogin) {
+			requestLogger(c).Warn("Failed telegram login attempt")
+		}
+		h.metrics.LoginFailed(metrics.MethodTelegram)
+		c.Error(err)
 		return
 	}
+	h.metrics.LoginSucceeded(metrics.MethodTelegram)
 
-	token, err := h.authService.GenerateToken(user, h.jwtSecret)
+	tokens, err := h.authService.IssueTokens(c.Request.Context(), user)
 	if err != nil {
-		h.logger.Error("Failed to generate token",
+		c.Error(err)
+		return
+	}
+
+	if created {
+		requestLogger(c).Info("User registered with telegram",
 			zap.String("user_id", user.ID),
-			zap.Error(err),
+			zap.String("username", user.Username),
 		)
-		response.WriteError(c, http.StatusInternalServerError, "Failed to generate token")
+		response.WriteCreated(c, "User registered successfully", authResponse(tokens, user))
 		return
 	}
-
-	h.logger.Info("User logged in successfully",
+	requestLogger(c).Info("User logged in with telegram",
 		zap.String("user_id", user.ID),
 		zap.String("username", user.Username),
 	)
-	response.WriteSuccess(c, "Login successful", model.AuthResponse{
-		Token: token,
-		User:  user,
-	})
-}
\ No newline at end of file
+	response.WriteSuccess(c, "Login successful", authResponse(tokens, user))
+}
+
+func (h *authHandler) LinkTelegram(c *gin.Context) {
+	claims := c.MustGet("user_claims").(*model.JWTClaims)
+
+	var req model.TelegramAuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid link telegram request",
+			zap.String("user_id", claims.UserID),
+			zap.Error(err),
+		)
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	account, err := h.authService.LinkTelegram(c.Request.Context(), claims.UserID, req.AuthData)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Telegram account linked",
+		zap.String("user_id", claims.UserID),
+		zap.String("external_id", account.ExternalID),
+	)
+	response.WriteSuccess(c, "Telegram account linked successfully", account)
+}
+
+func (h *authHandler) Refresh(c *gin.Context) {
+	var req model.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid refresh request", zap.Error(err))
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	tokens, user, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			requestLogger(c).Warn("Refresh token reuse detected, session revoked")
+		}
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Token refreshed", zap.String("user_id", user.ID))
+	response.WriteSuccess(c, "Token refreshed successfully", authResponse(tokens, user))
+}
+
+func (h *authHandler) Logout(c *gin.Context) {
+	claims := c.MustGet("user_claims").(*model.JWTClaims)
+	if err := h.authService.Logout(c.Request.Context(), claims); err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("User logged out",
+		zap.String("user_id", claims.UserID),
+		zap.String("session_id", claims.SessionID),
+	)
+	response.WriteSuccess(c, "Logged out successfully", nil)
+}
+
+func (h *authHandler) LogoutAll(c *gin.Context) {
+	claims := c.MustGet("user_claims").(*model.JWTClaims)
+	if err := h.authService.LogoutAll(c.Request.Context(), claims.UserID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("User logged out from all sessions", zap.String("user_id", claims.UserID))
+	response.WriteSuccess(c, "Logged out from all sessions", nil)
+}
+
+func (h *authHandler) AdminGetLockout(c *gin.Context) {
+	lockout, err := h.authService.GetLockout(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Login lockout retrieved successfully", lockout)
+}
+
+func (h *authHandler) AdminUnlock(c *gin.Context) {
+	userID := c.Param("id")
+	actor := c.MustGet("user_claims").(*model.JWTClaims)
+
+	if err := h.authService.Unlock(c.Request.Context(), userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("User unlocked",
+		zap.String("user_id", userID),
+		zap.String("actor_id", actor.UserID),
+	)
+	response.WriteSuccess(c, "User unlocked successfully", nil)
+}
+
+func authResponse(tokens *model.TokenPair, user *model.User) model.AuthResponse {
+	return model.AuthResponse{
+		Token:            tokens.AccessToken,
+		ExpiresAt:        tokens.AccessExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+		User:             user,
+	}
+}
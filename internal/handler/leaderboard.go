@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"denet/internal/http/response"
+	"denet/internal/model"
+	"denet/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type LeaderboardHandler interface {
+	GetWindowLeaderboard(c *gin.Context)
+	ListSeasons(c *gin.Context)
+	GetSeasonResults(c *gin.Context)
+	CreateSeason(c *gin.Context)
+}
+
+type leaderboardHandler struct {
+	leaderboardService service.LeaderboardService
+}
+
+func NewLeaderboardHandler(leaderboardService service.LeaderboardService) LeaderboardHandler {
+	return &leaderboardHandler{
+		leaderboardService: leaderboardService,
+	}
+}
+
+func (h *leaderboardHandler) GetWindowLeaderboard(c *gin.Context) {
+	window := c.Param("window")
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		response.WriteError(c, http.StatusBadRequest, "Invalid limit parametr")
+		return
+	}
+
+	board, err := h.leaderboardService.GetWindowLeaderboard(c.Request.Context(), window, c.Query("season_id"), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Leaderboard retrieved successfully", board)
+}
+
+func (h *leaderboardHandler) ListSeasons(c *gin.Context) {
+	seasons, err := h.leaderboardService.ListSeasons(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Seasons retrieved successfully", seasons)
+}
+
+func (h *leaderboardHandler) GetSeasonResults(c *gin.Context) {
+	seasonID := c.Param("id")
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		response.WriteError(c, http.StatusBadRequest, "Invalid limit parametr")
+		return
+	}
+
+	board, err := h.leaderboardService.GetSeasonResults(c.Request.Context(), seasonID, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Season results retrieved successfully", board)
+}
+
+func (h *leaderboardHandler) CreateSeason(c *gin.Context) {
+	var req model.CreateSeasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid create season request", zap.Error(err))
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	season, err := h.leaderboardService.CreateSeason(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Season created",
+		zap.String("season_id", season.ID),
+		zap.String("name", season.Name),
+	)
+	response.WriteCreated(c, "Season created successfully", season)
+}
@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"denet/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// requestLogger returns the logger scoped to the request being handled, which
+// carries its request ID, route and user.
+func requestLogger(c *gin.Context) *zap.Logger {
+	return logging.FromContext(c.Request.Context())
+}
@@ -1,8 +1,11 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
+	"denet/internal/http/response"
+	"denet/internal/logging"
 	"denet/internal/model"
 
 	"github.com/gin-gonic/gin"
@@ -10,12 +13,21 @@ import (
 	"go.uber.org/zap"
 )
 
-func AuthMiddleware(jwtSecret string, logger *zap.Logger) gin.HandlerFunc {
+// RevocationChecker reports whether an access token was revoked, either by
+// its own jti or through its session.
+type RevocationChecker interface {
+	IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error)
+}
+
+// AuthMiddleware authenticates the bearer token and adds its user to the
+// request's logger.
+func AuthMiddleware(keyfunc jwt.Keyfunc, revocations RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		logger := logging.FromContext(c.Request.Context())
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			logger.Debug("Authorization header missing")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			response.WriteError(c, http.StatusUnauthorized, "Authorization header required")
 			c.Abort()
 			return
 		}
@@ -23,7 +35,7 @@ func AuthMiddleware(jwtSecret string, logger *zap.Logger) gin.HandlerFunc {
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			logger.Debug("Invalid authorization header format")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+			response.WriteError(c, http.StatusUnauthorized, "Invalid authorization header format")
 			c.Abort()
 			return
 		}
@@ -31,23 +43,66 @@ func AuthMiddleware(jwtSecret string, logger *zap.Logger) gin.HandlerFunc {
 		tokenString := parts[1]
 		claims := &model.JWTClaims{}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, claims, keyfunc)
 
 		if err != nil || !token.Valid {
 			logger.Debug("Invalid token", zap.Error(err))
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			response.WriteError(c, http.StatusUnauthorized, "Invalid token")
+			c.Abort()
+			return
+		}
+
+		revoked, err := revocations.IsTokenRevoked(c.Request.Context(), claims.ID, claims.SessionID)
+		if err != nil {
+			logger.Error("Failed to check token revocation", zap.Error(err))
+			response.WriteError(c, http.StatusInternalServerError, "Internal server error")
+			c.Abort()
+			return
+		}
+		if revoked {
+			logger.Debug("Revoked token",
+				zap.String("user_id", claims.UserID),
+				zap.String("session_id", claims.SessionID),
+			)
+			response.WriteError(c, http.StatusUnauthorized, "Token has been revoked")
 			c.Abort()
 			return
 		}
 
 		c.Set("user_claims", claims)
-		
-		logger.Debug("User authenticated",
-			zap.String("user_id", claims.UserID),
+		ctx := logging.With(c.Request.Context(), zap.String("user_id", claims.UserID))
+		c.Request = c.Request.WithContext(ctx)
+
+		logging.FromContext(ctx).Debug("User authenticated",
 			zap.String("username", claims.Username),
 		)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// RequirePermission rejects requests whose token does not carry every one of
+// the given permissions. It must run after AuthMiddleware.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("user_claims")
+		if !exists {
+			response.WriteError(c, http.StatusUnauthorized, "Unauthorized")
+			c.Abort()
+			return
+		}
+
+		jwtClaims := claims.(*model.JWTClaims)
+		for _, permission := range permissions {
+			if !jwtClaims.HasPermission(permission) {
+				logging.FromContext(c.Request.Context()).Warn("Permission denied",
+					zap.String("permission", permission),
+					zap.String("path", c.Request.URL.Path),
+				)
+				response.WriteError(c, http.StatusForbidden, "Access denied")
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
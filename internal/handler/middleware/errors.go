@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"denet/internal/apperror"
+	"denet/internal/http/response"
+	"denet/internal/logging"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ErrorHandler renders the last error a handler attached with c.Error,
+// unless a response was already written. Server errors are logged with their
+// cause; the client only ever sees the error's safe message and code.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := apperror.From(c.Errors.Last().Err)
+		fields := []zap.Field{
+			zap.String("code", err.Code),
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.Error(err),
+		}
+		logger := logging.FromContext(c.Request.Context())
+		if err.Status >= http.StatusInternalServerError {
+			logger.Error("Request failed", fields...)
+		} else {
+			logger.Debug("Request rejected", fields...)
+		}
+		response.WriteAppError(c, err)
+	}
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"denet/internal/apperror"
+	"denet/internal/http/response"
+	"denet/internal/logging"
+	"denet/internal/model"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+// IdempotencyStore reserves keys and remembers the responses sent for them.
+// Begin reports a key that cannot be used for the request with a typed
+// apperror.Error.
+type IdempotencyStore interface {
+	Begin(ctx context.Context, scope, key, fingerprint string) (*model.IdempotencyRecord, error)
+	Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, scope, key string) error
+}
+
+// Idempotency makes mutating requests carrying an Idempotency-Key header
+// safe to retry. The first request runs normally and its response is stored;
+// a repeat with the same key and body gets the stored response back, and a
+// repeat with a different body is rejected. Server errors and 429 responses
+// are not stored, so the request can be retried once the server recovers or
+// the client is let through again. Requests without the header, and safe
+// methods, pass straight through.
+//
+// Keys are scoped to the authenticated user when there is one, so it must run
+// after AuthMiddleware on protected routes, and to the client IP otherwise.
+// Responses are stored as sent, so keep it off routes answering with
+// credentials.
+func Idempotency(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !isMutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > model.MaxIdempotencyKeyLength {
+			response.WriteError(c, http.StatusBadRequest, "Idempotency-Key header is too long")
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentRequestBytes+1))
+		if err != nil {
+			response.WriteError(c, http.StatusBadRequest, "Failed to read request body")
+			c.Abort()
+			return
+		}
+		if len(body) > maxIdempotentRequestBytes {
+			response.WriteError(c, http.StatusRequestEntityTooLarge, "Request body too large")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := idempotencyScope(c)
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.RequestURI(), body)
+		ctx := c.Request.Context()
+		logger := logging.FromContext(ctx)
+
+		stored, err := store.Begin(ctx, scope, key, fingerprint)
+		if err != nil {
+			appErr := apperror.From(err)
+			if appErr.Status >= http.StatusInternalServerError {
+				logger.Error("Failed to reserve idempotency key",
+					zap.String("scope", scope),
+					zap.Error(err),
+				)
+			}
+			response.WriteAppError(c, appErr)
+			c.Abort()
+			return
+		}
+		if stored != nil {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(*stored.StatusCode, stored.ContentType, stored.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			// Runs on server errors, on 429s and when a handler panics.
+			if err := store.Release(context.WithoutCancel(ctx), scope, key); err != nil {
+				logger.Error("Failed to release idempotency key",
+					zap.String("scope", scope),
+					zap.Error(err),
+				)
+			}
+		}()
+
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			return
+		}
+		if err := store.Complete(context.WithoutCancel(ctx), scope, key, status, writer.Header().Get("Content-Type"), writer.body.Bytes()); err != nil {
+			logger.Error("Failed to store idempotent response",
+				zap.String("scope", scope),
+				zap.Error(err),
+			)
+			return
+		}
+		completed = true
+	}
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func idempotencyScope(c *gin.Context) string {
+	user := "anonymous:" + c.ClientIP()
+	if claims, ok := c.Get("user_claims"); ok {
+		user = claims.(*model.JWTClaims).UserID
+	}
+	return user + " " + c.Request.Method + " " + c.FullPath()
+}
+
+func requestFingerprint(method, uri string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(uri))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// capturingWriter keeps a copy of the response body while writing it through.
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
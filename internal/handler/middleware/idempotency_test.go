@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"denet/internal/apperror"
+	"denet/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errKeyInFlight = apperror.New("idempotency_key_in_flight", http.StatusConflict, "in flight")
+	errKeyMismatch = apperror.New("idempotency_key_mismatch", http.StatusUnprocessableEntity, "mismatch")
+)
+
+// fakeIdempotencyStore keeps records in memory with the semantics of
+// service.IdempotencyService.
+type fakeIdempotencyStore struct {
+	records  map[string]*model.IdempotencyRecord
+	released int
+}
+
+func (s *fakeIdempotencyStore) Begin(ctx context.Context, scope, key, fingerprint string) (*model.IdempotencyRecord, error) {
+	existing, ok := s.records[scope+"|"+key]
+	switch {
+	case !ok:
+		s.records[scope+"|"+key] = &model.IdempotencyRecord{Scope: scope, Key: key, Fingerprint: fingerprint}
+		return nil, nil
+	case existing.Fingerprint != fingerprint:
+		return nil, errKeyMismatch
+	case !existing.Completed():
+		return nil, errKeyInFlight
+	}
+	return existing, nil
+}
+
+func (s *fakeIdempotencyStore) Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error {
+	record := s.records[scope+"|"+key]
+	record.StatusCode = &statusCode
+	record.ContentType = contentType
+	record.ResponseBody = body
+	return nil
+}
+
+func (s *fakeIdempotencyStore) Release(ctx context.Context, scope, key string) error {
+	delete(s.records, scope+"|"+key)
+	s.released++
+	return nil
+}
+
+// newIdempotentRouter serves POST /orders, answering with status and counting
+// the requests that reached it.
+func newIdempotentRouter(store *fakeIdempotencyStore, status *int, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery())
+	r.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			c.Set("user_claims", &model.JWTClaims{UserID: user})
+		}
+	})
+	r.Use(Idempotency(store))
+	handle := func(c *gin.Context) {
+		*calls++
+		if *status == 0 {
+			panic("boom")
+		}
+		c.JSON(*status, gin.H{"call": *calls})
+	}
+	r.POST("/orders", handle)
+	r.GET("/orders", handle)
+	return r
+}
+
+func serve(r *gin.Engine, method, key, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/orders", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if user != "" {
+		req.Header.Set("X-Test-User", user)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	store := &fakeIdempotencyStore{records: make(map[string]*model.IdempotencyRecord)}
+	status, calls := http.StatusCreated, 0
+	r := newIdempotentRouter(store, &status, &calls)
+
+	first := serve(r, http.MethodPost, "k1", "alice", `{"reward":"mug"}`)
+	second := serve(r, http.MethodPost, "k1", "alice", `{"reward":"mug"}`)
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get(IdempotentReplayedHeader) != "true" || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("%s header not set on the replay only", IdempotentReplayedHeader)
+	}
+
+	if w := serve(r, http.MethodPost, "k1", "alice", `{"reward":"hat"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reuse with another body = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	// Keys are scoped per user.
+	if w := serve(r, http.MethodPost, "k1", "bob", `{"reward":"mug"}`); w.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("same key from another user = %d after %d calls, want a fresh %d", w.Code, calls, http.StatusCreated)
+	}
+}
+
+func TestIdempotencyPassesThrough(t *testing.T) {
+	store := &fakeIdempotencyStore{records: make(map[string]*model.IdempotencyRecord)}
+	status, calls := http.StatusOK, 0
+	r := newIdempotentRouter(store, &status, &calls)
+
+	serve(r, http.MethodPost, "", "alice", `{}`)
+	serve(r, http.MethodPost, "", "alice", `{}`)
+	serve(r, http.MethodGet, "k1", "alice", "")
+	serve(r, http.MethodGet, "k1", "alice", "")
+	if calls != 4 {
+		t.Errorf("handler ran %d times, want 4", calls)
+	}
+	if len(store.records) != 0 {
+		t.Errorf("stored %d records for requests it should ignore", len(store.records))
+	}
+
+	if w := serve(r, http.MethodPost, strings.Repeat("k", model.MaxIdempotencyKeyLength+1), "alice", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("overlong key = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"server error", http.StatusServiceUnavailable},
+		{"rate limited", http.StatusTooManyRequests},
+		{"panic", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeIdempotencyStore{records: make(map[string]*model.IdempotencyRecord)}
+			status, calls := tt.status, 0
+			r := newIdempotentRouter(store, &status, &calls)
+
+			serve(r, http.MethodPost, "k1", "alice", `{}`)
+			if store.released != 1 || len(store.records) != 0 {
+				t.Fatalf("released %d keys and kept %d records, want the key released", store.released, len(store.records))
+			}
+
+			status = http.StatusCreated
+			if w := serve(r, http.MethodPost, "k1", "alice", `{}`); w.Code != http.StatusCreated || calls != 2 {
+				t.Errorf("retry = %d after %d calls, want it to run again", w.Code, calls)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"denet/internal/logging"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -8,7 +9,9 @@ import (
 )
 
 
-func Logger(logger *zap.Logger) gin.HandlerFunc {
+// Logger writes an access log line for every request through the request's
+// logger, so it carries the request ID and, once authenticated, the user.
+func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
@@ -23,7 +26,7 @@ func Logger(logger *zap.Logger) gin.HandlerFunc {
 		statusCode := c.Writer.Status()
 		errorMessage := c.Errors.ByType(gin.ErrorTypePrivate).String()
 
-		logger.Info("HTTP request",
+		logging.FromContext(c.Request.Context()).Info("HTTP request",
 			zap.Int("status", statusCode),
 			zap.String("method", method),
 			zap.String("path", path),
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"denet/internal/http/response"
+	"denet/internal/logging"
+	"denet/internal/model"
+	"denet/internal/ratelimit"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RateLimitKey returns what a request is counted against, or "" to let it
+// through uncounted.
+type RateLimitKey func(c *gin.Context) string
+
+// RateLimitByIP counts requests per client IP.
+func RateLimitByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimitByUser counts requests per authenticated user. It must run after
+// AuthMiddleware.
+func RateLimitByUser(c *gin.Context) string {
+	claims, exists := c.Get("user_claims")
+	if !exists {
+		return ""
+	}
+	return "user:" + claims.(*model.JWTClaims).UserID
+}
+
+// RateLimitByRouteAndIP counts requests per client IP separately for every
+// route.
+func RateLimitByRouteAndIP(c *gin.Context) string {
+	return "route:" + c.Request.Method + " " + c.FullPath() + ":ip:" + c.ClientIP()
+}
+
+// RateLimit takes a token from the bucket named after name and the request's
+// key, and rejects the request with 429 and Retry-After when there is none.
+// The bucket's state is reported in X-RateLimit-Limit, X-RateLimit-Remaining
+// and X-RateLimit-Reset, the seconds until it is full again; when several
+// limits apply, the innermost one reports. Requests are let through if the
+// store fails, so that an outage of it does not take the API down.
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit, key RateLimitKey) gin.HandlerFunc {
+	if limit.Unlimited() {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return func(c *gin.Context) {
+		k := key(c)
+		if k == "" {
+			c.Next()
+			return
+		}
+
+		result, err := store.Take(c.Request.Context(), name+":"+k, limit)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("Rate limiter unavailable, letting request through",
+				zap.String("limit", name),
+				zap.Error(err),
+			)
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("X-RateLimit-Reset", ceilSeconds(result.Reset))
+		if !result.Allowed {
+			header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+			logging.FromContext(c.Request.Context()).Debug("Rate limit exceeded",
+				zap.String("limit", name),
+			)
+			response.WriteError(c, http.StatusTooManyRequests, "Too many requests")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds formats d as whole seconds, rounded up, for the Retry-After
+// and X-RateLimit-Reset headers.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
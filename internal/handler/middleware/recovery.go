@@ -1,23 +1,25 @@
 package middleware
 
 import (
+	"denet/internal/http/response"
+	"denet/internal/logging"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-func Recovery(logger *zap.Logger) gin.HandlerFunc {
+func Recovery() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				logger.Error("Panic recovered",
+				logging.FromContext(c.Request.Context()).Error("Panic recovered",
 					zap.Any("error", err),
 					zap.String("path", c.Request.URL.Path),
 					zap.String("method", c.Request.Method),
 				)
 
-				c.JSON(500, gin.H{
-					"error": "internal server error",
-				})
+				response.WriteError(c, http.StatusInternalServerError, "Internal server error")
 				c.Abort()
 			}
 		}()
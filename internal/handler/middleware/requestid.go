@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"denet/internal/logging"
+	"denet/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+// RequestID tags every request with an ID, taken from the X-Request-ID
+// header when the caller sent a usable one and generated otherwise, and
+// echoes it in the response. The request context gets a child of logger
+// carrying the ID, the route and the trace, which everything downstream logs
+// through; AuthMiddleware adds the user.
+func RequestID(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		c.Header(RequestIDHeader, id)
+
+		ctx := logging.WithRequestID(c.Request.Context(), id)
+		fields := []zap.Field{
+			zap.String("request_id", id),
+			zap.String("route", c.FullPath()),
+		}
+		fields = append(fields, tracing.LogFields(ctx)...)
+		ctx = logging.WithLogger(ctx, logger.With(fields...))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// validRequestID accepts IDs short enough and plain enough to put in a log
+// line as they are.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
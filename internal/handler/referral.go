@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"denet/internal/http/response"
+	"denet/internal/model"
+	"denet/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ReferralHandler interface {
+	SetReferralCode(c *gin.Context)
+	GetReferees(c *gin.Context)
+	GetTree(c *gin.Context)
+	GetStats(c *gin.Context)
+	ListRules(c *gin.Context)
+	CreateRule(c *gin.Context)
+	UpdateRule(c *gin.Context)
+}
+
+type referralHandler struct {
+	referralService service.ReferralService
+}
+
+func NewReferralHandler(referralService service.ReferralService) ReferralHandler {
+	return &referralHandler{
+		referralService: referralService,
+	}
+}
+
+func (h *referralHandler) SetReferralCode(c *gin.Context) {
+	userID := c.Param("id")
+	claims := c.MustGet("user_claims").(*model.JWTClaims)
+	if claims.UserID != userID {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	var req model.SetReferralCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid set referral code request",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.referralService.SetReferralCode(c.Request.Context(), userID, req.Code); err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Referral code updated",
+		zap.String("user_id", userID),
+		zap.String("code", req.Code),
+	)
+	response.WriteSuccess(c, "Referral code updated successfully", gin.H{"referral_code": req.Code})
+}
+
+func (h *referralHandler) GetReferees(c *gin.Context) {
+	userID := c.Param("id")
+	if !canReadUser(c, userID) {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		response.WriteError(c, http.StatusBadRequest, "Invalid limit parametr")
+		return
+	}
+
+	page, err := h.referralService.GetReferees(c.Request.Context(), userID, c.Query("cursor"), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Referees retrieved successfully", page)
+}
+
+func (h *referralHandler) GetTree(c *gin.Context) {
+	userID := c.Param("id")
+	if !canReadUser(c, userID) {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", "3"))
+	if err != nil || depth <= 0 {
+		response.WriteError(c, http.StatusBadRequest, "Invalid depth parameter")
+		return
+	}
+
+	tree, err := h.referralService.GetTree(c.Request.Context(), userID, depth)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Referral tree retrieved successfully", tree)
+}
+
+func (h *referralHandler) GetStats(c *gin.Context) {
+	userID := c.Param("id")
+	if !canReadUser(c, userID) {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	stats, err := h.referralService.GetStats(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Referral stats retrieved successfully", stats)
+}
+
+func (h *referralHandler) ListRules(c *gin.Context) {
+	rules, err := h.referralService.ListRules(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Referral rules retrieved successfully", gin.H{"rules": rules})
+}
+
+func (h *referralHandler) CreateRule(c *gin.Context) {
+	var req model.CreateReferralRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid create referral rule request", zap.Error(err))
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	rule, err := h.referralService.CreateRule(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Referral rule created",
+		zap.String("rule_id", rule.ID),
+		zap.String("event", rule.Event),
+		zap.Int("level", rule.Level),
+	)
+	response.WriteCreated(c, "Referral rule created successfully", rule)
+}
+
+func (h *referralHandler) UpdateRule(c *gin.Context) {
+	ruleID := c.Param("id")
+	var req model.UpdateReferralRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid update referral rule request",
+			zap.String("rule_id", ruleID),
+			zap.Error(err),
+		)
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	rule, err := h.referralService.UpdateRule(c.Request.Context(), ruleID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Referral rule updated", zap.String("rule_id", rule.ID))
+	response.WriteSuccess(c, "Referral rule updated successfully", rule)
+}
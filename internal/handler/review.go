@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"denet/internal/http/response"
+	"denet/internal/model"
+	"denet/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type TaskReviewHandler interface {
+	ListSubmissions(c *gin.Context)
+	ApproveSubmission(c *gin.Context)
+	RejectSubmission(c *gin.Context)
+	RevokeSubmission(c *gin.Context)
+}
+
+type taskReviewHandler struct {
+	reviewService service.TaskReviewService
+}
+
+func NewTaskReviewHandler(reviewService service.TaskReviewService) TaskReviewHandler {
+	return &taskReviewHandler{
+		reviewService: reviewService,
+	}
+}
+
+func (h *taskReviewHandler) ListSubmissions(c *gin.Context) {
+	status := model.TaskSubmissionStatus(c.DefaultQuery("status", string(model.TaskSubmissionPending)))
+	switch status {
+	case model.TaskSubmissionPending, model.TaskSubmissionApproved, model.TaskSubmissionRejected, model.TaskSubmissionRevoked:
+	default:
+		response.WriteError(c, http.StatusBadRequest, "Invalid status parameter")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		response.WriteError(c, http.StatusBadRequest, "Invalid limit parametr")
+		return
+	}
+
+	page, err := h.reviewService.ListSubmissions(c.Request.Context(), status, c.Query("cursor"), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Task submissions retrieved successfully", page)
+}
+
+func (h *taskReviewHandler) ApproveSubmission(c *gin.Context) {
+	h.review(c, "approved", h.reviewService.Approve)
+}
+
+func (h *taskReviewHandler) RejectSubmission(c *gin.Context) {
+	h.review(c, "rejected", h.reviewService.Reject)
+}
+
+func (h *taskReviewHandler) RevokeSubmission(c *gin.Context) {
+	h.review(c, "revoked", h.reviewService.Revoke)
+}
+
+// review runs one moderator decision. The note in the body is optional.
+func (h *taskReviewHandler) review(c *gin.Context, outcome string,
+	decide func(ctx context.Context, id, reviewerID, note string) (*model.UserTask, error)) {
+	submissionID := c.Param("id")
+	actor := c.MustGet("user_claims").(*model.JWTClaims)
+
+	var req model.ReviewSubmissionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			requestLogger(c).Warn("Invalid review request",
+				zap.String("submission_id", submissionID),
+				zap.Error(err),
+			)
+			response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+	}
+
+	submission, err := decide(c.Request.Context(), submissionID, actor.UserID, req.Note)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Task submission "+outcome,
+		zap.String("submission_id", submission.ID),
+		zap.String("user_id", submission.UserID),
+		zap.String("task_id", submission.TaskID),
+		zap.String("actor_id", actor.UserID),
+	)
+	response.WriteSuccess(c, "Task submission "+outcome+" successfully", submission)
+}
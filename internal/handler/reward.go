@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"context"
+	"denet/internal/http/response"
+	"denet/internal/model"
+	"denet/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type RewardHandler interface {
+	ListRewards(c *gin.Context)
+	Redeem(c *gin.Context)
+	ListUserOrders(c *gin.Context)
+	AdminListRewards(c *gin.Context)
+	AdminGetReward(c *gin.Context)
+	AdminCreateReward(c *gin.Context)
+	AdminUpdateReward(c *gin.Context)
+	AdminArchiveReward(c *gin.Context)
+	AdminListOrders(c *gin.Context)
+	AdminFulfilOrder(c *gin.Context)
+	AdminCancelOrder(c *gin.Context)
+}
+
+type rewardHandler struct {
+	rewardService service.RewardService
+}
+
+func NewRewardHandler(rewardService service.RewardService) RewardHandler {
+	return &rewardHandler{
+		rewardService: rewardService,
+	}
+}
+
+func (h *rewardHandler) ListRewards(c *gin.Context) {
+	rewards, err := h.rewardService.ListAvailable(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Rewards retrieved successfully", gin.H{"rewards": rewards})
+}
+
+func (h *rewardHandler) Redeem(c *gin.Context) {
+	rewardID := c.Param("id")
+	claims := c.MustGet("user_claims").(*model.JWTClaims)
+
+	order, err := h.rewardService.Redeem(c.Request.Context(), claims.UserID, rewardID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Reward redeemed",
+		zap.String("order_id", order.ID),
+		zap.String("user_id", order.UserID),
+		zap.String("reward_id", order.RewardID),
+		zap.Int("cost", order.Cost),
+	)
+	response.WriteCreated(c, "Reward redeemed successfully", order)
+}
+
+func (h *rewardHandler) ListUserOrders(c *gin.Context) {
+	userID := c.Param("id")
+	if !canReadUser(c, userID) {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		response.WriteError(c, http.StatusBadRequest, "Invalid limit parametr")
+		return
+	}
+
+	page, err := h.rewardService.ListUserOrders(c.Request.Context(), userID, c.Query("cursor"), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Reward orders retrieved successfully", page)
+}
+
+func (h *rewardHandler) AdminListRewards(c *gin.Context) {
+	filter := model.RewardFilter{IncludeArchived: c.Query("include_archived") == "true"}
+	rewards, err := h.rewardService.List(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Rewards retrieved successfully", gin.H{
+		"rewards": rewards,
+		"total":   len(rewards),
+	})
+}
+
+func (h *rewardHandler) AdminGetReward(c *gin.Context) {
+	reward, err := h.rewardService.GetReward(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Reward retrieved successfully", reward)
+}
+
+func (h *rewardHandler) AdminCreateReward(c *gin.Context) {
+	var req model.CreateRewardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid create reward request", zap.Error(err))
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	reward, err := h.rewardService.CreateReward(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Reward created",
+		zap.String("reward_id", reward.ID),
+		zap.String("name", reward.Name),
+	)
+	response.WriteCreated(c, "Reward created successfully", reward)
+}
+
+func (h *rewardHandler) AdminUpdateReward(c *gin.Context) {
+	rewardID := c.Param("id")
+	var req model.UpdateRewardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid update reward request",
+			zap.String("reward_id", rewardID),
+			zap.Error(err),
+		)
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	reward, err := h.rewardService.UpdateReward(c.Request.Context(), rewardID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Reward updated", zap.String("reward_id", reward.ID))
+	response.WriteSuccess(c, "Reward updated successfully", reward)
+}
+
+func (h *rewardHandler) AdminArchiveReward(c *gin.Context) {
+	reward, err := h.rewardService.ArchiveReward(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Reward archived", zap.String("reward_id", reward.ID))
+	response.WriteSuccess(c, "Reward archived successfully", reward)
+}
+
+func (h *rewardHandler) AdminListOrders(c *gin.Context) {
+	status := model.RewardOrderStatus(c.DefaultQuery("status", string(model.RewardOrderPending)))
+	switch status {
+	case model.RewardOrderPending, model.RewardOrderFulfilled, model.RewardOrderCancelled:
+	default:
+		response.WriteError(c, http.StatusBadRequest, "Invalid status parameter")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		response.WriteError(c, http.StatusBadRequest, "Invalid limit parametr")
+		return
+	}
+
+	page, err := h.rewardService.ListOrders(c.Request.Context(), status, c.Query("cursor"), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Reward orders retrieved successfully", page)
+}
+
+func (h *rewardHandler) AdminFulfilOrder(c *gin.Context) {
+	h.handleOrder(c, "fulfilled", h.rewardService.Fulfil)
+}
+
+func (h *rewardHandler) AdminCancelOrder(c *gin.Context) {
+	h.handleOrder(c, "cancelled", h.rewardService.Cancel)
+}
+
+// handleOrder settles one order. The note in the body is optional.
+func (h *rewardHandler) handleOrder(c *gin.Context, outcome string,
+	settle func(ctx context.Context, orderID, actorID, note string) (*model.RewardOrder, error)) {
+	orderID := c.Param("id")
+	actor := c.MustGet("user_claims").(*model.JWTClaims)
+
+	var req model.HandleRewardOrderRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			requestLogger(c).Warn("Invalid reward order request",
+				zap.String("order_id", orderID),
+				zap.Error(err),
+			)
+			response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+	}
+
+	order, err := settle(c.Request.Context(), orderID, actor.UserID, req.Note)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Reward order "+outcome,
+		zap.String("order_id", order.ID),
+		zap.String("user_id", order.UserID),
+		zap.String("reward_id", order.RewardID),
+		zap.String("actor_id", actor.UserID),
+	)
+	response.WriteSuccess(c, "Reward order "+outcome+" successfully", order)
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"denet/internal/http/response"
+	"denet/internal/model"
+	"denet/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type RoleHandler interface {
+	ListRoles(c *gin.Context)
+	CreateRole(c *gin.Context)
+	GetUserRoles(c *gin.Context)
+	GrantRole(c *gin.Context)
+	RevokeRole(c *gin.Context)
+	GetAuditLog(c *gin.Context)
+}
+
+type roleHandler struct {
+	roleService service.RoleService
+}
+
+func NewRoleHandler(roleService service.RoleService) RoleHandler {
+	return &roleHandler{
+		roleService: roleService,
+	}
+}
+
+func (h *roleHandler) ListRoles(c *gin.Context) {
+	roles, err := h.roleService.ListRoles(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Roles retrieved successfully", gin.H{"roles": roles})
+}
+
+func (h *roleHandler) CreateRole(c *gin.Context) {
+	var req model.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid create role request", zap.Error(err))
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	role, err := h.roleService.CreateRole(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Role created",
+		zap.String("role", role.Name),
+		zap.Strings("permissions", role.Permissions),
+	)
+	response.WriteCreated(c, "Role created successfully", role)
+}
+
+func (h *roleHandler) GetUserRoles(c *gin.Context) {
+	userID := c.Param("id")
+	roles, err := h.roleService.GetUserRoles(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "User roles retrieved successfully", gin.H{
+		"user_id": userID,
+		"roles":   roles,
+	})
+}
+
+func (h *roleHandler) GrantRole(c *gin.Context) {
+	userID := c.Param("id")
+	actor := c.MustGet("user_claims").(*model.JWTClaims)
+
+	var req model.GrantRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid grant role request",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.roleService.GrantRole(c.Request.Context(), actor.UserID, userID, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Role granted",
+		zap.String("user_id", userID),
+		zap.String("role", req.Role),
+		zap.String("actor_id", actor.UserID),
+	)
+	response.WriteSuccess(c, "Role granted successfully", nil)
+}
+
+func (h *roleHandler) RevokeRole(c *gin.Context) {
+	userID := c.Param("id")
+	roleName := c.Param("role")
+	actor := c.MustGet("user_claims").(*model.JWTClaims)
+
+	if err := h.roleService.RevokeRole(c.Request.Context(), actor.UserID, userID, roleName, c.Query("reason")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Role revoked",
+		zap.String("user_id", userID),
+		zap.String("role", roleName),
+		zap.String("actor_id", actor.UserID),
+	)
+	response.WriteSuccess(c, "Role revoked successfully", nil)
+}
+
+func (h *roleHandler) GetAuditLog(c *gin.Context) {
+	userID := c.Param("id")
+	entries, err := h.roleService.GetAuditLog(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Role audit log retrieved successfully", gin.H{"entries": entries})
+}
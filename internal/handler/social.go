@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"denet/internal/http/response"
+	"denet/internal/model"
+	"denet/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SocialHandler interface {
+	ListAccounts(c *gin.Context)
+	LinkAccount(c *gin.Context)
+	UnlinkAccount(c *gin.Context)
+}
+
+type socialHandler struct {
+	socialService service.SocialService
+}
+
+func NewSocialHandler(socialService service.SocialService) SocialHandler {
+	return &socialHandler{
+		socialService: socialService,
+	}
+}
+
+func (h *socialHandler) ListAccounts(c *gin.Context) {
+	userID := c.Param("id")
+	if !canReadUser(c, userID) {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	accounts, err := h.socialService.ListAccounts(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Social accounts retrieved successfully", gin.H{"accounts": accounts})
+}
+
+func (h *socialHandler) LinkAccount(c *gin.Context) {
+	userID := c.Param("id")
+	provider := model.SocialProvider(c.Param("provider"))
+	jwtClaims := c.MustGet("user_claims").(*model.JWTClaims)
+	if jwtClaims.UserID != userID {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	var req model.LinkSocialAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid link social account request",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	account, err := h.socialService.LinkAccount(c.Request.Context(), userID, provider, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Social account linked",
+		zap.String("user_id", userID),
+		zap.String("provider", string(provider)),
+		zap.String("external_id", account.ExternalID),
+	)
+	response.WriteSuccess(c, "Social account linked successfully", account)
+}
+
+func (h *socialHandler) UnlinkAccount(c *gin.Context) {
+	userID := c.Param("id")
+	provider := model.SocialProvider(c.Param("provider"))
+	jwtClaims := c.MustGet("user_claims").(*model.JWTClaims)
+	if jwtClaims.UserID != userID {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	if err := h.socialService.UnlinkAccount(c.Request.Context(), userID, provider); err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Social account unlinked",
+		zap.String("user_id", userID),
+		zap.String("provider", string(provider)),
+	)
+	response.WriteSuccess(c, "Social account unlinked successfully", nil)
+}
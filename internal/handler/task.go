@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"denet/internal/http/response"
+	"denet/internal/model"
+	"denet/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type TaskHandler interface {
+	ListTasks(c *gin.Context)
+	AdminListTasks(c *gin.Context)
+	AdminGetTask(c *gin.Context)
+	AdminCreateTask(c *gin.Context)
+	AdminUpdateTask(c *gin.Context)
+	AdminArchiveTask(c *gin.Context)
+}
+
+type taskHandler struct {
+	taskService service.TaskService
+}
+
+func NewTaskHandler(taskService service.TaskService) TaskHandler {
+	return &taskHandler{
+		taskService: taskService,
+	}
+}
+
+func (h *taskHandler) ListTasks(c *gin.Context) {
+	tasks, err := h.taskService.ListAvailable(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tasks": tasks})
+}
+
+func (h *taskHandler) AdminListTasks(c *gin.Context) {
+	filter := model.TaskFilter{
+		IncludeArchived:    c.Query("include_archived") == "true",
+		IncludeUnpublished: true,
+	}
+	tasks, err := h.taskService.List(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Tasks retrieved successfully", gin.H{
+		"tasks": tasks,
+		"total": len(tasks),
+	})
+}
+
+func (h *taskHandler) AdminGetTask(c *gin.Context) {
+	taskID := c.Param("id")
+	task, err := h.taskService.GetTask(c.Request.Context(), taskID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Task retrieved successfully", task)
+}
+
+func (h *taskHandler) AdminCreateTask(c *gin.Context) {
+	var req model.CreateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid create task request", zap.Error(err))
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	task, err := h.taskService.CreateTask(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Task created",
+		zap.String("task_id", task.ID),
+		zap.String("name", task.Name),
+	)
+	response.WriteCreated(c, "Task created successfully", task)
+}
+
+func (h *taskHandler) AdminUpdateTask(c *gin.Context) {
+	taskID := c.Param("id")
+	var req model.UpdateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid update task request",
+			zap.String("task_id", taskID),
+			zap.Error(err),
+		)
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	task, err := h.taskService.UpdateTask(c.Request.Context(), taskID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Task updated", zap.String("task_id", task.ID))
+	response.WriteSuccess(c, "Task updated successfully", task)
+}
+
+func (h *taskHandler) AdminArchiveTask(c *gin.Context) {
+	taskID := c.Param("id")
+	task, err := h.taskService.ArchiveTask(c.Request.Context(), taskID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Task archived", zap.String("task_id", task.ID))
+	response.WriteSuccess(c, "Task archived successfully", task)
+}
@@ -14,19 +14,19 @@ import (
 type UserHandler interface {
 	GetUserStatus(c *gin.Context)
 	GetLeaderboard(c *gin.Context)
+	GetRank(c *gin.Context)
 	CompleteTask(c *gin.Context)
 	SetReferrer(c *gin.Context)
+	GetTransactions(c *gin.Context)
 }
 
 type userHandler struct {
 	userService service.UserService
-	logger      *zap.Logger
 }
 
-func NewUserHandler(userService service.UserService, logger *zap.Logger) UserHandler {
+func NewUserHandler(userService service.UserService) UserHandler {
 	return &userHandler{
 		userService: userService,
-		logger:      logger,
 	}
 }
 
@@ -44,28 +44,18 @@ func (h *userHandler) GetUserStatus(c *gin.Context) {
 	}
 
 	jwtClaims := claims.(*model.JWTClaims)
-	if jwtClaims.UserID != userID && jwtClaims.Username != "admin" {
+	if jwtClaims.UserID != userID && !jwtClaims.HasPermission(model.PermUsersRead) {
 		response.WriteError(c, http.StatusForbidden, "Access denied")
 		return
 	}
 
 	userStatus, err := h.userService.GetUserStatus(c.Request.Context(), userID)
 	if err != nil {
-		h.logger.Error("Failed to get user status",
-			zap.String("user_id", userID),
-			zap.Error(err),
-		)
-
-		switch err.Error() {
-		case "user not found":
-			response.WriteError(c, http.StatusNotFound, "User not found")
-		default:
-			response.WriteError(c, http.StatusInternalServerError, "Internal server error")
-		}
+		c.Error(err)
 		return
 	}
 
-	h.logger.Debug("User status retrieved", zap.String("user_id", userID))
+	requestLogger(c).Debug("User status retrieved", zap.String("user_id", userID))
 	response.WriteSuccess(c, "User status retrieved successfully", userStatus)
 }
 
@@ -81,25 +71,33 @@ func (h *userHandler) GetLeaderboard(c *gin.Context) {
 		limit = 100
 	}
 
-	leaderboard, err := h.userService.GetLeaderboard(c.Request.Context(), limit)
+	page, err := h.userService.GetLeaderboard(c.Request.Context(), c.Query("cursor"), limit)
 	if err != nil {
-		h.logger.Error("Failed to get leaderboard",
-			zap.Int("limit", limit),
-			zap.Error(err),
-		)
-		response.WriteError(c, http.StatusInternalServerError, "Internal server error")
+		c.Error(err)
 		return
 	}
 
-	h.logger.Debug("Leaderboard retrieved",
+	requestLogger(c).Debug("Leaderboard retrieved",
 		zap.Int("limit", limit),
-		zap.Int("users_count", len(leaderboard)),
+		zap.Int("users_count", len(page.Entries)),
 	)
-	response.WriteSuccess(c, "Leaderboard retrieved successfully", gin.H{
-		"leaderboard": leaderboard,
-		"limit":       limit,
-		"total":       len(leaderboard),
-	})
+	response.WriteSuccess(c, "Leaderboard retrieved successfully", page)
+}
+
+func (h *userHandler) GetRank(c *gin.Context) {
+	userID := c.Param("id")
+	neighbours, err := strconv.Atoi(c.DefaultQuery("neighbours", "5"))
+	if err != nil || neighbours < 0 {
+		response.WriteError(c, http.StatusBadRequest, "Invalid neighbours parameter")
+		return
+	}
+
+	rank, err := h.userService.GetRank(c.Request.Context(), userID, neighbours)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "User rank retrieved successfully", rank)
 }
 
 func (h *userHandler) CompleteTask(c *gin.Context) {
@@ -123,7 +121,7 @@ func (h *userHandler) CompleteTask(c *gin.Context) {
 
 	var req model.CompleteTaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Warn("Invalid complete task request",
+		requestLogger(c).Warn("Invalid complete task request",
 			zap.String("user_id", userID),
 			zap.Error(err),
 		)
@@ -131,31 +129,23 @@ func (h *userHandler) CompleteTask(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.CompleteTask(c.Request.Context(), userID, req.TaskID); err != nil {
-		h.logger.Error("Failed to complete task",
-			zap.String("user_id", userID),
-			zap.String("task_id", req.TaskID),
-			zap.Error(err),
-		)
-
-		switch err.Error() {
-		case "user not found":
-			response.WriteError(c, http.StatusNotFound, "User not found")
-		case "task not found":
-			response.WriteError(c, http.StatusBadRequest, "Task not found")
-		case "task already completed":
-			response.WriteError(c, http.StatusConflict, "Task already completed")
-		default:
-			response.WriteError(c, http.StatusInternalServerError, "Internal server error")
-		}
+	submission, err := h.userService.CompleteTask(c.Request.Context(), userID, req.TaskID, req.Proof)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	h.logger.Info("Task completed successfully",
+	requestLogger(c).Info("Task submitted",
 		zap.String("user_id", userID),
 		zap.String("task_id", req.TaskID),
+		zap.String("status", string(submission.Status)),
 	)
-	response.WriteSuccess(c, "Task completed successfully", nil)
+	switch submission.Status {
+	case model.TaskSubmissionApproved:
+		response.WriteSuccess(c, "Task completed successfully", submission)
+	default:
+		response.WriteAccepted(c, "Task submitted for review", submission)
+	}
 }
 
 func (h *userHandler) SetReferrer(c *gin.Context) {
@@ -179,7 +169,7 @@ func (h *userHandler) SetReferrer(c *gin.Context) {
 
 	var req model.SetReferrerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Warn("Invalid set referrer request",
+		requestLogger(c).Warn("Invalid set referrer request",
 			zap.String("user_id", userID),
 			zap.Error(err),
 		)
@@ -187,29 +177,54 @@ func (h *userHandler) SetReferrer(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.SetReferrer(c.Request.Context(), userID, req.ReferrerID); err != nil {
-		h.logger.Error("Failed to set referrer",
-			zap.String("user_id", userID),
-			zap.String("referrer_id", req.ReferrerID),
-			zap.Error(err),
-		)
-
-		switch err.Error() {
-		case "user not found":
-			response.WriteError(c, http.StatusNotFound, "User not found")
-		case "referrer already set":
-			response.WriteError(c, http.StatusConflict, "Referrer already set")
-		case "user cannot refer themselves":
-			response.WriteError(c, http.StatusBadRequest, "User cannot refer themselves")
-		default:
-			response.WriteError(c, http.StatusInternalServerError, "Internal server error")
-		}
+	if err := h.userService.SetReferrer(c.Request.Context(), userID, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	h.logger.Info("Referrer set successfully",
+	requestLogger(c).Info("Referrer set successfully",
 		zap.String("user_id", userID),
 		zap.String("referrer_id", req.ReferrerID),
+		zap.String("referral_code", req.ReferralCode),
 	)
 	response.WriteSuccess(c, "Referrer set successfully", nil)
 }
+
+func (h *userHandler) GetTransactions(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		response.WriteError(c, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	claims, exists := c.Get("user_claims")
+	if !exists {
+		response.WriteError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	jwtClaims := claims.(*model.JWTClaims)
+	if jwtClaims.UserID != userID && !jwtClaims.HasPermission(model.PermUsersRead) {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		response.WriteError(c, http.StatusBadRequest, "Invalid limit parametr")
+		return
+	}
+	cursor := c.Query("cursor")
+
+	page, err := h.userService.GetTransactions(c.Request.Context(), userID, cursor, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Debug("Point transactions retrieved",
+		zap.String("user_id", userID),
+		zap.Int("count", len(page.Transactions)),
+	)
+	response.WriteSuccess(c, "Point transactions retrieved successfully", page)
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"denet/internal/http/response"
+	"denet/internal/metrics"
+	"denet/internal/model"
+	"denet/internal/service"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type WalletHandler interface {
+	IssueNonce(c *gin.Context)
+	Login(c *gin.Context)
+	ListWallets(c *gin.Context)
+	LinkWallet(c *gin.Context)
+	SetPrimary(c *gin.Context)
+	UnlinkWallet(c *gin.Context)
+}
+
+type walletHandler struct {
+	walletService service.WalletService
+	authService   service.AuthService
+	metrics       *metrics.Metrics
+}
+
+func NewWalletHandler(walletService service.WalletService, authService service.AuthService, metrics *metrics.Metrics) WalletHandler {
+	return &walletHandler{
+		walletService: walletService,
+		authService:   authService,
+		metrics:       metrics,
+	}
+}
+
+func (h *walletHandler) IssueNonce(c *gin.Context) {
+	nonce, err := h.walletService.IssueNonce(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteCreated(c, "Nonce issued successfully", nonce)
+}
+
+func (h *walletHandler) Login(c *gin.Context) {
+	var req model.WalletSignatureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid wallet login request", zap.Error(err))
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	user, err := h.walletService.Authenticate(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidWalletSignature) {
+			requestLogger(c).Warn("Failed wallet login attempt")
+		}
+		h.metrics.LoginFailed(metrics.MethodWallet)
+		c.Error(err)
+		return
+	}
+	h.metrics.LoginSucceeded(metrics.MethodWallet)
+
+	tokens, err := h.authService.IssueTokens(c.Request.Context(), user)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("User logged in with wallet",
+		zap.String("user_id", user.ID),
+		zap.String("username", user.Username),
+	)
+	response.WriteSuccess(c, "Login successful", authResponse(tokens, user))
+}
+
+func (h *walletHandler) ListWallets(c *gin.Context) {
+	userID := c.Param("id")
+	if !canReadUser(c, userID) {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	wallets, err := h.walletService.ListWallets(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.WriteSuccess(c, "Wallets retrieved successfully", gin.H{"wallets": wallets})
+}
+
+func (h *walletHandler) LinkWallet(c *gin.Context) {
+	userID := c.Param("id")
+	jwtClaims := c.MustGet("user_claims").(*model.JWTClaims)
+	if jwtClaims.UserID != userID {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	var req model.WalletSignatureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLogger(c).Warn("Invalid link wallet request",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		response.WriteError(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	wallet, err := h.walletService.LinkWallet(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Wallet linked",
+		zap.String("user_id", userID),
+		zap.String("address", wallet.Address),
+		zap.Int64("chain_id", wallet.ChainID),
+	)
+	response.WriteCreated(c, "Wallet linked successfully", wallet)
+}
+
+func (h *walletHandler) SetPrimary(c *gin.Context) {
+	userID := c.Param("id")
+	address := c.Param("address")
+	jwtClaims := c.MustGet("user_claims").(*model.JWTClaims)
+	if jwtClaims.UserID != userID {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	if err := h.walletService.SetPrimary(c.Request.Context(), userID, address); err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Primary wallet changed",
+		zap.String("user_id", userID),
+		zap.String("address", address),
+	)
+	response.WriteSuccess(c, "Primary wallet updated successfully", nil)
+}
+
+func (h *walletHandler) UnlinkWallet(c *gin.Context) {
+	userID := c.Param("id")
+	address := c.Param("address")
+	jwtClaims := c.MustGet("user_claims").(*model.JWTClaims)
+	if jwtClaims.UserID != userID {
+		response.WriteError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	if err := h.walletService.UnlinkWallet(c.Request.Context(), userID, address); err != nil {
+		c.Error(err)
+		return
+	}
+
+	requestLogger(c).Info("Wallet unlinked",
+		zap.String("user_id", userID),
+		zap.String("address", address),
+	)
+	response.WriteSuccess(c, "Wallet unlinked successfully", nil)
+}
@@ -0,0 +1,159 @@
+// Package health runs the dependency checks behind the readiness probe.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// ErrShuttingDown is reported once the server started draining.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// Check reports a dependency as healthy by returning nil. It must give up
+// when ctx is done.
+type Check func(ctx context.Context) error
+
+type Component struct {
+	Name      string  `json:"name"`
+	Status    Status  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of one round of checks. Status is down if any
+// critical component is.
+type Report struct {
+	Status     Status      `json:"status"`
+	CheckedAt  time.Time   `json:"checked_at"`
+	Components []Component `json:"components"`
+}
+
+type check struct {
+	name     string
+	critical bool
+	run      Check
+}
+
+// Registry holds the named checks. Reports are cached for a short while so
+// that frequent probes don't hammer the dependencies.
+type Registry struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu     sync.Mutex
+	checks []check
+	cached *Report
+
+	shuttingDown atomic.Bool
+}
+
+// NewRegistry returns a registry giving each check timeout to answer and
+// reusing a report for cacheTTL.
+func NewRegistry(timeout, cacheTTL time.Duration) *Registry {
+	return &Registry{timeout: timeout, cacheTTL: cacheTTL}
+}
+
+// Register adds a check. A failing critical check makes the service unready;
+// other failures only show up in the report.
+func (r *Registry) Register(name string, critical bool, run Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check{name: name, critical: critical, run: run})
+	r.cached = nil
+}
+
+// SetShuttingDown makes every following report fail, so that load balancers
+// stop routing new requests while in-flight ones drain.
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Readiness runs the checks, or returns the cached report if it is recent.
+// Concurrent callers share one round of checks.
+func (r *Registry) Readiness(ctx context.Context) *Report {
+	if r.shuttingDown.Load() {
+		return &Report{
+			Status:    StatusDown,
+			CheckedAt: time.Now().UTC(),
+			Components: []Component{{
+				Name:     "shutdown",
+				Status:   StatusDown,
+				Critical: true,
+				Error:    ErrShuttingDown.Error(),
+			}},
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cached != nil && time.Since(r.cached.CheckedAt) < r.cacheTTL {
+		return r.cached
+	}
+
+	// The report is shared, so one caller going away must not fail it.
+	ctx = context.WithoutCancel(ctx)
+	report := &Report{
+		Status:     StatusUp,
+		CheckedAt:  time.Now().UTC(),
+		Components: make([]Component, len(r.checks)),
+	}
+	var wg sync.WaitGroup
+	for i, c := range r.checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			report.Components[i] = r.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+	for _, component := range report.Components {
+		if component.Critical && component.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+	r.cached = report
+	return report
+}
+
+// run runs one check under the registry timeout. A check that ignores its
+// context is abandoned, not waited for.
+func (r *Registry) run(ctx context.Context, c check) Component {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() { result <- c.run(ctx) }()
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	component := Component{
+		Name:      c.name,
+		Status:    StatusUp,
+		Critical:  c.critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		component.Status = StatusDown
+		component.Error = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			component.Error = "timed out after " + r.timeout.String()
+		}
+	}
+	return component
+}
@@ -0,0 +1,113 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadiness(t *testing.T) {
+	registry := NewRegistry(50*time.Millisecond, time.Hour)
+	registry.Register("db", true, func(ctx context.Context) error { return nil })
+	registry.Register("cache", false, func(ctx context.Context) error { return errors.New("connection refused") })
+
+	report := registry.Readiness(context.Background())
+	if report.Status != StatusUp {
+		t.Errorf("Status = %s with only a non-critical failure, want up", report.Status)
+	}
+	want := []Component{
+		{Name: "db", Status: StatusUp, Critical: true},
+		{Name: "cache", Status: StatusDown, Critical: false, Error: "connection refused"},
+	}
+	for i, c := range report.Components {
+		c.LatencyMS = 0
+		if c != want[i] {
+			t.Errorf("Components[%d] = %+v, want %+v", i, c, want[i])
+		}
+	}
+
+	registry.Register("queue", true, func(ctx context.Context) error { return errors.New("no route to host") })
+	if report := registry.Readiness(context.Background()); report.Status != StatusDown {
+		t.Errorf("Status = %s with a critical failure, want down", report.Status)
+	}
+}
+
+func TestReadinessTimeout(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	registry := NewRegistry(timeout, 0)
+	registry.Register("obeys context", true, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	// A check that ignores its context is not waited for.
+	stuck := make(chan struct{})
+	t.Cleanup(func() { close(stuck) })
+	registry.Register("ignores context", false, func(ctx context.Context) error {
+		<-stuck
+		return nil
+	})
+
+	start := time.Now()
+	report := registry.Readiness(context.Background())
+	if elapsed := time.Since(start); elapsed > 20*timeout {
+		t.Errorf("Readiness took %s with a %s timeout", elapsed, timeout)
+	}
+	if report.Status != StatusDown {
+		t.Errorf("Status = %s, want down", report.Status)
+	}
+	for _, c := range report.Components {
+		if c.Status != StatusDown || c.Error != "timed out after 50ms" {
+			t.Errorf("%s = %s %q, want down, timed out after 50ms", c.Name, c.Status, c.Error)
+		}
+	}
+}
+
+func TestReadinessCache(t *testing.T) {
+	registry := NewRegistry(time.Second, time.Hour)
+	var runs atomic.Int32
+	registry.Register("db", true, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	first := registry.Readiness(context.Background())
+	if second := registry.Readiness(context.Background()); second != first {
+		t.Error("second report within the TTL was not the cached one")
+	}
+	if n := runs.Load(); n != 1 {
+		t.Errorf("check ran %d times within the TTL, want 1", n)
+	}
+
+	// The cache is bypassed once it is older than the TTL.
+	registry.mu.Lock()
+	registry.cached.CheckedAt = time.Now().Add(-2 * time.Hour)
+	registry.mu.Unlock()
+	registry.Readiness(context.Background())
+	if n := runs.Load(); n != 2 {
+		t.Errorf("check ran %d times after the TTL, want 2", n)
+	}
+
+	// A caller cancelling does not fail the shared round.
+	registry.Register("cache", false, func(ctx context.Context) error { return ctx.Err() })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report := registry.Readiness(ctx); report.Status != StatusUp {
+		t.Errorf("Status = %s for a cancelled caller, want up", report.Status)
+	}
+}
+
+func TestReadinessShuttingDown(t *testing.T) {
+	registry := NewRegistry(time.Second, time.Hour)
+	registry.Register("db", true, func(ctx context.Context) error { return nil })
+	if report := registry.Readiness(context.Background()); report.Status != StatusUp {
+		t.Fatalf("Status = %s, want up", report.Status)
+	}
+
+	registry.SetShuttingDown()
+	report := registry.Readiness(context.Background())
+	if report.Status != StatusDown || len(report.Components) != 1 || report.Components[0].Error != ErrShuttingDown.Error() {
+		t.Errorf("report while shutting down = %+v, want down with %q", *report, ErrShuttingDown)
+	}
+}
@@ -1,14 +1,18 @@
 package response
 
 import (
+	"denet/internal/apperror"
+	"denet/internal/logging"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Details string `json:"details,omitempty"`
+	Code      string `json:"code"`
+	Error     string `json:"error"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 type SuccessResponse struct {
@@ -16,9 +20,13 @@ type SuccessResponse struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// WriteError writes an error without a kind of its own; its code is derived
+// from the status.
 func WriteError(c *gin.Context, status int, message string, details ...string) {
 	errorResponse := ErrorResponse{
-		Error: message,
+		Code:      apperror.CodeForStatus(status),
+		Error:     message,
+		RequestID: logging.RequestID(c.Request.Context()),
 	}
 	if len(details) > 0 {
 		errorResponse.Details = details[0]
@@ -26,6 +34,16 @@ func WriteError(c *gin.Context, status int, message string, details ...string) {
 	c.JSON(status, errorResponse)
 }
 
+// WriteAppError writes a typed error. The cause is never sent.
+func WriteAppError(c *gin.Context, err *apperror.Error) {
+	c.JSON(err.Status, ErrorResponse{
+		Code:      err.Code,
+		Error:     err.Message,
+		Details:   err.Details,
+		RequestID: logging.RequestID(c.Request.Context()),
+	})
+}
+
 func WriteSuccess(c *gin.Context, message string, data interface{}) {
 	c.JSON(http.StatusOK, SuccessResponse{
 		Message: message,
@@ -33,6 +51,14 @@ func WriteSuccess(c *gin.Context, message string, data interface{}) {
 	})
 }
 
+// WriteAccepted reports a request that was taken but not yet acted on.
+func WriteAccepted(c *gin.Context, message string, data interface{}) {
+	c.JSON(http.StatusAccepted, SuccessResponse{
+		Message: message,
+		Data:    data,
+	})
+}
+
 func WriteCreated(c *gin.Context, message string, data interface{}) {
 	c.JSON(http.StatusCreated, SuccessResponse{
 		Message: message,
@@ -1,36 +1,182 @@
 package http
 
 import (
-	"denet/config"
 	"denet/internal/handler"
 	"denet/internal/handler/middleware"
+	"denet/internal/health"
+	"denet/internal/http/response"
+	"denet/internal/jwks"
+	"denet/internal/metrics"
+	"denet/internal/model"
+	"denet/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-func NewRoute(authHandler handler.AuthHandler, userHandler handler.UserHandler, conf config.Config, logger *zap.Logger) *gin.Engine {
+type Handlers struct {
+	Auth        handler.AuthHandler
+	User        handler.UserHandler
+	Task        handler.TaskHandler
+	Role        handler.RoleHandler
+	Referral    handler.ReferralHandler
+	Leaderboard handler.LeaderboardHandler
+	TaskReview  handler.TaskReviewHandler
+	Social      handler.SocialHandler
+	Wallet      handler.WalletHandler
+	Airdrop     handler.AirdropHandler
+	Reward      handler.RewardHandler
+}
+
+// Dependencies are what the router's middleware needs beyond the handlers.
+type Dependencies struct {
+	Keys        *jwks.KeyRing
+	Revocations middleware.RevocationChecker
+	Idempotency middleware.IdempotencyStore
+	Health      *health.Registry
+	Metrics     *metrics.Metrics
+	RateLimiter ratelimit.Store
+	RateLimits  RateLimits
+}
+
+// RateLimits are the token bucket limits of the API. PerIP applies to every
+// request and PerUser to every authenticated one; Auth applies per IP and
+// route to the endpoints issuing tokens, to slow down credential guessing.
+type RateLimits struct {
+	PerIP   ratelimit.Limit
+	PerUser ratelimit.Limit
+	Auth    ratelimit.Limit
+}
+
+func NewRoute(h Handlers, deps Dependencies, logger *zap.Logger) *gin.Engine {
 	r := gin.New()
+	authMiddleware := middleware.AuthMiddleware(deps.Keys.Keyfunc, deps.Revocations)
+	// Idempotency only acts on mutating methods, so it is applied per group
+	// after authentication to scope keys by user. Errors are rendered inside
+	// it so that the stored response is the one the client saw.
+	idempotency := middleware.Idempotency(deps.Idempotency)
+	errorHandler := middleware.ErrorHandler()
+	ipLimit := middleware.RateLimit(deps.RateLimiter, "ip", deps.RateLimits.PerIP, middleware.RateLimitByIP)
+	userLimit := middleware.RateLimit(deps.RateLimiter, "user", deps.RateLimits.PerUser, middleware.RateLimitByUser)
+	authLimit := middleware.RateLimit(deps.RateLimiter, "auth", deps.RateLimits.Auth, middleware.RateLimitByRouteAndIP)
 
-	r.Use(middleware.Logger(logger))
-	r.Use(middleware.Recovery(logger))
+	// Probes are registered ahead of the request logger so that they don't
+	// flood the log.
+	r.GET("/livez", middleware.Recovery(), livez)
+	r.GET("/readyz", middleware.Recovery(), readiness(deps.Health, false))
+
+	r.Use(middleware.Tracing())
+	r.Use(middleware.RequestID(logger))
+	r.Use(middleware.Logger())
+	r.Use(middleware.Metrics(deps.Metrics))
+	r.Use(middleware.Recovery())
 	r.Use(middleware.CORS())
+	r.Use(ipLimit)
+
+	r.GET("/.well-known/jwks.json", jwksHandler(deps.Keys))
 
 	public := r.Group("/api")
+	public.Use(errorHandler)
 	{
-		public.GET("/health", healthCheck)
-		public.POST("/auth/register", authHandler.Register)
-		public.POST("/auth/login", authHandler.Login)
-		public.GET("/tasks", getTasksList)
+		public.GET("/health", readiness(deps.Health, false))
+		public.GET("/tasks", h.Task.ListTasks)
+		public.GET("/rewards", h.Reward.ListRewards)
+		public.GET("/leaderboards/:window", h.Leaderboard.GetWindowLeaderboard)
+		public.GET("/seasons", h.Leaderboard.ListSeasons)
+		public.GET("/seasons/:id/results", h.Leaderboard.GetSeasonResults)
+	}
+
+	// Sign-in routes answer with tokens, which must not sit in the
+	// idempotency store, so they go without it. Register honours
+	// Idempotency-Key itself and issues fresh tokens on a retry.
+	auth := r.Group("/api/auth")
+	auth.Use(authLimit, errorHandler)
+	{
+		auth.POST("/register", h.Auth.Register)
+		auth.POST("/login", h.Auth.Login)
+		auth.POST("/telegram", h.Auth.TelegramLogin)
+		auth.POST("/wallet/nonce", h.Wallet.IssueNonce)
+		auth.POST("/wallet", h.Wallet.Login)
+		auth.POST("/refresh", h.Auth.Refresh)
 	}
 
 	protected := r.Group("/api")
-	protected.Use(middleware.AuthMiddleware(conf.JWT.SecretKey, logger))
+	protected.Use(authMiddleware, userLimit, idempotency, errorHandler)
 	{
-		protected.GET("/users/:id/status", userHandler.GetUserStatus)
-		protected.GET("/users/leaderboard", userHandler.GetLeaderboard)
-		protected.POST("/users/:id/task/complete", userHandler.CompleteTask)
-		protected.POST("/users/:id/referrer", userHandler.SetReferrer)
+		protected.POST("/auth/logout", h.Auth.Logout)
+		protected.POST("/auth/logout-all", h.Auth.LogoutAll)
+		protected.POST("/auth/telegram/link", h.Auth.LinkTelegram)
+		protected.GET("/users/:id/status", h.User.GetUserStatus)
+		protected.GET("/users/:id/transactions", h.User.GetTransactions)
+		protected.GET("/users/leaderboard", h.User.GetLeaderboard)
+		protected.GET("/users/:id/rank", h.User.GetRank)
+		protected.POST("/users/:id/task/complete", h.User.CompleteTask)
+		protected.POST("/users/:id/referrer", h.User.SetReferrer)
+		protected.PUT("/users/:id/referral-code", h.Referral.SetReferralCode)
+		protected.GET("/users/:id/referrals", h.Referral.GetReferees)
+		protected.GET("/users/:id/referrals/tree", h.Referral.GetTree)
+		protected.GET("/users/:id/referrals/stats", h.Referral.GetStats)
+		protected.GET("/users/:id/social-accounts", h.Social.ListAccounts)
+		protected.PUT("/users/:id/social-accounts/:provider", h.Social.LinkAccount)
+		protected.DELETE("/users/:id/social-accounts/:provider", h.Social.UnlinkAccount)
+		protected.GET("/users/:id/wallets", h.Wallet.ListWallets)
+		protected.POST("/users/:id/wallets", h.Wallet.LinkWallet)
+		protected.PUT("/users/:id/wallets/:address/primary", h.Wallet.SetPrimary)
+		protected.DELETE("/users/:id/wallets/:address", h.Wallet.UnlinkWallet)
+		protected.GET("/users/:id/airdrop-proof", h.Airdrop.GetProof)
+		protected.GET("/users/:id/reward-orders", h.Reward.ListUserOrders)
+		protected.POST("/rewards/:id/redeem", h.Reward.Redeem)
+	}
+
+	admin := r.Group("/api/admin")
+	admin.Use(authMiddleware, userLimit, idempotency, errorHandler)
+	{
+		tasks := admin.Group("/tasks", middleware.RequirePermission(model.PermTasksManage))
+		tasks.GET("", h.Task.AdminListTasks)
+		tasks.POST("", h.Task.AdminCreateTask)
+		tasks.GET("/:id", h.Task.AdminGetTask)
+		tasks.PATCH("/:id", h.Task.AdminUpdateTask)
+		tasks.DELETE("/:id", h.Task.AdminArchiveTask)
+
+		submissions := admin.Group("/task-submissions", middleware.RequirePermission(model.PermTasksReview))
+		submissions.GET("", h.TaskReview.ListSubmissions)
+		submissions.POST("/:id/approve", h.TaskReview.ApproveSubmission)
+		submissions.POST("/:id/reject", h.TaskReview.RejectSubmission)
+		submissions.POST("/:id/revoke", h.TaskReview.RevokeSubmission)
+
+		roles := admin.Group("", middleware.RequirePermission(model.PermRolesManage))
+		roles.GET("/roles", h.Role.ListRoles)
+		roles.POST("/roles", h.Role.CreateRole)
+		roles.GET("/users/:id/roles", h.Role.GetUserRoles)
+		roles.POST("/users/:id/roles", h.Role.GrantRole)
+		roles.DELETE("/users/:id/roles/:role", h.Role.RevokeRole)
+		roles.GET("/users/:id/roles/audit", h.Role.GetAuditLog)
+
+		lockouts := admin.Group("/users/:id/lockout", middleware.RequirePermission(model.PermUsersUnlock))
+		lockouts.GET("", h.Auth.AdminGetLockout)
+		lockouts.DELETE("", h.Auth.AdminUnlock)
+
+		referralRules := admin.Group("/referral-rules", middleware.RequirePermission(model.PermReferralsManage))
+		referralRules.GET("", h.Referral.ListRules)
+		referralRules.POST("", h.Referral.CreateRule)
+		referralRules.PATCH("/:id", h.Referral.UpdateRule)
+
+		seasons := admin.Group("/seasons", middleware.RequirePermission(model.PermSeasonsManage))
+		seasons.POST("", h.Leaderboard.CreateSeason)
+
+		rewards := admin.Group("", middleware.RequirePermission(model.PermRewardsManage))
+		rewards.GET("/rewards", h.Reward.AdminListRewards)
+		rewards.POST("/rewards", h.Reward.AdminCreateReward)
+		rewards.GET("/rewards/:id", h.Reward.AdminGetReward)
+		rewards.PATCH("/rewards/:id", h.Reward.AdminUpdateReward)
+		rewards.DELETE("/rewards/:id", h.Reward.AdminArchiveReward)
+		rewards.GET("/reward-orders", h.Reward.AdminListOrders)
+		rewards.POST("/reward-orders/:id/fulfil", h.Reward.AdminFulfilOrder)
+		rewards.POST("/reward-orders/:id/cancel", h.Reward.AdminCancelOrder)
+
+		airdrops := admin.Group("/airdrops", middleware.RequirePermission(model.PermAirdropsManage))
+		airdrops.GET("", h.Airdrop.AdminListAirdrops)
+		airdrops.POST("", h.Airdrop.AdminCreateAirdrop)
 	}
 
 	// 404 handler
@@ -39,28 +185,58 @@ func NewRoute(authHandler handler.AuthHandler, userHandler handler.UserHandler,
 	return r
 }
 
-func healthCheck(c *gin.Context) {
-	c.JSON(200, gin.H{
-		"status":  "OK",
-		"service": "user-rewards",
-		"version": "1.0.0",
-	})
+// NewInternalRoute serves the metrics scrape and the probes with the health
+// of every component. It is meant for the internal listener only.
+func NewInternalRoute(deps Dependencies) *gin.Engine {
+	r := gin.New()
+	r.Use(middleware.Recovery())
+
+	r.GET("/livez", livez)
+	r.GET("/readyz", readiness(deps.Health, true))
+	r.GET("/metrics", gin.WrapH(deps.Metrics.Handler()))
+
+	r.NoRoute(notFoundHandler)
+	return r
+}
+
+// livez only tells that the process serves requests; dependencies are left
+// to readyz so that an outage does not get every instance restarted.
+func livez(c *gin.Context) {
+	c.JSON(200, gin.H{"status": health.StatusUp})
+}
+
+// readiness reports the readiness of the dependencies, failing with 503
+// while any is down. The components are only listed when detailed is set, as
+// their errors come straight from the dependencies and are not for anonymous
+// callers.
+func readiness(registry *health.Registry, detailed bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := registry.Readiness(c.Request.Context())
+		status := 200
+		if report.Status != health.StatusUp {
+			status = 503
+		}
+		body := gin.H{
+			"status":     report.Status,
+			"service":    "user-rewards",
+			"version":    "1.0.0",
+			"checked_at": report.CheckedAt,
+		}
+		if detailed {
+			body["components"] = report.Components
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(status, body)
+	}
 }
 
-func getTasksList(c *gin.Context) {
-	tasks := []map[string]interface{}{
-		{"id": "1", "name": "referral", "description": "Пригласить друга по реферальному коду", "points": 100},
-		{"id": "2", "name": "telegram", "description": "Подписаться на Telegram канал", "points": 50},
-		{"id": "3", "name": "twitter", "description": "Подписаться на Twitter", "points": 50},
-		{"id": "4", "name": "discord", "description": "Присоединиться к Discord серверу", "points": 75},
-		{"id": "5", "name": "profile", "description": "Заполнить профиль", "points": 25},
+func jwksHandler(keys *jwks.KeyRing) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(200, keys.PublicSet())
 	}
-	c.JSON(200, gin.H{"tasks": tasks})
 }
 
 func notFoundHandler(c *gin.Context) {
-	c.JSON(404, gin.H{
-		"error":   "endpoint not found",
-		"message": "check the API documentation for available endpoints",
-	})
+	response.WriteError(c, 404, "endpoint not found", "check the API documentation for available endpoints")
 }
@@ -0,0 +1,366 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	rsaKeyBits      = 2048
+	pemCreatedField = "Created"
+)
+
+var (
+	ErrUnknownKey        = errors.New("unknown signing key")
+	ErrUnsupportedAlg    = errors.New("unsupported signing algorithm")
+	ErrNoActiveKey       = errors.New("no active signing key")
+	ErrAlgorithmMismatch = errors.New("token algorithm does not match key")
+	ErrRetentionTooShort = errors.New("key retention is shorter than the token lifetime")
+)
+
+type Config struct {
+	Algorithm string
+	// Dir holds one PKCS#8 PEM file per key, named <kid>.pem. Missing
+	// directories are created and an initial key is generated on first boot.
+	// Instances sharing the directory share the keys: each picks up the keys
+	// the others created when it checks the rotation schedule, and on
+	// meeting a token with a kid it has not loaded yet.
+	Dir string
+	// RotationInterval is how long a key signs new tokens before a fresh key
+	// replaces it. Zero disables rotation.
+	RotationInterval time.Duration
+	// Retention is how long a rotated-out key stays available for
+	// verification. It must be at least TokenTTL.
+	Retention time.Duration
+	// TokenTTL is the lifetime of the tokens the ring signs.
+	TokenTTL time.Duration
+}
+
+type Key struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	CreatedAt time.Time
+}
+
+// KeyRing signs tokens with the newest key and verifies tokens signed by any
+// key that has not yet been retired.
+type KeyRing struct {
+	mu     sync.RWMutex
+	keys   []*Key // newest first
+	config Config
+}
+
+func LoadOrCreate(config Config) (*KeyRing, error) {
+	if config.Algorithm != AlgRS256 && config.Algorithm != AlgEdDSA {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, config.Algorithm)
+	}
+	// Tokens signed just before a rotation would otherwise outlive their key.
+	if config.Retention < config.TokenTTL {
+		return nil, fmt.Errorf("%w: %s < %s", ErrRetentionTooShort, config.Retention, config.TokenTTL)
+	}
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	ring := &KeyRing{config: config}
+	if _, err := ring.RotateIfDue(time.Now()); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// Sign signs claims with the active key and sets the kid header.
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	r.mu.RLock()
+	key := r.activeKey()
+	r.mu.RUnlock()
+	if key == nil {
+		return "", ErrNoActiveKey
+	}
+
+	token := jwt.NewWithClaims(signingMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc resolves the verification key for a token by its kid header. A kid
+// the ring does not hold is looked up in the key directory, where another
+// instance may have just created it.
+func (r *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key := r.find(kid)
+	if key == nil {
+		var err error
+		if key, err = r.loadKey(kid); err != nil {
+			return nil, err
+		}
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, ErrAlgorithmMismatch
+	}
+	return key.Private.Public(), nil
+}
+
+func (r *KeyRing) find(kid string) *Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, key := range r.keys {
+		if key.ID == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+// loadKey reads the key kid from the directory and adds it to the ring. Kids
+// are generated as UUIDs, so anything else is rejected without touching the
+// file system.
+func (r *KeyRing) loadKey(kid string) (*Key, error) {
+	if _, err := uuid.Parse(kid); err != nil {
+		return nil, ErrUnknownKey
+	}
+	key, err := readKey(r.keyPath(kid))
+	if os.IsNotExist(err) {
+		return nil, ErrUnknownKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key %s: %w", kid, err)
+	}
+	if key.Algorithm != r.config.Algorithm {
+		return nil, ErrUnknownKey
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, known := range r.keys {
+		if known.ID == kid {
+			return known, nil
+		}
+	}
+	r.keys = append(r.keys, key)
+	sortKeys(r.keys)
+	return key, nil
+}
+
+// reload replaces the ring's keys with those in the directory.
+func (r *KeyRing) reload() error {
+	paths, err := filepath.Glob(filepath.Join(r.config.Dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	keys := make([]*Key, 0, len(paths))
+	for _, path := range paths {
+		key, err := readKey(path)
+		if os.IsNotExist(err) {
+			// Retired by another instance since the glob.
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load key %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	sortKeys(keys)
+	r.keys = keys
+	return nil
+}
+
+func sortKeys(keys []*Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+}
+
+// RotateIfDue reloads the key directory, then generates a new active key
+// when there is none or the newest one is older than the rotation interval,
+// and retires keys past retention. Reloading first lets instances sharing
+// the directory adopt a key one of them created instead of each making
+// their own. It reports whether a new key was created.
+func (r *KeyRing) RotateIfDue(now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.reload(); err != nil {
+		return false, err
+	}
+	rotated := false
+	active := r.activeKey()
+	if active == nil || (r.config.RotationInterval > 0 && now.Sub(active.CreatedAt) >= r.config.RotationInterval) {
+		key, err := r.generate(now)
+		if err != nil {
+			return false, err
+		}
+		r.keys = append([]*Key{key}, r.keys...)
+		rotated = true
+	}
+
+	// A key stops signing when its successor is created; from then on it is
+	// only kept for Retention.
+	kept := r.keys[:1]
+	for i := 1; i < len(r.keys); i++ {
+		retiredAt := r.keys[i-1].CreatedAt
+		if now.Sub(retiredAt) < r.config.Retention {
+			kept = append(kept, r.keys[i])
+			continue
+		}
+		if err := os.Remove(r.keyPath(r.keys[i].ID)); err != nil && !os.IsNotExist(err) {
+			return rotated, err
+		}
+	}
+	r.keys = kept
+	return rotated, nil
+}
+
+// RunRotation checks the rotation schedule until ctx is cancelled.
+func (r *KeyRing) RunRotation(ctx context.Context, logger *zap.Logger) {
+	if r.config.RotationInterval <= 0 {
+		return
+	}
+	checkEvery := r.config.RotationInterval / 10
+	if checkEvery > time.Hour {
+		checkEvery = time.Hour
+	}
+	ticker := time.NewTicker(checkEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			rotated, err := r.RotateIfDue(now)
+			if err != nil {
+				logger.Error("Failed to rotate signing keys", zap.Error(err))
+				continue
+			}
+			if rotated {
+				logger.Info("Signing key rotated", zap.String("kid", r.ActiveKeyID()))
+			}
+		}
+	}
+}
+
+func (r *KeyRing) ActiveKeyID() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if key := r.activeKey(); key != nil {
+		return key.ID
+	}
+	return ""
+}
+
+func (r *KeyRing) activeKey() *Key {
+	if len(r.keys) == 0 {
+		return nil
+	}
+	return r.keys[0]
+}
+
+func (r *KeyRing) generate(now time.Time) (*Key, error) {
+	var private crypto.Signer
+	switch r.config.Algorithm {
+	case AlgRS256:
+		rsaKey, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		private = rsaKey
+	case AlgEdDSA:
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private = edKey
+	}
+
+	key := &Key{
+		ID:        uuid.New().String(),
+		Algorithm: r.config.Algorithm,
+		Private:   private,
+		CreatedAt: now.UTC(),
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	block := &pem.Block{
+		Type:    "PRIVATE KEY",
+		Headers: map[string]string{pemCreatedField: key.CreatedAt.Format(time.RFC3339)},
+		Bytes:   der,
+	}
+	if err := os.WriteFile(r.keyPath(key.ID), pem.EncodeToMemory(block), 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *KeyRing) keyPath(kid string) string {
+	return filepath.Join(r.config.Dir, kid+".pem")
+}
+
+func readKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm, key.Private = AlgRS256, private
+	case ed25519.PrivateKey:
+		key.Algorithm, key.Private = AlgEdDSA, private
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedAlg, parsed)
+	}
+
+	// Keys provisioned by hand may not carry the header; the file time is
+	// the best available approximation of when they were created.
+	if created, ok := block.Headers[pemCreatedField]; ok {
+		if key.CreatedAt, err = time.Parse(time.RFC3339, created); err != nil {
+			return nil, err
+		}
+	} else {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		key.CreatedAt = info.ModTime().UTC()
+	}
+	return key, nil
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	if alg == AlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
@@ -0,0 +1,102 @@
+package leaderboard
+
+import (
+	"context"
+	"denet/internal/model"
+	"sync"
+)
+
+// MemoryStore keeps the index in process. It is the default backend for a
+// single instance deployment.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	list  *skiplist
+	users map[string]model.LeaderboardUser
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		list:  newSkiplist(),
+		users: make(map[string]model.LeaderboardUser),
+	}
+}
+
+func (s *MemoryStore) Replace(ctx context.Context, entries []model.LeaderboardUser) error {
+	list := newSkiplist()
+	users := make(map[string]model.LeaderboardUser, len(entries))
+	for _, entry := range entries {
+		entry.Rank = 0
+		if _, ok := users[entry.ID]; ok {
+			continue
+		}
+		list.insert(entry)
+		users[entry.ID] = entry
+	}
+
+	s.mu.Lock()
+	s.list, s.users = list, users
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Upsert(ctx context.Context, entry model.LeaderboardUser) error {
+	entry.Rank = 0
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.users[entry.ID]; ok {
+		s.list.remove(old)
+	}
+	s.list.insert(entry)
+	s.users[entry.ID] = entry
+	return nil
+}
+
+func (s *MemoryStore) Remove(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.users[userID]; ok {
+		s.list.remove(old)
+		delete(s.users, userID)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, userID string) (*model.LeaderboardUser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	entry.Rank = s.list.countThrough(entry)
+	return &entry, nil
+}
+
+func (s *MemoryStore) CountThrough(ctx context.Context, key model.LeaderboardUser) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.list.countThrough(key), nil
+}
+
+func (s *MemoryStore) Range(ctx context.Context, offset, limit int) ([]model.LeaderboardUser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := []model.LeaderboardUser{}
+	if offset < 0 || limit <= 0 || offset >= s.list.length {
+		return entries, nil
+	}
+	x := s.list.byRank(offset + 1)
+	for rank := offset + 1; x != nil && len(entries) < limit; rank++ {
+		entry := x.entry
+		entry.Rank = rank
+		entries = append(entries, entry)
+		x = x.next[0].node
+	}
+	return entries, nil
+}
+
+func (s *MemoryStore) Len(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.list.length, nil
+}
@@ -0,0 +1,248 @@
+package leaderboard
+
+import (
+	"context"
+	"denet/internal/model"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// replaceBatch bounds the size of a single ZADD/HSET while rebuilding the
+// index.
+const replaceBatch = 1000
+
+// RedisStore keeps the index in a Redis sorted set so that several instances
+// share one leaderboard. Any server speaking the Redis protocol with
+// scripting support works, including in-memory stand-ins.
+//
+// The score is the negated balance. Members are "<reached_at unix nanos,
+// zero padded>:<user id>", so Redis' lexicographic ordering of equal scores
+// gives the earliest achievement first and the ID as the final tie-break.
+// Two hashes map user IDs to their current member and username.
+type RedisStore struct {
+	client redis.UniversalClient
+	redisKeys
+	staging redisKeys
+}
+
+type redisKeys struct {
+	ranks   string
+	members string
+	names   string
+}
+
+// NewRedisStore builds a store under prefix. All keys share a hash tag so
+// that scripts and renames touching several of them also work on a cluster.
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	tag := "{" + prefix + "}"
+	return &RedisStore{
+		client:    client,
+		redisKeys: newRedisKeys(tag),
+		staging:   newRedisKeys(tag + ":staging"),
+	}
+}
+
+func newRedisKeys(base string) redisKeys {
+	return redisKeys{
+		ranks:   base + ":ranks",
+		members: base + ":members",
+		names:   base + ":names",
+	}
+}
+
+var upsertScript = redis.NewScript(`
+local old = redis.call('HGET', KEYS[2], ARGV[1])
+if old then
+	redis.call('ZREM', KEYS[1], old)
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[3])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+redis.call('HSET', KEYS[3], ARGV[1], ARGV[4])
+return 1
+`)
+
+var removeScript = redis.NewScript(`
+local old = redis.call('HGET', KEYS[2], ARGV[1])
+if old then
+	redis.call('ZREM', KEYS[1], old)
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+return 1
+`)
+
+// countThroughScript ranks a probe member by placing it at the probe score,
+// reading its rank and restoring the set in one atomic step. The member may
+// be absent, or present with another score when the user's balance changed
+// since the probe was taken; in that case the stored entry counts only if it
+// orders at or before the probe.
+var countThroughScript = redis.NewScript(`
+local score = tonumber(ARGV[1])
+local existing = redis.call('ZSCORE', KEYS[1], ARGV[2])
+redis.call('ZADD', KEYS[1], score, ARGV[2])
+local rank = redis.call('ZRANK', KEYS[1], ARGV[2])
+if not existing then
+	redis.call('ZREM', KEYS[1], ARGV[2])
+	return rank
+end
+redis.call('ZADD', KEYS[1], existing, ARGV[2])
+if tonumber(existing) <= score then
+	return rank + 1
+end
+return rank
+`)
+
+// Replace builds the new index under staging keys and renames it into place,
+// so readers never see a half-loaded leaderboard.
+func (s *RedisStore) Replace(ctx context.Context, entries []model.LeaderboardUser) error {
+	staging := s.staging
+	if err := s.client.Del(ctx, staging.ranks, staging.members, staging.names).Err(); err != nil {
+		return err
+	}
+	for start := 0; start < len(entries); start += replaceBatch {
+		end := min(start+replaceBatch, len(entries))
+		ranks := make([]redis.Z, 0, end-start)
+		members := make([]interface{}, 0, 2*(end-start))
+		names := make([]interface{}, 0, 2*(end-start))
+		for _, entry := range entries[start:end] {
+			member := encodeMember(entry)
+			ranks = append(ranks, redis.Z{Score: score(entry), Member: member})
+			members = append(members, entry.ID, member)
+			names = append(names, entry.ID, entry.Username)
+		}
+		pipe := s.client.TxPipeline()
+		pipe.ZAdd(ctx, staging.ranks, ranks...)
+		pipe.HSet(ctx, staging.members, members...)
+		pipe.HSet(ctx, staging.names, names...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+	}
+
+	pipe := s.client.TxPipeline()
+	if len(entries) == 0 {
+		pipe.Del(ctx, s.ranks, s.members, s.names)
+	} else {
+		pipe.Rename(ctx, staging.ranks, s.ranks)
+		pipe.Rename(ctx, staging.members, s.members)
+		pipe.Rename(ctx, staging.names, s.names)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) Upsert(ctx context.Context, entry model.LeaderboardUser) error {
+	keys := []string{s.ranks, s.members, s.names}
+	return upsertScript.Run(ctx, s.client, keys, entry.ID, score(entry), encodeMember(entry), entry.Username).Err()
+}
+
+func (s *RedisStore) Remove(ctx context.Context, userID string) error {
+	keys := []string{s.ranks, s.members, s.names}
+	return removeScript.Run(ctx, s.client, keys, userID).Err()
+}
+
+func (s *RedisStore) Get(ctx context.Context, userID string) (*model.LeaderboardUser, error) {
+	member, err := s.client.HGet(ctx, s.members, userID).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	pipe := s.client.Pipeline()
+	rank := pipe.ZRank(ctx, s.ranks, member)
+	balance := pipe.ZScore(ctx, s.ranks, member)
+	name := pipe.HGet(ctx, s.names, userID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	entry, err := decodeMember(member, balance.Val())
+	if err != nil {
+		return nil, err
+	}
+	entry.Username = name.Val()
+	entry.Rank = int(rank.Val()) + 1
+	return &entry, nil
+}
+
+func (s *RedisStore) CountThrough(ctx context.Context, key model.LeaderboardUser) (int, error) {
+	count, err := countThroughScript.Run(ctx, s.client, []string{s.ranks}, score(key), encodeMember(key)).Int()
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *RedisStore) Range(ctx context.Context, offset, limit int) ([]model.LeaderboardUser, error) {
+	entries := []model.LeaderboardUser{}
+	if offset < 0 || limit <= 0 {
+		return entries, nil
+	}
+	ranked, err := s.client.ZRangeWithScores(ctx, s.ranks, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ranked) == 0 {
+		return entries, nil
+	}
+
+	ids := make([]string, 0, len(ranked))
+	for i, z := range ranked {
+		entry, err := decodeMember(z.Member.(string), z.Score)
+		if err != nil {
+			return nil, err
+		}
+		entry.Rank = offset + i + 1
+		entries = append(entries, entry)
+		ids = append(ids, entry.ID)
+	}
+	names, err := s.client.HMGet(ctx, s.names, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		if name, ok := name.(string); ok {
+			entries[i].Username = name
+		}
+	}
+	return entries, nil
+}
+
+func (s *RedisStore) Len(ctx context.Context) (int, error) {
+	n, err := s.client.ZCard(ctx, s.ranks).Result()
+	return int(n), err
+}
+
+func score(entry model.LeaderboardUser) float64 {
+	return -float64(entry.Balance)
+}
+
+func encodeMember(entry model.LeaderboardUser) string {
+	return fmt.Sprintf("%019d:%s", entry.ReachedAt.UnixNano(), entry.ID)
+}
+
+func decodeMember(member string, score float64) (model.LeaderboardUser, error) {
+	nanos, id, ok := strings.Cut(member, ":")
+	if !ok {
+		return model.LeaderboardUser{}, fmt.Errorf("malformed leaderboard member %q", member)
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return model.LeaderboardUser{}, fmt.Errorf("malformed leaderboard member %q", member)
+	}
+	return model.LeaderboardUser{
+		ID:        id,
+		Balance:   int(-score),
+		ReachedAt: time.Unix(0, n).UTC(),
+	}, nil
+}
@@ -0,0 +1,145 @@
+package leaderboard
+
+import (
+	"denet/internal/model"
+	"math/rand/v2"
+)
+
+const (
+	maxLevel    = 32
+	levelFactor = 4
+)
+
+// skiplist is an indexable skip list: every forward link records how many
+// level-0 nodes it jumps over, which makes rank lookups O(log n) as well.
+type skiplist struct {
+	head   *node
+	level  int
+	length int
+}
+
+type node struct {
+	entry model.LeaderboardUser
+	next  []link
+}
+
+type link struct {
+	node *node
+	span int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		head:  &node{next: make([]link, maxLevel)},
+		level: 1,
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < maxLevel && rand.IntN(levelFactor) == 0 {
+		level++
+	}
+	return level
+}
+
+// insert adds entry, which must not already be present.
+func (l *skiplist) insert(entry model.LeaderboardUser) {
+	var update [maxLevel]*node
+	var rank [maxLevel]int
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		if i < l.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.next[i].node != nil && less(&x.next[i].node.entry, &entry) {
+			rank[i] += x.next[i].span
+			x = x.next[i].node
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > l.level {
+		for i := l.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = l.head
+			update[i].next[i].span = l.length
+		}
+		l.level = level
+	}
+
+	n := &node{entry: entry, next: make([]link, level)}
+	for i := 0; i < level; i++ {
+		n.next[i].node = update[i].next[i].node
+		update[i].next[i].node = n
+		n.next[i].span = update[i].next[i].span - (rank[0] - rank[i])
+		update[i].next[i].span = rank[0] - rank[i] + 1
+	}
+	for i := level; i < l.level; i++ {
+		update[i].next[i].span++
+	}
+	l.length++
+}
+
+// remove deletes the node holding exactly entry and reports whether it was
+// found.
+func (l *skiplist) remove(entry model.LeaderboardUser) bool {
+	var update [maxLevel]*node
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && less(&x.next[i].node.entry, &entry) {
+			x = x.next[i].node
+		}
+		update[i] = x
+	}
+
+	x = x.next[0].node
+	if x == nil || x.entry.ID != entry.ID {
+		return false
+	}
+	for i := 0; i < l.level; i++ {
+		if update[i].next[i].node == x {
+			update[i].next[i].span += x.next[i].span - 1
+			update[i].next[i].node = x.next[i].node
+		} else {
+			update[i].next[i].span--
+		}
+	}
+	for l.level > 1 && l.head.next[l.level-1].node == nil {
+		l.level--
+	}
+	l.length--
+	return true
+}
+
+// countThrough returns how many entries order at or before key.
+func (l *skiplist) countThrough(key model.LeaderboardUser) int {
+	count := 0
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && !less(&key, &x.next[i].node.entry) {
+			count += x.next[i].span
+			x = x.next[i].node
+		}
+	}
+	return count
+}
+
+// byRank returns the node at the 1-based rank, or nil when out of range.
+func (l *skiplist) byRank(rank int) *node {
+	traversed := 0
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && traversed+x.next[i].span <= rank {
+			traversed += x.next[i].span
+			x = x.next[i].node
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Package leaderboard keeps the lifetime leaderboard in a sorted index so
+// that rank lookups and pages do not have to rank the users table on every
+// request.
+package leaderboard
+
+import (
+	"context"
+	"denet/internal/model"
+	"errors"
+)
+
+var ErrNotFound = errors.New("leaderboard entry not found")
+
+// Store is a sorted index of users in leaderboard order: balance descending,
+// then whoever reached that balance first, then user ID. Entries returned by
+// Get and Range carry their 1-based rank.
+type Store interface {
+	// Replace swaps the whole index for entries, which must already be in
+	// leaderboard order.
+	Replace(ctx context.Context, entries []model.LeaderboardUser) error
+	Upsert(ctx context.Context, entry model.LeaderboardUser) error
+	Remove(ctx context.Context, userID string) error
+	Get(ctx context.Context, userID string) (*model.LeaderboardUser, error)
+	// CountThrough returns how many entries order at or before key. key does
+	// not have to be in the index.
+	CountThrough(ctx context.Context, key model.LeaderboardUser) (int, error)
+	// Range returns up to limit entries starting at the 0-based offset.
+	Range(ctx context.Context, offset, limit int) ([]model.LeaderboardUser, error)
+	Len(ctx context.Context) (int, error)
+}
+
+// less reports whether a ranks above b.
+func less(a, b *model.LeaderboardUser) bool {
+	if a.Balance != b.Balance {
+		return a.Balance > b.Balance
+	}
+	if !a.ReachedAt.Equal(b.ReachedAt) {
+		return a.ReachedAt.Before(b.ReachedAt)
+	}
+	return a.ID < b.ID
+}
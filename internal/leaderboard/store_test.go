@@ -0,0 +1,222 @@
+package leaderboard
+
+import (
+	"context"
+	"denet/internal/model"
+	"errors"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func entry(id string, balance int, reachedAfter time.Duration) model.LeaderboardUser {
+	return model.LeaderboardUser{ID: id, Username: "user-" + id, Balance: balance, ReachedAt: epoch.Add(reachedAfter)}
+}
+
+// stores returns an empty store of every backend.
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"redis":  NewRedisStore(client, "test"),
+	}
+}
+
+func TestStoreOrder(t *testing.T) {
+	ctx := context.Background()
+	// In leaderboard order: balance descending, then earliest, then ID.
+	entries := []model.LeaderboardUser{
+		entry("d", 300, 0),
+		entry("a", 200, time.Minute),
+		entry("b", 200, time.Minute),
+		entry("c", 200, 2*time.Minute),
+		entry("e", 100, 0),
+	}
+
+	countThrough := []struct {
+		name string
+		key  model.LeaderboardUser
+		want int
+	}{
+		{"above everyone", entry("z", 400, 0), 0},
+		{"first entry", entries[0], 1},
+		{"tie broken by id", entries[2], 3},
+		{"absent between ties", entry("bb", 200, time.Minute), 3},
+		{"absent earlier at same balance", entry("z", 200, 0), 1},
+		{"below everyone", entry("z", 0, 0), 5},
+	}
+
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			// Inserted out of order so that the store does the sorting.
+			for _, i := range []int{4, 1, 3, 0, 2} {
+				if err := store.Upsert(ctx, entries[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for i, want := range entries {
+				got, err := store.Get(ctx, want.ID)
+				if err != nil {
+					t.Fatalf("Get(%s): %v", want.ID, err)
+				}
+				if got.Rank != i+1 || got.Balance != want.Balance || !got.ReachedAt.Equal(want.ReachedAt) || got.Username != want.Username {
+					t.Errorf("Get(%s) = %+v, want rank %d of %+v", want.ID, *got, i+1, want)
+				}
+			}
+
+			page, err := store.Range(ctx, 1, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := entryIDs(page); ids != "a b c" {
+				t.Errorf("Range(1, 3) = %s, want a b c", ids)
+			}
+			if page[0].Rank != 2 || page[2].Rank != 4 {
+				t.Errorf("Range(1, 3) ranks = %d..%d, want 2..4", page[0].Rank, page[2].Rank)
+			}
+			if page, _ := store.Range(ctx, 5, 10); len(page) != 0 {
+				t.Errorf("Range past the end = %s, want nothing", entryIDs(page))
+			}
+
+			for _, tt := range countThrough {
+				got, err := store.CountThrough(ctx, tt.key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("CountThrough(%s) = %d, want %d", tt.name, got, tt.want)
+				}
+			}
+			// Probing must not leave the key behind.
+			if n, _ := store.Len(ctx); n != len(entries) {
+				t.Errorf("Len = %d after probes, want %d", n, len(entries))
+			}
+
+			// Moving a user re-ranks them; removing one closes the gap.
+			if err := store.Upsert(ctx, entry("e", 250, 3*time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Remove(ctx, "a"); err != nil {
+				t.Fatal(err)
+			}
+			all, err := store.Range(ctx, 0, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := entryIDs(all); ids != "d e b c" {
+				t.Errorf("after moves = %s, want d e b c", ids)
+			}
+			if _, err := store.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get(removed) error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+// TestStoresAgree replays the same random changes on every backend and
+// compares what they report.
+func TestStoresAgree(t *testing.T) {
+	ctx := context.Background()
+	backends := stores(t)
+	memory := backends["memory"]
+	random := rand.New(rand.NewSource(1))
+
+	randomEntry := func() model.LeaderboardUser {
+		// Few distinct balances and times so that ties are common.
+		return entry(strconv.Itoa(random.Intn(60)), random.Intn(8)*50, time.Duration(random.Intn(4))*time.Minute)
+	}
+
+	var initial []model.LeaderboardUser
+	for i := 0; i < 30; i++ {
+		initial = append(initial, entry(strconv.Itoa(i), random.Intn(8)*50, time.Duration(random.Intn(4))*time.Minute))
+	}
+	sortEntries(initial)
+	for _, store := range backends {
+		if err := store.Replace(ctx, initial); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for step := 0; step < 300; step++ {
+		changed := randomEntry()
+		remove := random.Intn(5) == 0
+		for _, store := range backends {
+			var err error
+			if remove {
+				err = store.Remove(ctx, changed.ID)
+			} else {
+				err = store.Upsert(ctx, changed)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		probe := randomEntry()
+		offset, limit := random.Intn(40), 1+random.Intn(10)
+		want := snapshot(t, memory, probe, offset, limit)
+		for name, store := range backends {
+			if got := snapshot(t, store, probe, offset, limit); got != want {
+				t.Fatalf("step %d: %s reports\n%s\nmemory reports\n%s", step, name, got, want)
+			}
+		}
+	}
+}
+
+// snapshot renders what store answers for a rank lookup, a count and a page.
+func snapshot(t *testing.T, store Store, probe model.LeaderboardUser, offset, limit int) string {
+	t.Helper()
+	ctx := context.Background()
+	n, err := store.Len(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := store.CountThrough(ctx, probe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rank := 0
+	if got, err := store.Get(ctx, probe.ID); err == nil {
+		rank = got.Rank
+	} else if !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	page, err := store.Range(ctx, offset, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := "len " + strconv.Itoa(n) + " count " + strconv.Itoa(count) + " rank " + strconv.Itoa(rank) + " page"
+	for _, e := range page {
+		out += " " + strconv.Itoa(e.Rank) + ":" + e.ID + "/" + strconv.Itoa(e.Balance) + "/" + e.ReachedAt.Format(time.TimeOnly)
+	}
+	return out
+}
+
+func sortEntries(entries []model.LeaderboardUser) {
+	for i := 1; i < len(entries); i++ {
+		for j := i; j > 0 && less(&entries[j], &entries[j-1]); j-- {
+			entries[j], entries[j-1] = entries[j-1], entries[j]
+		}
+	}
+}
+
+func entryIDs(entries []model.LeaderboardUser) string {
+	ids := ""
+	for i, e := range entries {
+		if i > 0 {
+			ids += " "
+		}
+		ids += e.ID
+	}
+	return ids
+}
@@ -0,0 +1,182 @@
+package leaderboard
+
+import (
+	"context"
+	"denet/internal/logging"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// unitOfWork serves the lifetime leaderboard from a Store and writes every
+// balance change through to it once the surrounding transaction commits.
+// Reads fall back to Postgres when the store fails.
+type unitOfWork struct {
+	repository.UnitOfWork
+	store Store
+}
+
+// NewUnitOfWork wraps uow so that leaderboard reads hit store. The store
+// must have been loaded with Warm first.
+func NewUnitOfWork(uow repository.UnitOfWork, store Store) repository.UnitOfWork {
+	return &unitOfWork{UnitOfWork: uow, store: store}
+}
+
+func (u *unitOfWork) Users() repository.UserRepository {
+	return &userRepository{UserRepository: u.UnitOfWork.Users(), uow: u}
+}
+
+func (u *unitOfWork) Ledger() repository.LedgerRepository {
+	return &ledgerRepository{LedgerRepository: u.UnitOfWork.Ledger(), uow: u}
+}
+
+// refresh reloads userID from Postgres into the store once the current
+// transaction commits. Reading the committed row rather than trusting the
+// caller keeps the store ordered exactly like the database.
+func (u *unitOfWork) refresh(ctx context.Context, userID string) {
+	repository.AfterCommit(ctx, func(ctx context.Context) {
+		entry, err := u.UnitOfWork.Users().GetLeaderboardEntry(ctx, userID)
+		if err == nil {
+			err = u.store.Upsert(ctx, *entry)
+		}
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to update leaderboard index",
+				zap.String("user_id", userID),
+				zap.Error(err),
+			)
+		}
+	})
+}
+
+type userRepository struct {
+	repository.UserRepository
+	uow *unitOfWork
+}
+
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	if err := r.UserRepository.Create(ctx, user); err != nil {
+		return err
+	}
+	r.uow.refresh(ctx, user.ID)
+	return nil
+}
+
+func (r *userRepository) CreateWithPassword(ctx context.Context, user *model.User, password string) error {
+	if err := r.UserRepository.CreateWithPassword(ctx, user, password); err != nil {
+		return err
+	}
+	r.uow.refresh(ctx, user.ID)
+	return nil
+}
+
+func (r *userRepository) GetLeaderboard(ctx context.Context, cursor string, limit int) (*model.LeaderboardPage, error) {
+	page, err := r.leaderboardPage(ctx, cursor, limit)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, err
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("Leaderboard index unavailable, reading from database", zap.Error(err))
+		return r.UserRepository.GetLeaderboard(ctx, cursor, limit)
+	}
+	return page, nil
+}
+
+func (r *userRepository) leaderboardPage(ctx context.Context, cursor string, limit int) (*model.LeaderboardPage, error) {
+	offset := 0
+	if cursor != "" {
+		balance, reachedAt, id, err := repository.DecodeRankCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		key := model.LeaderboardUser{ID: id, Balance: balance, ReachedAt: reachedAt}
+		if offset, err = r.uow.store.CountThrough(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := r.uow.store.Range(ctx, offset, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &model.LeaderboardPage{Entries: entries}
+	if len(page.Entries) > limit {
+		page.Entries = page.Entries[:limit]
+		last := page.Entries[limit-1]
+		page.NextCursor = repository.EncodeRankCursor(last.Balance, last.ReachedAt, last.ID)
+	}
+	return page, nil
+}
+
+func (r *userRepository) GetRank(ctx context.Context, userID string, neighbours int) (*model.UserRank, error) {
+	rank, err := r.rank(ctx, userID, neighbours)
+	if err != nil {
+		// A user missing from the index may simply not have been written
+		// through yet, so the database has the final word.
+		if err != ErrNotFound {
+			logging.FromContext(ctx).Warn("Leaderboard index unavailable, reading from database", zap.Error(err))
+		}
+		return r.UserRepository.GetRank(ctx, userID, neighbours)
+	}
+	return rank, nil
+}
+
+func (r *userRepository) rank(ctx context.Context, userID string, neighbours int) (*model.UserRank, error) {
+	user, err := r.uow.store.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	total, err := r.uow.store.Len(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := max(user.Rank-1-neighbours, 0)
+	above, err := r.uow.store.Range(ctx, start, user.Rank-1-start)
+	if err != nil {
+		return nil, err
+	}
+	below, err := r.uow.store.Range(ctx, user.Rank, neighbours)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserRank{
+		User:  *user,
+		Total: total,
+		Above: above,
+		Below: below,
+	}, nil
+}
+
+type ledgerRepository struct {
+	repository.LedgerRepository
+	uow *unitOfWork
+}
+
+func (r *ledgerRepository) Append(ctx context.Context, entry *model.PointTransaction) (int, error) {
+	balance, err := r.LedgerRepository.Append(ctx, entry)
+	if err != nil {
+		return 0, err
+	}
+	r.uow.refresh(ctx, entry.UserID)
+	return balance, nil
+}
+
+// Warm loads every user from Postgres into store, replacing its contents. A
+// balance written through while the snapshot is taken may be overwritten
+// with its previous value until that user's next change.
+func Warm(ctx context.Context, users repository.UserRepository, store Store) (int, error) {
+	var entries []model.LeaderboardUser
+	err := users.ScanLeaderboard(ctx, func(entry model.LeaderboardUser) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := store.Replace(ctx, entries); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,133 @@
+// Package merkle builds Merkle trees that OpenZeppelin's MerkleProof library
+// verifies on-chain. Trees are laid out like @openzeppelin/merkle-tree's
+// StandardMerkleTree, so the same values give the same root in both.
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"sort"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	ErrEmptyTree     = errors.New("merkle: tree has no leaves")
+	ErrInvalidAmount = errors.New("merkle: amount does not fit in uint256")
+)
+
+type Hash [32]byte
+
+func (h Hash) Hex() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+// Tree is a complete binary tree stored in an array, the root first and the
+// leaves, sorted, at the end in reverse order.
+type Tree struct {
+	nodes []Hash
+	index map[Hash]int
+}
+
+// New builds a tree over leaves. Duplicate leaves are not supported.
+func New(leaves []Hash) (*Tree, error) {
+	if len(leaves) == 0 {
+		return nil, ErrEmptyTree
+	}
+	sorted := make([]Hash, len(leaves))
+	copy(sorted, leaves)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+
+	nodes := make([]Hash, 2*len(sorted)-1)
+	index := make(map[Hash]int, len(sorted))
+	for i, leaf := range sorted {
+		pos := len(nodes) - 1 - i
+		nodes[pos] = leaf
+		index[leaf] = pos
+	}
+	for i := len(nodes) - 1 - len(sorted); i >= 0; i-- {
+		nodes[i] = hashPair(nodes[2*i+1], nodes[2*i+2])
+	}
+	return &Tree{nodes: nodes, index: index}, nil
+}
+
+func (t *Tree) Root() Hash {
+	return t.nodes[0]
+}
+
+// Proof returns the sibling hashes from leaf up to the root, or false if
+// leaf is not in the tree.
+func (t *Tree) Proof(leaf Hash) ([]Hash, bool) {
+	pos, ok := t.index[leaf]
+	if !ok {
+		return nil, false
+	}
+	proof := []Hash{}
+	for pos > 0 {
+		sibling := pos + 1
+		if pos%2 == 0 {
+			sibling = pos - 1
+		}
+		proof = append(proof, t.nodes[sibling])
+		pos = (pos - 1) / 2
+	}
+	return proof, true
+}
+
+// Verify reports whether proof links leaf to root, the way
+// MerkleProof.verify does on-chain.
+func Verify(proof []Hash, root, leaf Hash) bool {
+	computed := leaf
+	for _, sibling := range proof {
+		computed = hashPair(computed, sibling)
+	}
+	return computed == root
+}
+
+// AddressAmountLeaf is the StandardMerkleTree leaf for the values
+// (address, uint256): keccak256(keccak256(abi.encode(address, amount))).
+// address is a 20-byte hex address with or without the 0x prefix.
+func AddressAmountLeaf(address string, amount *big.Int) (Hash, error) {
+	addr, err := hex.DecodeString(trimHexPrefix(address))
+	if err != nil || len(addr) != 20 {
+		return Hash{}, errors.New("merkle: invalid address " + address)
+	}
+	if amount.Sign() < 0 || amount.BitLen() > 256 {
+		return Hash{}, ErrInvalidAmount
+	}
+	encoded := make([]byte, 64)
+	copy(encoded[12:32], addr)
+	amount.FillBytes(encoded[32:])
+	inner := keccak256(encoded)
+	return keccak256(inner[:]), nil
+}
+
+// hashPair is OpenZeppelin's commutative keccak256: the pair is sorted before
+// hashing, so proofs need no left/right flags.
+func hashPair(a, b Hash) Hash {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return keccak256(a[:], b[:])
+}
+
+func keccak256(data ...[]byte) Hash {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	var out Hash
+	h.Sum(out[:0])
+	return out
+}
+
+func trimHexPrefix(s string) string {
+	if len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		return s[2:]
+	}
+	return s
+}
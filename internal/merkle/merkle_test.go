@@ -0,0 +1,139 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func amount(t *testing.T, s string) *big.Int {
+	t.Helper()
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("bad amount %q", s)
+	}
+	return n
+}
+
+func leaf(t *testing.T, address, value string) Hash {
+	t.Helper()
+	h, err := AddressAmountLeaf(address, amount(t, value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// The values and root are those of the @openzeppelin/merkle-tree README:
+// StandardMerkleTree.of(values, ["address", "uint256"]).
+func TestStandardMerkleTreeVector(t *testing.T) {
+	leaves := []Hash{
+		leaf(t, "0x1111111111111111111111111111111111111111", "5000000000000000000"),
+		leaf(t, "0x2222222222222222222222222222222222222222", "2500000000000000000"),
+	}
+	const wantRoot = "0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77"
+
+	for _, order := range [][]Hash{leaves, {leaves[1], leaves[0]}} {
+		tree, err := New(order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tree.Root().Hex(); got != wantRoot {
+			t.Fatalf("Root() = %s, want %s", got, wantRoot)
+		}
+		for i, l := range leaves {
+			proof, ok := tree.Proof(l)
+			if !ok {
+				t.Fatalf("Proof(leaf %d) not found", i)
+			}
+			if len(proof) != 1 || proof[0] != leaves[1-i] {
+				t.Errorf("Proof(leaf %d) = %v, want the other leaf", i, proof)
+			}
+			if !Verify(proof, tree.Root(), l) {
+				t.Errorf("Verify(leaf %d) = false", i)
+			}
+		}
+	}
+}
+
+func TestProofs(t *testing.T) {
+	for _, size := range []int{1, 2, 3, 4, 5, 7, 8, 9, 16, 33} {
+		t.Run(fmt.Sprintf("%d leaves", size), func(t *testing.T) {
+			leaves := make([]Hash, size)
+			for i := range leaves {
+				leaves[i] = leaf(t, fmt.Sprintf("0x%040x", i+1), fmt.Sprint(1000*(i+1)))
+			}
+			tree, err := New(leaves)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size == 1 && tree.Root() != leaves[0] {
+				t.Errorf("root of a single leaf = %s, want the leaf", tree.Root().Hex())
+			}
+
+			for i, l := range leaves {
+				proof, ok := tree.Proof(l)
+				if !ok {
+					t.Fatalf("Proof(leaf %d) not found", i)
+				}
+				if !Verify(proof, tree.Root(), l) {
+					t.Errorf("Verify(leaf %d) = false", i)
+				}
+				if len(proof) > 0 {
+					tampered := append([]Hash(nil), proof...)
+					tampered[len(tampered)-1][0] ^= 1
+					if Verify(tampered, tree.Root(), l) {
+						t.Errorf("Verify(leaf %d, tampered proof) = true", i)
+					}
+				}
+				if size > 1 && Verify(proof, tree.Root(), leaves[(i+1)%size]) {
+					t.Errorf("proof of leaf %d verifies leaf %d", i, (i+1)%size)
+				}
+			}
+
+			outsider := leaf(t, "0xffffffffffffffffffffffffffffffffffffffff", "1")
+			if _, ok := tree.Proof(outsider); ok {
+				t.Error("Proof(unknown leaf) found")
+			}
+		})
+	}
+}
+
+func TestNewEmpty(t *testing.T) {
+	if _, err := New(nil); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("New(nil) error = %v, want ErrEmptyTree", err)
+	}
+}
+
+func TestAddressAmountLeaf(t *testing.T) {
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	tests := []struct {
+		name    string
+		address string
+		amount  *big.Int
+		wantErr bool
+	}{
+		{"prefixed", "0x1111111111111111111111111111111111111111", big.NewInt(5), false},
+		{"unprefixed", "1111111111111111111111111111111111111111", big.NewInt(5), false},
+		{"max amount", "0x1111111111111111111111111111111111111111", maxUint256, false},
+		{"short address", "0x1111", big.NewInt(5), true},
+		{"not hex", "0xzz11111111111111111111111111111111111111", big.NewInt(5), true},
+		{"negative amount", "0x1111111111111111111111111111111111111111", big.NewInt(-1), true},
+		{"amount over uint256", "0x1111111111111111111111111111111111111111", new(big.Int).Add(maxUint256, big.NewInt(1)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := AddressAmountLeaf(tt.address, tt.amount)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	prefixed, _ := AddressAmountLeaf("0xABCDEF0000000000000000000000000000000001", big.NewInt(7))
+	bare, _ := AddressAmountLeaf("abcdef0000000000000000000000000000000001", big.NewInt(7))
+	if prefixed != bare {
+		t.Error("leaf depends on the 0x prefix or the hex case")
+	}
+}
@@ -0,0 +1,111 @@
+// Package metrics exposes the service's Prometheus metrics. Collectors live
+// on a registry passed in by the caller, so tests can use a fresh one and
+// gather from it.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "denet"
+
+// Label values for the auth method of registrations and logins.
+const (
+	MethodPassword = "password"
+	MethodTelegram = "telegram"
+	MethodWallet   = "wallet"
+	// MethodExternal registers users created without a password, by an
+	// external identity.
+	MethodExternal = "external"
+)
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	registrations  *prometheus.CounterVec
+	logins         *prometheus.CounterVec
+	tasksCompleted *prometheus.CounterVec
+	referralsSet   prometheus.Counter
+	pointsIssued   *prometheus.CounterVec
+}
+
+// New creates the collectors and registers them, with the Go runtime and
+// process collectors, on registry.
+func New(registry *prometheus.Registry) *Metrics {
+	m := &Metrics{
+		registry: registry,
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		registrations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "registrations_total",
+			Help:      "Users registered, by method.",
+		}, []string{"method"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by method and result.",
+		}, []string{"method", "result"}),
+		tasksCompleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tasks_completed_total",
+			Help:      "Task completions credited, by task.",
+		}, []string{"task_id"}),
+		referralsSet: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "referrals_set_total",
+			Help:      "Referrers recorded for users.",
+		}),
+		pointsIssued: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "points_issued_total",
+			Help:      "Points credited to users, by ledger source.",
+		}, []string{"source"}),
+	}
+	registry.MustRegister(
+		m.httpRequests, m.httpDuration,
+		m.registrations, m.logins, m.tasksCompleted, m.referralsSet, m.pointsIssued,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Register adds further collectors, such as the database pool stats.
+func (m *Metrics) Register(collector prometheus.Collector) error {
+	return m.registry.Register(collector)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *Metrics) ObserveHTTP(method, route, status string, seconds float64) {
+	m.httpRequests.WithLabelValues(method, route, status).Inc()
+	m.httpDuration.WithLabelValues(method, route, status).Observe(seconds)
+}
+
+func (m *Metrics) LoginSucceeded(method string) {
+	m.logins.WithLabelValues(method, "success").Inc()
+}
+
+func (m *Metrics) LoginFailed(method string) {
+	m.logins.WithLabelValues(method, "failure").Inc()
+}
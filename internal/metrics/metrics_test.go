@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"context"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserve(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	m.ObserveHTTP("GET", "/api/users/:id", "200", 0.02)
+	m.ObserveHTTP("GET", "/api/users/:id", "200", 0.03)
+	m.ObserveHTTP("GET", "/api/users/:id", "404", 0.01)
+	m.LoginSucceeded(MethodPassword)
+	m.LoginFailed(MethodPassword)
+	m.LoginFailed(MethodPassword)
+
+	counts := []struct {
+		name string
+		c    prometheus.Collector
+		want float64
+	}{
+		{"200s", m.httpRequests.WithLabelValues("GET", "/api/users/:id", "200"), 2},
+		{"404s", m.httpRequests.WithLabelValues("GET", "/api/users/:id", "404"), 1},
+		{"login successes", m.logins.WithLabelValues(MethodPassword, "success"), 1},
+		{"login failures", m.logins.WithLabelValues(MethodPassword, "failure"), 2},
+	}
+	for _, tt := range counts {
+		if got := testutil.ToFloat64(tt.c); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		`denet_http_requests_total{method="GET",route="/api/users/:id",status="200"} 2`,
+		`denet_http_request_duration_seconds_count{method="GET",route="/api/users/:id",status="200"} 2`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("exposition lacks %s", want)
+		}
+	}
+}
+
+type fakeUnitOfWork struct {
+	repository.UnitOfWork
+	users  fakeUsers
+	ledger fakeLedger
+}
+
+func (u *fakeUnitOfWork) Users() repository.UserRepository    { return &u.users }
+func (u *fakeUnitOfWork) Ledger() repository.LedgerRepository { return &u.ledger }
+
+type fakeUsers struct {
+	repository.UserRepository
+	err error
+}
+
+func (r *fakeUsers) Create(ctx context.Context, user *model.User) error { return r.err }
+
+func (r *fakeUsers) CreateWithPassword(ctx context.Context, user *model.User, password string) error {
+	return r.err
+}
+
+func (r *fakeUsers) SetReferrer(ctx context.Context, userID, referrerID string) error { return r.err }
+
+type fakeLedger struct {
+	repository.LedgerRepository
+	err error
+}
+
+func (r *fakeLedger) Append(ctx context.Context, entry *model.PointTransaction) (int, error) {
+	return 100, r.err
+}
+
+// Outside a transaction AfterCommit runs its hook right away, so the
+// counts show up as soon as the write returns.
+func TestUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+	m := New(prometheus.NewRegistry())
+	inner := &fakeUnitOfWork{}
+	uow := NewUnitOfWork(inner, m)
+
+	uow.Users().CreateWithPassword(ctx, &model.User{}, "secret")
+	uow.Users().Create(ctx, &model.User{})
+	uow.Users().SetReferrer(ctx, "u1", "u2")
+
+	taskID := "task-1"
+	for _, entry := range []model.PointTransaction{
+		{Delta: 50, SourceType: model.PointSourceTask, SourceID: &taskID},
+		{Delta: 20, SourceType: model.PointSourceReferral},
+		// Debits and refunds are not issued points.
+		{Delta: -30, SourceType: model.PointSourceAdmin},
+		{Delta: 40, SourceType: model.PointSourceRedemption},
+	} {
+		if _, err := uow.Ledger().Append(ctx, &entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Failed writes are not counted.
+	inner.users.err = errors.New("duplicate")
+	inner.ledger.err = errors.New("insufficient balance")
+	uow.Users().CreateWithPassword(ctx, &model.User{}, "secret")
+	uow.Users().SetReferrer(ctx, "u1", "u2")
+	uow.Ledger().Append(ctx, &model.PointTransaction{Delta: 50, SourceType: model.PointSourceTask, SourceID: &taskID})
+
+	counts := []struct {
+		name string
+		c    prometheus.Collector
+		want float64
+	}{
+		{"password registrations", m.registrations.WithLabelValues(MethodPassword), 1},
+		{"external registrations", m.registrations.WithLabelValues(MethodExternal), 1},
+		{"referrals", m.referralsSet, 1},
+		{"task points", m.pointsIssued.WithLabelValues(string(model.PointSourceTask)), 50},
+		{"referral points", m.pointsIssued.WithLabelValues(string(model.PointSourceReferral)), 20},
+		{"admin points", m.pointsIssued.WithLabelValues(string(model.PointSourceAdmin)), 0},
+		{"redemption points", m.pointsIssued.WithLabelValues(string(model.PointSourceRedemption)), 0},
+		{"task completions", m.tasksCompleted.WithLabelValues(taskID), 1},
+	}
+	for _, tt := range counts {
+		if got := testutil.ToFloat64(tt.c); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"context"
+	"denet/internal/model"
+	"denet/internal/repository"
+)
+
+// unitOfWork counts business events as their writes commit, so that work
+// rolled back or retried is not counted.
+type unitOfWork struct {
+	repository.UnitOfWork
+	metrics *Metrics
+}
+
+// NewUnitOfWork wraps uow so that registrations, referrals set, points
+// issued and task completions are counted on m.
+func NewUnitOfWork(uow repository.UnitOfWork, m *Metrics) repository.UnitOfWork {
+	return &unitOfWork{UnitOfWork: uow, metrics: m}
+}
+
+func (u *unitOfWork) Users() repository.UserRepository {
+	return &userRepository{UserRepository: u.UnitOfWork.Users(), metrics: u.metrics}
+}
+
+func (u *unitOfWork) Ledger() repository.LedgerRepository {
+	return &ledgerRepository{LedgerRepository: u.UnitOfWork.Ledger(), metrics: u.metrics}
+}
+
+type userRepository struct {
+	repository.UserRepository
+	metrics *Metrics
+}
+
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	if err := r.UserRepository.Create(ctx, user); err != nil {
+		return err
+	}
+	r.count(ctx, MethodExternal)
+	return nil
+}
+
+func (r *userRepository) CreateWithPassword(ctx context.Context, user *model.User, password string) error {
+	if err := r.UserRepository.CreateWithPassword(ctx, user, password); err != nil {
+		return err
+	}
+	r.count(ctx, MethodPassword)
+	return nil
+}
+
+func (r *userRepository) SetReferrer(ctx context.Context, userID, referrerID string) error {
+	if err := r.UserRepository.SetReferrer(ctx, userID, referrerID); err != nil {
+		return err
+	}
+	repository.AfterCommit(ctx, func(context.Context) { r.metrics.referralsSet.Inc() })
+	return nil
+}
+
+func (r *userRepository) count(ctx context.Context, method string) {
+	repository.AfterCommit(ctx, func(context.Context) {
+		r.metrics.registrations.WithLabelValues(method).Inc()
+	})
+}
+
+type ledgerRepository struct {
+	repository.LedgerRepository
+	metrics *Metrics
+}
+
+func (r *ledgerRepository) Append(ctx context.Context, entry *model.PointTransaction) (int, error) {
+	balance, err := r.LedgerRepository.Append(ctx, entry)
+	// Refunds of cancelled reward orders return points rather than issue
+	// them.
+	if err != nil || entry.Delta <= 0 || entry.SourceType == model.PointSourceRedemption {
+		return balance, err
+	}
+	source := string(entry.SourceType)
+	var taskID string
+	if entry.SourceType == model.PointSourceTask && entry.SourceID != nil {
+		taskID = *entry.SourceID
+	}
+	repository.AfterCommit(ctx, func(context.Context) {
+		r.metrics.pointsIssued.WithLabelValues(source).Add(float64(entry.Delta))
+		if taskID != "" {
+			r.metrics.tasksCompleted.WithLabelValues(taskID).Inc()
+		}
+	})
+	return balance, nil
+}
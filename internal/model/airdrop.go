@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+const PermAirdropsManage = "airdrops:manage"
+
+// Airdrop is a frozen snapshot of point balances converted to token amounts.
+// Amounts are decimal strings in the token's base units, as they may exceed
+// 64 bits.
+type Airdrop struct {
+	ID            string    `json:"id" db:"id"`
+	Name          string    `json:"name" db:"name"`
+	SnapshotAt    time.Time `json:"snapshot_at" db:"snapshot_at"`
+	UnitsPerPoint string    `json:"units_per_point" db:"units_per_point"`
+	MerkleRoot    string    `json:"merkle_root" db:"merkle_root"`
+	TotalAmount   string    `json:"total_amount" db:"total_amount"`
+	Recipients    int       `json:"recipients" db:"recipients"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// AirdropBalance is a user's balance at the snapshot time with the wallet it
+// is paid to, which is the user's primary wallet when the snapshot is taken.
+type AirdropBalance struct {
+	UserID  string `db:"user_id"`
+	Address string `db:"address"`
+	Points  int64  `db:"points"`
+}
+
+// AirdropClaim holds what a recipient submits to the claim contract.
+type AirdropClaim struct {
+	AirdropID  string   `json:"airdrop_id" db:"airdrop_id"`
+	MerkleRoot string   `json:"merkle_root" db:"merkle_root"`
+	UserID     string   `json:"user_id" db:"user_id"`
+	Address    string   `json:"address" db:"address"`
+	Points     int64    `json:"points" db:"points"`
+	Amount     string   `json:"amount" db:"amount"`
+	Proof      []string `json:"proof" db:"proof"`
+}
+
+type CreateAirdropRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+	// SnapshotAt defaults to now. Balances are taken at SnapshotAt but paid
+	// to the wallet that is primary when the airdrop is created, also for a
+	// past SnapshotAt: no history of primary wallets is kept.
+	SnapshotAt *time.Time `json:"snapshot_at"`
+	// UnitsPerPoint is how many token base units one point is worth.
+	UnitsPerPoint string `json:"units_per_point" binding:"required,numeric,max=78"`
+}
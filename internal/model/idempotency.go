@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// MaxIdempotencyKeyLength is the longest Idempotency-Key that is stored.
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyRecord remembers a mutating request made with an
+// Idempotency-Key header. StatusCode is nil while the first request is still
+// being processed.
+type IdempotencyRecord struct {
+	Scope        string    `json:"scope" db:"scope"`
+	Key          string    `json:"key" db:"key"`
+	Fingerprint  string    `json:"fingerprint" db:"fingerprint"`
+	StatusCode   *int      `json:"status_code,omitempty" db:"status_code"`
+	ContentType  string    `json:"content_type,omitempty" db:"content_type"`
+	ResponseBody []byte    `json:"-" db:"response_body"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+}
+
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != nil
+}
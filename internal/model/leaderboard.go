@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+const (
+	PermSeasonsManage = "seasons:manage"
+)
+
+const (
+	LeaderboardDaily   = "daily"
+	LeaderboardWeekly  = "weekly"
+	LeaderboardMonthly = "monthly"
+	LeaderboardSeason  = "season"
+)
+
+// EarningSources are the ledger sources that count towards windowed
+// leaderboards; admin adjustments are left out.
+var EarningSources = []PointSource{PointSourceTask, PointSourceReferral}
+
+type Season struct {
+	ID         string     `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	StartsAt   time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt     time.Time  `json:"ends_at" db:"ends_at"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type WindowLeaderboardEntry struct {
+	UserID   string `json:"user_id" db:"user_id"`
+	Username string `json:"username" db:"username"`
+	Points   int    `json:"points" db:"points"`
+	Rank     int    `json:"rank" db:"rank"`
+}
+
+type WindowLeaderboard struct {
+	Window  string                   `json:"window"`
+	From    time.Time                `json:"from"`
+	To      time.Time                `json:"to"`
+	Season  *Season                  `json:"season,omitempty"`
+	Final   bool                     `json:"final"`
+	Entries []WindowLeaderboardEntry `json:"entries"`
+}
+
+type CreateSeasonRequest struct {
+	Name     string    `json:"name" binding:"required,min=1,max=100"`
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required,gtfield=StartsAt"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+type PointSource string
+
+const (
+	PointSourceTask     PointSource = "task"
+	PointSourceReferral PointSource = "referral"
+	PointSourceAdmin    PointSource = "admin"
+	// PointSourceRedemption debits points spent on rewards and refunds them
+	// when an order is cancelled.
+	PointSourceRedemption PointSource = "redemption"
+)
+
+type PointTransaction struct {
+	ID             string      `json:"id" db:"id"`
+	UserID         string      `json:"user_id" db:"user_id"`
+	Delta          int         `json:"delta" db:"delta"`
+	Reason         string      `json:"reason" db:"reason"`
+	SourceType     PointSource `json:"source_type" db:"source_type"`
+	SourceID       *string     `json:"source_id,omitempty" db:"source_id"`
+	IdempotencyKey string      `json:"idempotency_key" db:"idempotency_key"`
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+}
+
+type PointTransactionPage struct {
+	Transactions []PointTransaction `json:"transactions"`
+	NextCursor   string             `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+const PermUsersUnlock = "users:unlock"
+
+// LoginLockout tracks a user's failed password logins. Enough failures in a
+// row lock the account, for longer each time, until a successful login or an
+// admin clears the record.
+type LoginLockout struct {
+	UserID         string     `json:"user_id" db:"user_id"`
+	FailedAttempts int        `json:"failed_attempts" db:"failed_attempts"`
+	LastFailedAt   *time.Time `json:"last_failed_at,omitempty" db:"last_failed_at"`
+	// Lockouts counts the locks since the record was last cleared.
+	Lockouts    int        `json:"lockouts" db:"lockouts"`
+	LockedUntil *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsLocked reports whether password logins are refused at now.
+func (l *LoginLockout) IsLocked(now time.Time) bool {
+	return l.LockedUntil != nil && now.Before(*l.LockedUntil)
+}
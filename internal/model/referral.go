@@ -0,0 +1,84 @@
+package model
+
+import "time"
+
+const (
+	PermReferralsManage = "referrals:manage"
+)
+
+// Referral reward events. Signup pays when a referee is linked, milestone
+// pays once when the referee's task earnings reach MilestonePoints, and
+// earning pays Percent of every task reward the referee receives.
+const (
+	ReferralEventSignup    = "signup"
+	ReferralEventMilestone = "milestone"
+	ReferralEventEarning   = "earning"
+)
+
+// ReferralRule describes a payout to the referrer Level steps up the chain.
+type ReferralRule struct {
+	ID              string    `json:"id" db:"id"`
+	Event           string    `json:"event" db:"event"`
+	Level           int       `json:"level" db:"level"`
+	MilestonePoints *int      `json:"milestone_points,omitempty" db:"milestone_points"`
+	Points          int       `json:"points" db:"points"`
+	Percent         float64   `json:"percent" db:"percent"`
+	Active          bool      `json:"active" db:"active"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+type ReferralAncestor struct {
+	UserID string
+	Level  int
+}
+
+type CreateReferralRuleRequest struct {
+	Event           string  `json:"event" binding:"required,oneof=signup milestone earning"`
+	Level           int     `json:"level" binding:"required,min=1,max=10"`
+	MilestonePoints *int    `json:"milestone_points" binding:"required_if=Event milestone,omitempty,min=1"`
+	Points          int     `json:"points" binding:"min=0"`
+	Percent         float64 `json:"percent" binding:"min=0,max=100"`
+}
+
+type UpdateReferralRuleRequest struct {
+	Points  *int     `json:"points" binding:"omitempty,min=0"`
+	Percent *float64 `json:"percent" binding:"omitempty,min=0,max=100"`
+	Active  *bool    `json:"active"`
+}
+
+type SetReferralCodeRequest struct {
+	Code string `json:"code" binding:"required,min=4,max=32,alphanum"`
+}
+
+type Referee struct {
+	ID       string    `json:"id" db:"id"`
+	Username string    `json:"username" db:"username"`
+	Balance  int       `json:"balance" db:"balance"`
+	JoinedAt time.Time `json:"joined_at" db:"created_at"`
+}
+
+type RefereePage struct {
+	Referees   []Referee `json:"referees"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+type ReferralTreeNode struct {
+	Referee
+	ReferrerID string              `json:"-"`
+	Depth      int                 `json:"depth"`
+	Children   []*ReferralTreeNode `json:"children"`
+}
+
+type ReferralTree struct {
+	UserID    string              `json:"user_id"`
+	Depth     int                 `json:"depth"`
+	Total     int                 `json:"total"`
+	Truncated bool                `json:"truncated"`
+	Referees  []*ReferralTreeNode `json:"referees"`
+}
+
+type ReferralStats struct {
+	DirectInvites       int `json:"direct_invites"`
+	ActiveReferees      int `json:"active_referees"`
+	PointsFromReferrals int `json:"points_from_referrals"`
+}
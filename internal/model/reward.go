@@ -0,0 +1,98 @@
+package model
+
+import "time"
+
+const PermRewardsManage = "rewards:manage"
+
+// Reward is a catalog item users buy with points. A nil Stock or
+// PerUserLimit means unlimited; AvailableFrom and AvailableUntil bound when it
+// can be redeemed.
+type Reward struct {
+	ID             string     `json:"id" db:"id"`
+	Name           string     `json:"name" db:"name"`
+	Description    string     `json:"description" db:"description"`
+	Cost           int        `json:"cost" db:"cost"`
+	Stock          *int       `json:"stock,omitempty" db:"stock"`
+	PerUserLimit   *int       `json:"per_user_limit,omitempty" db:"per_user_limit"`
+	AvailableFrom  *time.Time `json:"available_from,omitempty" db:"available_from"`
+	AvailableUntil *time.Time `json:"available_until,omitempty" db:"available_until"`
+	ArchivedAt     *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsAvailable reports whether the reward can be redeemed at now, stock
+// aside.
+func (r *Reward) IsAvailable(now time.Time) bool {
+	if r.ArchivedAt != nil {
+		return false
+	}
+	if r.AvailableFrom != nil && now.Before(*r.AvailableFrom) {
+		return false
+	}
+	return r.AvailableUntil == nil || now.Before(*r.AvailableUntil)
+}
+
+type RewardFilter struct {
+	IncludeArchived bool
+	// AvailableAt, if set, keeps only rewards inside their window at that
+	// time.
+	AvailableAt *time.Time
+}
+
+type CreateRewardRequest struct {
+	Name           string     `json:"name" binding:"required,min=1,max=100"`
+	Description    string     `json:"description"`
+	Cost           int        `json:"cost" binding:"required,min=1"`
+	Stock          *int       `json:"stock" binding:"omitempty,min=0"`
+	PerUserLimit   *int       `json:"per_user_limit" binding:"omitempty,min=1"`
+	AvailableFrom  *time.Time `json:"available_from"`
+	AvailableUntil *time.Time `json:"available_until"`
+}
+
+// UpdateRewardRequest changes the given fields. ClearStock, ClearPerUserLimit
+// and ClearWindow remove a limit, since a null field means "unchanged".
+type UpdateRewardRequest struct {
+	Name              *string    `json:"name" binding:"omitempty,min=1,max=100"`
+	Description       *string    `json:"description"`
+	Cost              *int       `json:"cost" binding:"omitempty,min=1"`
+	Stock             *int       `json:"stock" binding:"omitempty,min=0"`
+	ClearStock        bool       `json:"clear_stock"`
+	PerUserLimit      *int       `json:"per_user_limit" binding:"omitempty,min=1"`
+	ClearPerUserLimit bool       `json:"clear_per_user_limit"`
+	AvailableFrom     *time.Time `json:"available_from"`
+	AvailableUntil    *time.Time `json:"available_until"`
+	ClearWindow       bool       `json:"clear_window"`
+}
+
+type RewardOrderStatus string
+
+const (
+	RewardOrderPending   RewardOrderStatus = "pending"
+	RewardOrderFulfilled RewardOrderStatus = "fulfilled"
+	RewardOrderCancelled RewardOrderStatus = "cancelled"
+)
+
+// RewardOrder is one redemption. Cost is what the user paid, refunded in
+// full if the order is cancelled.
+type RewardOrder struct {
+	ID        string            `json:"id" db:"id"`
+	UserID    string            `json:"user_id" db:"user_id"`
+	RewardID  string            `json:"reward_id" db:"reward_id"`
+	Cost      int               `json:"cost" db:"cost"`
+	Status    RewardOrderStatus `json:"status" db:"status"`
+	Note      *string           `json:"note,omitempty" db:"note"`
+	HandledBy *string           `json:"handled_by,omitempty" db:"handled_by"`
+	HandledAt *time.Time        `json:"handled_at,omitempty" db:"handled_at"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+type RewardOrderPage struct {
+	Orders     []RewardOrder `json:"orders"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+type HandleRewardOrderRequest struct {
+	Note string `json:"note" binding:"max=1000"`
+}
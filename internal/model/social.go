@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+type SocialProvider string
+
+const (
+	SocialTelegram SocialProvider = "telegram"
+	SocialTwitter  SocialProvider = "twitter"
+	SocialDiscord  SocialProvider = "discord"
+)
+
+// SocialProviders are the platforms an account can be linked on. Each one is
+// also the verification type of the tasks checked against it.
+var SocialProviders = []SocialProvider{SocialTelegram, SocialTwitter, SocialDiscord}
+
+// SocialAccount links a user to their account on a platform. ExternalID is
+// the platform's numeric user ID, which verifiers query by; Handle is for
+// display only. Verified is set when the platform confirmed the user holds
+// the account, as the Telegram login widget does; tasks are only checked
+// automatically against verified accounts.
+type SocialAccount struct {
+	UserID     string         `json:"user_id" db:"user_id"`
+	Provider   SocialProvider `json:"provider" db:"provider"`
+	ExternalID string         `json:"external_id" db:"external_id"`
+	Handle     string         `json:"handle,omitempty" db:"handle"`
+	Verified   bool           `json:"verified" db:"verified"`
+	LinkedAt   time.Time      `json:"linked_at" db:"linked_at"`
+}
+
+type LinkSocialAccountRequest struct {
+	ExternalID string `json:"external_id" binding:"required,numeric,max=32"`
+	Handle     string `json:"handle" binding:"max=64"`
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const PermTasksReview = "tasks:review"
+
+// Verification types shipped with the service; further ones are registered
+// with the verifier registry at startup.
+const (
+	VerificationAuto   = "auto"
+	VerificationManual = "manual"
+)
+
+type Task struct {
+	ID          string     `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Description string     `json:"description" db:"description"`
+	Points      int        `json:"points" db:"points"`
+	PublishedAt *time.Time `json:"published_at,omitempty" db:"published_at"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+
+	// VerificationType selects the TaskVerifier that decides submissions;
+	// VerificationConfig is passed to it as is.
+	VerificationType   string          `json:"verification_type" db:"verification_type"`
+	VerificationConfig json.RawMessage `json:"verification_config,omitempty" db:"verification_config"`
+}
+
+// IsAvailable reports whether users can see and complete the task at now.
+func (t *Task) IsAvailable(now time.Time) bool {
+	if t.ArchivedAt != nil || t.PublishedAt == nil {
+		return false
+	}
+	return !t.PublishedAt.After(now)
+}
+
+type TaskFilter struct {
+	IncludeArchived    bool
+	IncludeUnpublished bool
+}
+
+type CreateTaskRequest struct {
+	Name        string     `json:"name" binding:"required,min=1,max=100"`
+	Description string     `json:"description"`
+	Points      int        `json:"points" binding:"required,min=1"`
+	PublishedAt *time.Time `json:"published_at"`
+	// VerificationType defaults to auto.
+	VerificationType   string          `json:"verification_type" binding:"omitempty,max=32"`
+	VerificationConfig json.RawMessage `json:"verification_config"`
+}
+
+type UpdateTaskRequest struct {
+	Name               *string         `json:"name" binding:"omitempty,min=1,max=100"`
+	Description        *string         `json:"description"`
+	Points             *int            `json:"points" binding:"omitempty,min=1"`
+	PublishedAt        *time.Time      `json:"published_at"`
+	VerificationType   *string         `json:"verification_type" binding:"omitempty,min=1,max=32"`
+	VerificationConfig json.RawMessage `json:"verification_config"`
+}
+
+type TaskSubmissionStatus string
+
+const (
+	TaskSubmissionPending  TaskSubmissionStatus = "pending"
+	TaskSubmissionApproved TaskSubmissionStatus = "approved"
+	TaskSubmissionRejected TaskSubmissionStatus = "rejected"
+	TaskSubmissionRevoked  TaskSubmissionStatus = "revoked"
+)
+
+// UserTask is a user's submission for a task. Points are credited when it is
+// approved and clawed back when an approved submission is revoked.
+type UserTask struct {
+	ID         string               `json:"id" db:"id"`
+	UserID     string               `json:"user_id" db:"user_id"`
+	TaskID     string               `json:"task_id" db:"task_id"`
+	Status     TaskSubmissionStatus `json:"status" db:"status"`
+	Proof      *string              `json:"proof,omitempty" db:"proof"`
+	ReviewNote *string              `json:"review_note,omitempty" db:"review_note"`
+	ReviewedBy *string              `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt *time.Time           `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	CreatedAt  time.Time            `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at" db:"updated_at"`
+}
+
+type UserTaskPage struct {
+	Submissions []UserTask `json:"submissions"`
+	NextCursor  string     `json:"next_cursor,omitempty"`
+}
+
+// TaskVerdict is a verifier's decision on a submission. Pending leaves it in
+// the moderator review queue.
+type TaskVerdict struct {
+	Status TaskSubmissionStatus
+	Note   string
+}
+
+type ReviewSubmissionRequest struct {
+	Note string `json:"note" binding:"max=500"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Session groups every refresh token produced by rotating one login; it is
+// the refresh token family. Revoking it invalidates all of them together with
+// the access tokens that carry its ID.
+type Session struct {
+	ID           string     `json:"id" db:"id"`
+	UserID       string     `json:"user_id" db:"user_id"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokeReason *string    `json:"revoke_reason,omitempty" db:"revoke_reason"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+type RefreshToken struct {
+	ID        string     `db:"id"`
+	SessionID string     `db:"session_id"`
+	UserID    string     `db:"user_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	// SessionRevoked is filled by lookups that join auth_sessions.
+	SessionRevoked bool `db:"-"`
+}
+
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+const (
+	RevokeReasonLogout    = "logout"
+	RevokeReasonLogoutAll = "logout_all"
+	RevokeReasonReuse     = "refresh_token_reuse"
+)
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -9,35 +10,38 @@ import (
 type User struct {
 	ID           string    `json:"id" db:"id"`
 	Username     string    `json:"username" db:"username"`
-	Email        string    `json:"email" db:"email"`
+	Email        string    `json:"email,omitempty" db:"email"`
 	PasswordHash string    `json:"-" db:"password_hash"`
 	Balance      int       `json:"balance" db:"balance"`
 	ReferrerID   *string   `json:"referrer_id,omitempty" db:"referrer_id"`
+	ReferralCode string    `json:"referral_code" db:"referral_code"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
-type Task struct {
-	ID          string    `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Description string    `json:"description" db:"description"`
-	Points      int       `json:"points" db:"points"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+type LeaderboardUser struct {
+	ID        string    `json:"id" db:"id"`
+	Username  string    `json:"username" db:"username"`
+	Balance   int       `json:"balance" db:"balance"`
+	Rank      int       `json:"rank" db:"rank"`
+	ReachedAt time.Time `json:"reached_at" db:"balance_reached_at"`
 }
 
-type UserTask struct {
-	ID        string    `json:"id" db:"id"`
-	UserID    string    `json:"user_id" db:"user_id"`
-	TaskID    string    `json:"task_id" db:"task_id"`
-	Completed bool      `json:"completed" db:"completed"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+// LeaderboardPage is one slice of the lifetime leaderboard. Users are ordered
+// by balance, then by who reached that balance first.
+type LeaderboardPage struct {
+	Entries    []LeaderboardUser `json:"entries"`
+	NextCursor string            `json:"next_cursor,omitempty"`
 }
 
-type LeaderboardUser struct {
-	ID       string `json:"id" db:"id"`
-	Username string `json:"username" db:"username"`
-	Balance  int    `json:"balance" db:"balance"`
-	Rank     int    `json:"rank" db:"rank"`
+// UserRank places a user on the lifetime leaderboard together with the users
+// directly above and below them.
+type UserRank struct {
+	User       LeaderboardUser   `json:"user"`
+	Total      int               `json:"total"`
+	Percentile float64           `json:"percentile"`
+	Above      []LeaderboardUser `json:"above"`
+	Below      []LeaderboardUser `json:"below"`
 }
 
 type UserStatus struct {
@@ -48,16 +52,23 @@ type UserStatus struct {
 
 type CompleteTaskRequest struct {
 	TaskID string `json:"task_id" binding:"required"`
+	// Proof is whatever the task's verifier needs to check the claim, such
+	// as a link to a post or a screenshot URL.
+	Proof string `json:"proof" binding:"max=2000"`
 }
 
+// SetReferrerRequest identifies the referrer either by user ID or by referral
+// code; exactly one of the two must be set.
 type SetReferrerRequest struct {
-	ReferrerID string `json:"referrer_id" binding:"required"`
+	ReferrerID   string `json:"referrer_id" binding:"required_without=ReferralCode,excluded_with=ReferralCode"`
+	ReferralCode string `json:"referral_code" binding:"required_without=ReferrerID"`
 }
 
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=50"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Username     string `json:"username" binding:"required,min=3,max=50"`
+	Email        string `json:"email" binding:"required,email"`
+	Password     string `json:"password" binding:"required,min=6"`
+	ReferralCode string `json:"referral_code" binding:"omitempty,max=32"`
 }
 
 type LoginRequest struct {
@@ -65,14 +76,27 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// TelegramAuthRequest carries the Telegram Login Widget payload exactly as the
+// widget produced it; every field in it is covered by its hash.
+type TelegramAuthRequest struct {
+	AuthData     map[string]json.RawMessage `json:"auth_data" binding:"required"`
+	ReferralCode string                     `json:"referral_code" binding:"omitempty,max=32"`
+}
+
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  *User  `json:"user"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             *User     `json:"user"`
 }
 
 type JWTClaims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
+	UserID      string   `json:"user_id"`
+	Username    string   `json:"username"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	SessionID   string   `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
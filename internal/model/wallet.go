@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Wallet is an EVM address a user proved control of by signing a Sign-In
+// with Ethereum message. Address is EIP-55 checksummed. A user with wallets
+// has exactly one primary wallet.
+type Wallet struct {
+	ID         string    `json:"id" db:"id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	Address    string    `json:"address" db:"address"`
+	ChainID    int64     `json:"chain_id" db:"chain_id"`
+	IsPrimary  bool      `json:"is_primary" db:"is_primary"`
+	VerifiedAt time.Time `json:"verified_at" db:"verified_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+type WalletNonce struct {
+	Nonce     string    `json:"nonce"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// WalletSignatureRequest is a Sign-In with Ethereum message exactly as the
+// wallet signed it, and the hex personal_sign signature.
+type WalletSignatureRequest struct {
+	Message   string `json:"message" binding:"required,max=4096"`
+	Signature string `json:"signature" binding:"required,max=140"`
+}
@@ -0,0 +1,111 @@
+// Package ratelimit throttles clients with token buckets. Each bucket holds
+// up to Burst tokens and refills evenly over Period; every request takes one
+// token and is refused when none is left.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Burst requests at once and Burst requests per Period on
+// average. The zero Limit allows everything.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit parses "<burst>/<period>", as in "10/1m". An empty string, or
+// a zero burst, means no limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Limit{}, nil
+	}
+	burstText, periodText, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q: want <burst>/<period>", s)
+	}
+	burst, err := strconv.Atoi(strings.TrimSpace(burstText))
+	if err != nil || burst < 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: invalid burst", s)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(periodText))
+	if err != nil || period < time.Millisecond {
+		return Limit{}, fmt.Errorf("rate limit %q: invalid period", s)
+	}
+	if burst == 0 {
+		return Limit{}, nil
+	}
+	return Limit{Burst: burst, Period: period}, nil
+}
+
+// Unlimited reports whether l lets every request through.
+func (l Limit) Unlimited() bool {
+	return l.Burst <= 0 || l.Period <= 0
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "unlimited"
+	}
+	return strconv.Itoa(l.Burst) + "/" + l.Period.String()
+}
+
+// perSecond is the refill rate in tokens per second.
+func (l Limit) perSecond() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result is the state of a bucket after a request tried to take a token.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until a token is available again; zero when
+	// the request was allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store holds the buckets. Take is atomic per key, so that concurrent
+// requests never share a token.
+type Store interface {
+	// Take takes a token from the bucket under key, which starts out full.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// refill returns how many tokens a bucket holding tokens has after elapsed.
+func refill(tokens float64, elapsed time.Duration, limit Limit) float64 {
+	if elapsed > 0 {
+		tokens += elapsed.Seconds() * limit.perSecond()
+	}
+	return math.Min(tokens, float64(limit.Burst))
+}
+
+// result describes a bucket left holding tokens.
+func result(allowed bool, tokens float64, limit Limit) Result {
+	rate := limit.perSecond()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{"10/1m", Limit{Burst: 10, Period: time.Minute}, false},
+		{" 5 / 30s ", Limit{Burst: 5, Period: 30 * time.Second}, false},
+		{"", Limit{}, false},
+		{"0/1m", Limit{}, false},
+		{"10", Limit{}, true},
+		{"ten/1m", Limit{}, true},
+		{"-1/1m", Limit{}, true},
+		{"10/forever", Limit{}, true},
+		{"10/1us", Limit{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %v, %v, want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// clock is a settable time source for the stores under test.
+type clock struct{ now time.Time }
+
+func (c *clock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// stores returns an empty store of every backend and a clock driving both.
+func stores(t *testing.T) (map[string]Store, func(time.Duration)) {
+	t.Helper()
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	memory := NewMemoryStore()
+	memory.now = func() time.Time { return c.now }
+
+	server := miniredis.RunT(t)
+	server.SetTime(c.now)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	advance := func(d time.Duration) {
+		c.advance(d)
+		server.SetTime(c.now)
+		server.FastForward(d)
+	}
+	return map[string]Store{"memory": memory, "redis": NewRedisStore(client, "test")}, advance
+}
+
+func TestTake(t *testing.T) {
+	ctx := context.Background()
+	limit := Limit{Burst: 3, Period: 3 * time.Second}
+
+	backends, advance := stores(t)
+	type step struct {
+		after      time.Duration
+		key        string
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		reset      time.Duration
+	}
+	steps := []step{
+		{0, "a", true, 2, 0, time.Second},
+		{0, "a", true, 1, 0, 2 * time.Second},
+		{0, "a", true, 0, 0, 3 * time.Second},
+		{0, "a", false, 0, time.Second, 3 * time.Second},
+		// Buckets are independent.
+		{0, "b", true, 2, 0, time.Second},
+		// Half a token back: still refused, for the other half.
+		{500 * time.Millisecond, "a", false, 0, 500 * time.Millisecond, 2500 * time.Millisecond},
+		{500 * time.Millisecond, "a", true, 0, 0, 3 * time.Second},
+		// Refilling stops at Burst.
+		{time.Hour, "a", true, 2, 0, time.Second},
+	}
+
+	for i, s := range steps {
+		if s.after > 0 {
+			advance(s.after)
+		}
+		for name, store := range backends {
+			got, err := store.Take(ctx, s.key, limit)
+			if err != nil {
+				t.Fatalf("%s step %d: %v", name, i, err)
+			}
+			want := Result{Allowed: s.allowed, Limit: 3, Remaining: s.remaining, RetryAfter: s.retryAfter, Reset: s.reset}
+			if got != want {
+				t.Errorf("%s step %d: Take(%s) = %+v, want %+v", name, i, s.key, got, want)
+			}
+		}
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Burst: 2, Period: time.Hour}
+
+	store.Take(ctx, "a", limit)
+	now = now.Add(sweepInterval)
+	store.Take(ctx, "b", limit)
+	if n := len(store.buckets); n != 2 {
+		t.Fatalf("buckets = %d before any refilled, want 2", n)
+	}
+
+	// Each bucket refills half an hour after its take.
+	now = now.Add(time.Hour)
+	store.Take(ctx, "c", limit)
+	if _, ok := store.buckets["a"]; ok {
+		t.Error("refilled bucket a was kept")
+	}
+	if _, ok := store.buckets["b"]; ok {
+		t.Error("refilled bucket b was kept")
+	}
+	if _, ok := store.buckets["c"]; !ok {
+		t.Error("bucket c was dropped")
+	}
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// encodeCursor packs a (created_at, id) keyset position into an opaque token.
+func encodeCursor(createdAt time.Time, id string) string {
+	raw := strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return time.Unix(0, nanos).UTC(), parts[1], nil
+}
+
+// EncodeRankCursor packs a (balance, balance_reached_at, id) leaderboard
+// position into an opaque token. It is exported so that leaderboard caches
+// hand out cursors interchangeable with the database ones.
+func EncodeRankCursor(balance int, reachedAt time.Time, id string) string {
+	raw := strconv.Itoa(balance) + ":" + strconv.FormatInt(reachedAt.UnixNano(), 10) + ":" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeRankCursor(cursor string) (int, time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, time.Time{}, "", ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 3)
+	if len(parts) != 3 || parts[2] == "" {
+		return 0, time.Time{}, "", ErrInvalidCursor
+	}
+	balance, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, time.Time{}, "", ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, "", ErrInvalidCursor
+	}
+	return balance, time.Unix(0, nanos).UTC(), parts[2], nil
+}
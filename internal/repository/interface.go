@@ -2,48 +2,305 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"denet/internal/apperror"
 	"denet/internal/model"
+	"denet/internal/store"
+	"net/http"
+	"time"
 )
 
 var (
-	ErrUserNotFound    = errors.New("user not found")
-	ErrTaskNotFound    = errors.New("task not found")
-	ErrUserExists      = errors.New("user already exists")
-	ErrInvalidPassword = errors.New("invalid password")
+	ErrUserNotFound    = apperror.New("user_not_found", http.StatusNotFound, "User not found")
+	ErrTaskNotFound    = apperror.New("task_not_found", http.StatusNotFound, "Task not found")
+	ErrUserExists      = apperror.New("user_exists", http.StatusConflict, "Username or email already exists")
+	ErrInvalidPassword = apperror.New("invalid_password", http.StatusUnauthorized, "Invalid credentials")
+
+	ErrUserTaskNotFound = apperror.New("task_submission_not_found", http.StatusNotFound, "Task submission not found")
+	ErrUserTaskExists   = apperror.New("task_submission_exists", http.StatusConflict, "Task already submitted")
+
+	ErrReferrerAlreadySet   = apperror.New("referrer_already_set", http.StatusConflict, "Referrer already set")
+	ErrReferralCodeNotFound = apperror.New("referral_code_not_found", http.StatusNotFound, "Referral code not found")
+	ErrReferralCodeTaken    = apperror.New("referral_code_taken", http.StatusConflict, "Referral code already taken")
+	ErrReferralRuleNotFound = apperror.New("referral_rule_not_found", http.StatusNotFound, "Referral rule not found")
+	ErrReferralCycle        = apperror.New("referral_cycle", http.StatusBadRequest, "Referral cycle is not allowed")
+
+	ErrRoleNotFound       = apperror.New("role_not_found", http.StatusNotFound, "Role not found")
+	ErrRoleExists         = apperror.New("role_exists", http.StatusConflict, "Role already exists")
+	ErrRoleAlreadyGranted = apperror.New("role_already_granted", http.StatusConflict, "Role already granted")
+	ErrRoleNotGranted     = apperror.New("role_not_granted", http.StatusNotFound, "Role not granted")
+
+	ErrRefreshTokenNotFound = apperror.New("refresh_token_not_found", http.StatusUnauthorized, "Invalid refresh token")
+
+	ErrIdempotencyKeyNotFound = apperror.New("idempotency_key_not_found", http.StatusNotFound, "Idempotency key not found")
+
+	ErrSocialAccountNotFound = apperror.New("social_account_not_found", http.StatusNotFound, "Social account not linked")
+	ErrSocialAccountTaken    = apperror.New("social_account_taken", http.StatusConflict, "Social account is linked to another user")
+
+	ErrWalletNotFound      = apperror.New("wallet_not_found", http.StatusNotFound, "Wallet not found")
+	ErrWalletTaken         = apperror.New("wallet_taken", http.StatusConflict, "Wallet is already linked")
+	ErrWalletNonceNotFound = apperror.New("wallet_nonce_not_found", http.StatusUnauthorized, "Sign-in nonce is unknown, used or expired")
+
+	ErrSeasonNotFound = apperror.New("season_not_found", http.StatusNotFound, "Season not found")
+
+	ErrRewardNotFound      = apperror.New("reward_not_found", http.StatusNotFound, "Reward not found")
+	ErrRewardOrderNotFound = apperror.New("reward_order_not_found", http.StatusNotFound, "Reward order not found")
+
+	ErrLoginLockoutNotFound = apperror.New("login_lockout_not_found", http.StatusNotFound, "No failed logins recorded")
+
+	ErrAirdropNotFound      = apperror.New("airdrop_not_found", http.StatusNotFound, "Airdrop not found")
+	ErrAirdropExists        = apperror.New("airdrop_exists", http.StatusConflict, "Airdrop with this name already exists")
+	ErrAirdropClaimNotFound = apperror.New("airdrop_claim_not_found", http.StatusNotFound, "User has no allocation in this airdrop")
+
+	ErrDuplicateTransaction = apperror.New("duplicate_transaction", http.StatusConflict, "Duplicate point transaction")
+	ErrInsufficientBalance  = apperror.New("insufficient_balance", http.StatusConflict, "Not enough points")
+	ErrTransactionNotFound  = apperror.New("transaction_not_found", http.StatusNotFound, "Point transaction not found")
+	ErrInvalidCursor        = apperror.New("invalid_cursor", http.StatusBadRequest, "Invalid cursor")
 )
 
 type UserRepository interface {
 	Create(ctx context.Context, user *model.User) error
 	CreateWithPassword(ctx context.Context, user *model.User, password string) error
 	GetByID(ctx context.Context, id string) (*model.User, error)
+	// GetByIDForUpdate locks the user row, and with it the balance, until
+	// the surrounding transaction ends.
+	GetByIDForUpdate(ctx context.Context, id string) (*model.User, error)
 	GetByUsername(ctx context.Context, username string) (*model.User, error)
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
-	UpdateBalance(ctx context.Context, id string, newBalance int) error
+	// SetReferrer links userID to referrerID once; ErrReferrerAlreadySet is
+	// returned if a referrer is already recorded and ErrReferralCycle if
+	// userID is already an ancestor of referrerID.
 	SetReferrer(ctx context.Context, userID, referrerID string) error
-	GetLeaderboard(ctx context.Context, limit int) ([]model.LeaderboardUser, error)
+	GetByReferralCode(ctx context.Context, code string) (*model.User, error)
+	SetReferralCode(ctx context.Context, userID, code string) error
+	// GetReferralAncestors walks referrer_id upwards from userID and returns
+	// at most maxLevel ancestors, the direct referrer being level 1.
+	GetReferralAncestors(ctx context.Context, userID string, maxLevel int) ([]model.ReferralAncestor, error)
+	GetLeaderboard(ctx context.Context, cursor string, limit int) (*model.LeaderboardPage, error)
+	// GetRank returns the user's leaderboard position and up to neighbours
+	// users on either side of them.
+	GetRank(ctx context.Context, userID string, neighbours int) (*model.UserRank, error)
+	GetLeaderboardEntry(ctx context.Context, userID string) (*model.LeaderboardUser, error)
+	// ScanLeaderboard streams every user in leaderboard order.
+	ScanLeaderboard(ctx context.Context, fn func(model.LeaderboardUser) error) error
 	VerifyPassword(ctx context.Context, username, password string) (*model.User, error)
+	// CheckPassword returns ErrInvalidPassword unless password matches the
+	// user's password hash.
+	CheckPassword(ctx context.Context, user *model.User, password string) error
 }
 
 type TaskRepository interface {
 	GetByID(ctx context.Context, id string) (*model.Task, error)
+	// GetAll returns the tasks visible to users: published and not archived.
 	GetAll(ctx context.Context) ([]model.Task, error)
+	List(ctx context.Context, filter model.TaskFilter) ([]model.Task, error)
+	Create(ctx context.Context, task *model.Task) error
+	Update(ctx context.Context, task *model.Task) error
+	Archive(ctx context.Context, id string) (*model.Task, error)
 }
 
 type UserTaskRepository interface {
-	CompleteTask(ctx context.Context, userID, taskID string) error
+	// Create records a submission; ErrUserTaskExists is returned if the user
+	// already submitted the task.
+	Create(ctx context.Context, userTask *model.UserTask) error
+	GetByID(ctx context.Context, id string) (*model.UserTask, error)
+	// Get returns the user's submission for the task.
+	Get(ctx context.Context, userID, taskID string) (*model.UserTask, error)
+	// GetByIDForUpdate and GetForUpdate lock the submission until the
+	// surrounding transaction ends so that reviews are serialized.
+	GetByIDForUpdate(ctx context.Context, id string) (*model.UserTask, error)
+	GetForUpdate(ctx context.Context, userID, taskID string) (*model.UserTask, error)
+	Update(ctx context.Context, userTask *model.UserTask) error
+	// GetCompletedTasks returns the user's approved submissions.
 	GetCompletedTasks(ctx context.Context, userID string) ([]model.UserTask, error)
-	IsTaskCompleted(ctx context.Context, userID, taskID string) (bool, error)
+	// ListByStatus pages through submissions by when they last changed,
+	// oldest first, so a resubmission joins the end of the review queue.
+	ListByStatus(ctx context.Context, status model.TaskSubmissionStatus, cursor string, limit int) (*model.UserTaskPage, error)
+}
+
+// LedgerRepository is the only way to change a user's balance: every entry is
+// appended to point_transactions and users.balance is moved by the same delta.
+type LedgerRepository interface {
+	// Append records the entry and returns the user's resulting balance.
+	// ErrDuplicateTransaction is returned when the idempotency key was already used.
+	Append(ctx context.Context, entry *model.PointTransaction) (int, error)
+	ListByUser(ctx context.Context, userID, cursor string, limit int) (*model.PointTransactionPage, error)
+	GetByIdempotencyKey(ctx context.Context, key string) (*model.PointTransaction, error)
+	// SumEarned nets the entries of one source for a user, so clawed back
+	// points no longer count.
+	SumEarned(ctx context.Context, userID string, source model.PointSource) (int, error)
+	// GetWindowLeaderboard ranks users by the net points of
+	// model.EarningSources recorded in [from, to).
+	GetWindowLeaderboard(ctx context.Context, from, to time.Time, limit int) ([]model.WindowLeaderboardEntry, error)
+}
+
+type SocialAccountRepository interface {
+	// Link attaches the account to the user, replacing their previous one on
+	// the same platform. ErrSocialAccountTaken is returned if another user
+	// already linked it.
+	Link(ctx context.Context, account *model.SocialAccount) error
+	Get(ctx context.Context, userID string, provider model.SocialProvider) (*model.SocialAccount, error)
+	GetByExternalID(ctx context.Context, provider model.SocialProvider, externalID string) (*model.SocialAccount, error)
+	ListByUser(ctx context.Context, userID string) ([]model.SocialAccount, error)
+	Unlink(ctx context.Context, userID string, provider model.SocialProvider) error
+}
+
+type WalletRepository interface {
+	CreateNonce(ctx context.Context, nonce *model.WalletNonce) error
+	// ConsumeNonce deletes the nonce; ErrWalletNonceNotFound is returned if
+	// it was never issued, is already used or expired before now.
+	ConsumeNonce(ctx context.Context, nonce string, now time.Time) error
+	DeleteExpiredNonces(ctx context.Context, now time.Time) error
+	// Create stores a verified wallet; ErrWalletTaken is returned if the
+	// address is linked already, to this user or another.
+	Create(ctx context.Context, wallet *model.Wallet) error
+	GetByAddress(ctx context.Context, address string) (*model.Wallet, error)
+	// ListByUser returns the user's wallets, oldest first.
+	ListByUser(ctx context.Context, userID string) ([]model.Wallet, error)
+	SetPrimary(ctx context.Context, userID, address string) error
+	Delete(ctx context.Context, userID, address string) (*model.Wallet, error)
 }
 
+type SeasonRepository interface {
+	Create(ctx context.Context, season *model.Season) error
+	GetByID(ctx context.Context, id string) (*model.Season, error)
+	// GetCurrent returns the season running at now, preferring the one that
+	// started last when seasons overlap.
+	GetCurrent(ctx context.Context, now time.Time) (*model.Season, error)
+	List(ctx context.Context) ([]model.Season, error)
+	// LockEnded returns ended, not yet archived seasons and locks them for
+	// the surrounding transaction, skipping ones another worker holds.
+	LockEnded(ctx context.Context, now time.Time) ([]model.Season, error)
+	// Archive stores the final standings of season and marks it archived.
+	Archive(ctx context.Context, season *model.Season) error
+	GetResults(ctx context.Context, seasonID string, limit int) ([]model.WindowLeaderboardEntry, error)
+}
+
+type RewardRepository interface {
+	GetByID(ctx context.Context, id string) (*model.Reward, error)
+	// GetByIDForUpdate locks the reward until the surrounding transaction
+	// ends, serializing redemptions of it.
+	GetByIDForUpdate(ctx context.Context, id string) (*model.Reward, error)
+	List(ctx context.Context, filter model.RewardFilter) ([]model.Reward, error)
+	Create(ctx context.Context, reward *model.Reward) error
+	Update(ctx context.Context, reward *model.Reward) error
+	Archive(ctx context.Context, id string) (*model.Reward, error)
+	// AdjustStock moves a limited stock by delta; unlimited rewards are left
+	// alone.
+	AdjustStock(ctx context.Context, id string, delta int) error
+
+	CreateOrder(ctx context.Context, order *model.RewardOrder) error
+	GetOrderForUpdate(ctx context.Context, id string) (*model.RewardOrder, error)
+	UpdateOrder(ctx context.Context, order *model.RewardOrder) error
+	// CountActiveOrders counts the user's orders of a reward that were not
+	// cancelled.
+	CountActiveOrders(ctx context.Context, userID, rewardID string) (int, error)
+	// ListOrdersByUser pages through the user's orders, newest first.
+	ListOrdersByUser(ctx context.Context, userID, cursor string, limit int) (*model.RewardOrderPage, error)
+	// ListOrdersByStatus pages through orders oldest first, the order they
+	// are fulfilled in.
+	ListOrdersByStatus(ctx context.Context, status model.RewardOrderStatus, cursor string, limit int) (*model.RewardOrderPage, error)
+}
+
+// LoginLockoutRepository keeps count of failed password logins. Users
+// without a record have none.
+type LoginLockoutRepository interface {
+	Get(ctx context.Context, userID string) (*model.LoginLockout, error)
+	// GetForUpdate returns the user's record, creating an empty one if there
+	// is none, and locks it until the surrounding transaction ends.
+	GetForUpdate(ctx context.Context, userID string) (*model.LoginLockout, error)
+	Update(ctx context.Context, lockout *model.LoginLockout) error
+	Delete(ctx context.Context, userID string) error
+}
+
+type AirdropRepository interface {
+	Create(ctx context.Context, airdrop *model.Airdrop) error
+	GetByID(ctx context.Context, id string) (*model.Airdrop, error)
+	// GetLatest returns the airdrop with the latest snapshot time.
+	GetLatest(ctx context.Context) (*model.Airdrop, error)
+	List(ctx context.Context) ([]model.Airdrop, error)
+	// SnapshotBalances returns the positive ledger balances at the given time
+	// of users that have a primary wallet now, paired with that wallet rather
+	// than the one that was primary at the given time.
+	SnapshotBalances(ctx context.Context, at time.Time) ([]model.AirdropBalance, error)
+	AddClaim(ctx context.Context, claim *model.AirdropClaim) error
+	GetClaim(ctx context.Context, airdropID, userID string) (*model.AirdropClaim, error)
+}
+
+type ReferralRepository interface {
+	ListRules(ctx context.Context, activeOnly bool) ([]model.ReferralRule, error)
+	GetRule(ctx context.Context, id string) (*model.ReferralRule, error)
+	CreateRule(ctx context.Context, rule *model.ReferralRule) error
+	UpdateRule(ctx context.Context, rule *model.ReferralRule) error
+	ListReferees(ctx context.Context, userID, cursor string, limit int) (*model.RefereePage, error)
+	// GetTree returns up to limit descendants of userID no deeper than depth,
+	// ordered by depth. Nodes are returned flat; Children is left empty.
+	GetTree(ctx context.Context, userID string, depth, limit int) ([]*model.ReferralTreeNode, error)
+	GetStats(ctx context.Context, userID string) (*model.ReferralStats, error)
+}
+
+// RoleRepository stores roles, their grants to users and the audit log of
+// grants and revocations.
+type RoleRepository interface {
+	List(ctx context.Context) ([]model.Role, error)
+	GetByName(ctx context.Context, name string) (*model.Role, error)
+	Create(ctx context.Context, role *model.Role) error
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	GetUserPermissions(ctx context.Context, userID string) ([]string, error)
+	Grant(ctx context.Context, userID, roleName string, grantedBy *string) error
+	Revoke(ctx context.Context, userID, roleName string) error
+	AddAuditEntry(ctx context.Context, entry *model.RoleAuditEntry) error
+	ListAuditEntries(ctx context.Context, userID string) ([]model.RoleAuditEntry, error)
+}
+
+type TokenRepository interface {
+	CreateSession(ctx context.Context, session *model.Session) error
+	RevokeSession(ctx context.Context, sessionID, reason string) error
+	RevokeUserSessions(ctx context.Context, userID, reason string) error
+	CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error
+	// GetRefreshTokenForUpdate locks the token row until the surrounding
+	// transaction ends so that concurrent refreshes are serialized.
+	GetRefreshTokenForUpdate(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id string) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti, sessionID string) (bool, error)
+}
+
+// TransactionRepository runs fn inside a database transaction carried on the
+// context passed to fn; repositories called with that context join it.
+// Nested calls become savepoints, and the outermost call is retried when the
+// transaction fails with store.ErrSerializationFailure.
 type TransactionRepository interface {
 	WithTransaction(ctx context.Context, fn func(context.Context) error) error
+	WithTransactionOptions(ctx context.Context, opts store.TxOptions, fn func(context.Context) error) error
+}
+
+type IdempotencyRepository interface {
+	// Reserve claims the key for a new request and reports whether it did.
+	// It fails to claim a key that is in flight or holds an unexpired
+	// response.
+	Reserve(ctx context.Context, record *model.IdempotencyRecord) (bool, error)
+	Get(ctx context.Context, scope, key string) (*model.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *model.IdempotencyRecord) error
+	// Release drops an in-flight reservation so the request can be retried.
+	Release(ctx context.Context, scope, key string) error
+	DeleteExpired(ctx context.Context, now time.Time) error
 }
 
 type UnitOfWork interface {
 	Users() UserRepository
 	Tasks() TaskRepository
 	UserTasks() UserTaskRepository
+	Ledger() LedgerRepository
+	Roles() RoleRepository
+	Tokens() TokenRepository
+	Idempotency() IdempotencyRepository
+	Referrals() ReferralRepository
+	Seasons() SeasonRepository
+	SocialAccounts() SocialAccountRepository
+	Wallets() WalletRepository
+	Rewards() RewardRepository
+	Airdrops() AirdropRepository
+	LoginLockouts() LoginLockoutRepository
 	Transactions() TransactionRepository
 	Close() error
 }
\ No newline at end of file
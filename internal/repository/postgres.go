@@ -2,19 +2,26 @@ package repository
 
 import (
 	"context"
-	"denet/internal/store"
+	"database/sql"
 	"denet/internal/model"
+	"denet/internal/store"
+	"denet/internal/tracing"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type PostgresUnitOfWork struct {
-	db store.Database
+	db       store.Database
+	txConfig TxConfig
 }
 
-func NewPostgresUnitOfWork(db store.Database) UnitOfWork {
-	return &PostgresUnitOfWork{db: db}
+func NewPostgresUnitOfWork(db store.Database, txConfig TxConfig) UnitOfWork {
+	return &PostgresUnitOfWork{db: db, txConfig: txConfig}
 }
 
 func (uow *PostgresUnitOfWork) Users() UserRepository {
@@ -29,8 +36,52 @@ func (uow *PostgresUnitOfWork) UserTasks() UserTaskRepository {
 	return &PostgresUserTaskRepository{db: uow.db}
 }
 
+func (uow *PostgresUnitOfWork) Ledger() LedgerRepository {
+	return &PostgresLedgerRepository{db: uow.db}
+}
+
+func (uow *PostgresUnitOfWork) Roles() RoleRepository {
+	return &PostgresRoleRepository{db: uow.db}
+}
+
+func (uow *PostgresUnitOfWork) Tokens() TokenRepository {
+	return &PostgresTokenRepository{db: uow.db}
+}
+
+func (uow *PostgresUnitOfWork) Idempotency() IdempotencyRepository {
+	return &PostgresIdempotencyRepository{db: uow.db}
+}
+
+func (uow *PostgresUnitOfWork) Referrals() ReferralRepository {
+	return &PostgresReferralRepository{db: uow.db}
+}
+
+func (uow *PostgresUnitOfWork) Seasons() SeasonRepository {
+	return &PostgresSeasonRepository{db: uow.db}
+}
+
+func (uow *PostgresUnitOfWork) SocialAccounts() SocialAccountRepository {
+	return &PostgresSocialAccountRepository{db: uow.db}
+}
+
+func (uow *PostgresUnitOfWork) Wallets() WalletRepository {
+	return &PostgresWalletRepository{db: uow.db}
+}
+
+func (uow *PostgresUnitOfWork) Rewards() RewardRepository {
+	return &PostgresRewardRepository{db: uow.db}
+}
+
+func (uow *PostgresUnitOfWork) Airdrops() AirdropRepository {
+	return &PostgresAirdropRepository{db: uow.db}
+}
+
+func (uow *PostgresUnitOfWork) LoginLockouts() LoginLockoutRepository {
+	return &PostgresLoginLockoutRepository{db: uow.db}
+}
+
 func (uow *PostgresUnitOfWork) Transactions() TransactionRepository {
-	return &PostgresTransactionRepository{db: uow.db}
+	return &PostgresTransactionRepository{db: uow.db, config: uow.txConfig}
 }
 
 func (uow *PostgresUnitOfWork) Close() error {
@@ -41,71 +92,246 @@ type PostgresUserRepository struct {
 	db store.Database
 }
 
+const userColumns = `id, username, COALESCE(email, ''), COALESCE(password_hash, ''), balance, referrer_id, referral_code, created_at, updated_at`
+
+func scanUser(row store.Row, user *model.User) error {
+	return row.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Balance, &user.ReferrerID, &user.ReferralCode, &user.CreatedAt, &user.UpdatedAt)
+}
+
 func (r *PostgresUserRepository) Create(ctx context.Context, user *model.User) error {
 	user.ID = uuid.New().String()
-	query := `INSERT INTO users (id, username, email, balance) VALUES ($1, $2, $3, $4)`
-	return r.db.Exec(ctx, query, user.ID, user.Username, user.Email, user.Balance)
+	query := `INSERT INTO users (id, username, email, balance, referral_code) VALUES ($1, $2, NULLIF($3, ''), $4, $5)`
+	err := querier(ctx, r.db).Exec(ctx, query, user.ID, user.Username, user.Email, user.Balance, user.ReferralCode)
+	if errors.Is(err, store.ErrUniqueViolation) {
+		return ErrUserExists
+	}
+	return err
 }
 
 func (r *PostgresUserRepository) CreateWithPassword(ctx context.Context, user *model.User, password string) error {
 	user.ID = uuid.New().String()
+	_, span := tracing.Start(ctx, "bcrypt.GenerateFromPassword")
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	tracing.End(span, err)
 	if err != nil {
 		return err
 	}
-	query := `INSERT INTO users (id, username, email, password_hash, balance) VALUES ($1, $2, $3, $4, $5)`
-	return r.db.Exec(ctx, query, user.ID, user.Username, user.Email, string(hashedPassword), user.Balance)
+	query := `INSERT INTO users (id, username, email, password_hash, balance, referral_code) VALUES ($1, $2, $3, $4, $5, $6)`
+	err = querier(ctx, r.db).Exec(ctx, query, user.ID, user.Username, user.Email, string(hashedPassword), user.Balance, user.ReferralCode)
+	if errors.Is(err, store.ErrUniqueViolation) {
+		return ErrUserExists
+	}
+	return err
 }
 
 func (r *PostgresUserRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
-	query := `SELECT id, username, email, password_hash, balance, referrer_id, created_at, updated_at FROM users WHERE id = $1`
-	row := r.db.QueryRow(ctx, query, id)
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+}
+
+func (r *PostgresUserRepository) GetByIDForUpdate(ctx context.Context, id string) (*model.User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1 FOR UPDATE`, id)
+}
+
+func (r *PostgresUserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE username = $1`, username)
+}
+
+func (r *PostgresUserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1`, email)
+}
+
+func (r *PostgresUserRepository) getOne(ctx context.Context, query string, args ...interface{}) (*model.User, error) {
+	row := querier(ctx, r.db).QueryRow(ctx, query, args...)
 	var user model.User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Balance, &user.ReferrerID, &user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
-		return nil, ErrUserNotFound
+	if err := scanUser(row, &user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *PostgresUserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
-	query := `SELECT id, username, email, password_hash, balance, referrer_id, created_at, updated_at FROM users WHERE username = $1`
-	row := r.db.QueryRow(ctx, query, username)
+func (r *PostgresUserRepository) GetByReferralCode(ctx context.Context, code string) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE lower(referral_code) = lower($1)`
+	row := querier(ctx, r.db).QueryRow(ctx, query, code)
 	var user model.User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Balance, &user.ReferrerID, &user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
-		return nil, ErrUserNotFound
+	if err := scanUser(row, &user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReferralCodeNotFound
+		}
+		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *PostgresUserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
-	query := `SELECT id, username, email, password_hash, balance, referrer_id, created_at, updated_at FROM users WHERE email = $1`
-	row := r.db.QueryRow(ctx, query, email)
-	var user model.User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Balance, &user.ReferrerID, &user.CreatedAt, &user.UpdatedAt)
+func (r *PostgresUserRepository) SetReferralCode(ctx context.Context, userID, code string) error {
+	query := `UPDATE users SET referral_code = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 RETURNING id`
+	row := querier(ctx, r.db).QueryRow(ctx, query, code, userID)
+	var id string
+	if err := row.Scan(&id); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrUserNotFound
+		case errors.Is(err, store.ErrUniqueViolation):
+			return ErrReferralCodeTaken
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) GetReferralAncestors(ctx context.Context, userID string, maxLevel int) ([]model.ReferralAncestor, error) {
+	query := `WITH RECURSIVE chain AS (
+		SELECT referrer_id AS id, 1 AS level FROM users WHERE id = $1 AND referrer_id IS NOT NULL
+		UNION ALL
+		SELECT u.referrer_id, c.level + 1 FROM chain c JOIN users u ON u.id = c.id
+		WHERE u.referrer_id IS NOT NULL AND c.level < $2
+	)
+	SELECT id, level FROM chain ORDER BY level`
+	rows, err := querier(ctx, r.db).Query(ctx, query, userID, maxLevel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ancestors []model.ReferralAncestor
+	for rows.Next() {
+		var ancestor model.ReferralAncestor
+		if err := rows.Scan(&ancestor.UserID, &ancestor.Level); err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, ancestor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ancestors, nil
+}
+
+// Leaderboard order is balance, then whoever reached that balance first, then
+// id so that every position is unique and pages never overlap.
+const (
+	leaderboardColumns = `id, username, balance, balance_reached_at`
+	leaderboardOrder   = `balance DESC, balance_reached_at, id`
+	rankedBefore       = `(balance > $1 OR (balance = $1 AND (balance_reached_at, id) < ($2, $3)))`
+	rankedThrough      = `(balance > $1 OR (balance = $1 AND (balance_reached_at, id) <= ($2, $3)))`
+	rankedAfter        = `(balance < $1 OR (balance = $1 AND (balance_reached_at, id) > ($2, $3)))`
+)
+
+func (r *PostgresUserRepository) GetLeaderboard(ctx context.Context, cursor string, limit int) (*model.LeaderboardPage, error) {
+	query := `SELECT ` + leaderboardColumns + ` FROM users`
+	var args []interface{}
+	rank := 1
+	if cursor != "" {
+		balance, reachedAt, id, err := DecodeRankCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		args = []interface{}{balance, reachedAt, id}
+		// The cursor row may have moved since the page was served, so count
+		// what is at or above its old position rather than trusting it.
+		row := querier(ctx, r.db).QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE `+rankedThrough, args...)
+		if err := row.Scan(&rank); err != nil {
+			return nil, err
+		}
+		rank++
+		query += ` WHERE ` + rankedAfter
+	}
+	query += ` ORDER BY ` + leaderboardOrder + ` LIMIT ` + strconv.Itoa(limit+1)
+
+	entries, err := r.listLeaderboard(ctx, query, args...)
 	if err != nil {
-		return nil, ErrUserNotFound
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Rank = rank + i
+	}
+	page := &model.LeaderboardPage{Entries: entries}
+	if len(page.Entries) > limit {
+		page.Entries = page.Entries[:limit]
+		last := page.Entries[limit-1]
+		page.NextCursor = EncodeRankCursor(last.Balance, last.ReachedAt, last.ID)
+	}
+	return page, nil
+}
+
+func (r *PostgresUserRepository) GetLeaderboardEntry(ctx context.Context, userID string) (*model.LeaderboardUser, error) {
+	var user model.LeaderboardUser
+	row := querier(ctx, r.db).QueryRow(ctx, `SELECT `+leaderboardColumns+` FROM users WHERE id = $1`, userID)
+	if err := row.Scan(&user.ID, &user.Username, &user.Balance, &user.ReachedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *PostgresUserRepository) UpdateBalance(ctx context.Context, id string, newBalance int) error {
-	query := `UPDATE users SET balance = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
-	return r.db.Exec(ctx, query, newBalance, id)
+func (r *PostgresUserRepository) ScanLeaderboard(ctx context.Context, fn func(model.LeaderboardUser) error) error {
+	rows, err := querier(ctx, r.db).Query(ctx, `SELECT `+leaderboardColumns+` FROM users ORDER BY `+leaderboardOrder)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rank := 1; rows.Next(); rank++ {
+		user := model.LeaderboardUser{Rank: rank}
+		if err := rows.Scan(&user.ID, &user.Username, &user.Balance, &user.ReachedAt); err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
-func (r *PostgresUserRepository) GetLeaderboard(ctx context.Context, limit int) ([]model.LeaderboardUser, error) {
-	query := `SELECT id, username, balance, RANK() OVER (ORDER BY balance DESC) as rank FROM users ORDER BY balance DESC LIMIT $1`
-	rows, err := r.db.Query(ctx, query, limit)
+func (r *PostgresUserRepository) GetRank(ctx context.Context, userID string, neighbours int) (*model.UserRank, error) {
+	entry, err := r.GetLeaderboardEntry(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := model.UserRank{User: *entry}
+	user := &result.User
+	args := []interface{}{user.Balance, user.ReachedAt, user.ID}
+
+	row := querier(ctx, r.db).QueryRow(ctx, `SELECT COUNT(*) FILTER (WHERE `+rankedBefore+`), COUNT(*) FROM users`, args...)
+	if err := row.Scan(&user.Rank, &result.Total); err != nil {
+		return nil, err
+	}
+	user.Rank++
+
+	above, err := r.listLeaderboard(ctx, `SELECT `+leaderboardColumns+` FROM users WHERE `+rankedBefore+
+		` ORDER BY balance, balance_reached_at DESC, id DESC LIMIT `+strconv.Itoa(neighbours), args...)
+	if err != nil {
+		return nil, err
+	}
+	result.Above = make([]model.LeaderboardUser, len(above))
+	for i, entry := range above {
+		entry.Rank = user.Rank - i - 1
+		result.Above[len(above)-i-1] = entry
+	}
+
+	result.Below, err = r.listLeaderboard(ctx, `SELECT `+leaderboardColumns+` FROM users WHERE `+rankedAfter+
+		` ORDER BY `+leaderboardOrder+` LIMIT `+strconv.Itoa(neighbours), args...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Below {
+		result.Below[i].Rank = user.Rank + i + 1
+	}
+	return &result, nil
+}
+
+func (r *PostgresUserRepository) listLeaderboard(ctx context.Context, query string, args ...interface{}) ([]model.LeaderboardUser, error) {
+	rows, err := querier(ctx, r.db).Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var users []model.LeaderboardUser
+	users := []model.LeaderboardUser{}
 	for rows.Next() {
 		var user model.LeaderboardUser
-		if err := rows.Scan(&user.ID, &user.Username, &user.Balance, &user.Rank); err != nil {
+		if err := rows.Scan(&user.ID, &user.Username, &user.Balance, &user.ReachedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -117,12 +343,43 @@ func (r *PostgresUserRepository) GetLeaderboard(ctx context.Context, limit int)
 }
 
 func (r *PostgresUserRepository) SetReferrer(ctx context.Context, userID, referrerID string) error {
-	_, err := r.GetByID(ctx, referrerID)
-	if err != nil {
-		return ErrUserNotFound
+	if _, err := r.GetByID(ctx, referrerID); err != nil {
+		return err
 	}
-	query := `UPDATE users SET referrer_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
-	return r.db.Exec(ctx, query, referrerID, userID)
+	// Serialize referral graph changes so that two concurrent links cannot
+	// close a cycle between them. The lock is held until the surrounding
+	// transaction ends.
+	if err := querier(ctx, r.db).Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('users.referrer_id'))`); err != nil {
+		return err
+	}
+	cycleQuery := `WITH RECURSIVE chain AS (
+		SELECT id, referrer_id FROM users WHERE id = $1
+		UNION
+		SELECT u.id, u.referrer_id FROM chain c JOIN users u ON u.id = c.referrer_id
+	)
+	SELECT EXISTS (SELECT 1 FROM chain WHERE id = $2)`
+	var cycle bool
+	if err := querier(ctx, r.db).QueryRow(ctx, cycleQuery, referrerID, userID).Scan(&cycle); err != nil {
+		return err
+	}
+	if cycle {
+		return ErrReferralCycle
+	}
+	// The referrer link is write-once: the update only matches while it is
+	// still unset.
+	query := `UPDATE users SET referrer_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND referrer_id IS NULL RETURNING id`
+	row := querier(ctx, r.db).QueryRow(ctx, query, referrerID, userID)
+	var id string
+	if err := row.Scan(&id); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if _, err := r.GetByID(ctx, userID); err != nil {
+			return err
+		}
+		return ErrReferrerAlreadySet
+	}
+	return nil
 }
 
 func (r *PostgresUserRepository) VerifyPassword(ctx context.Context, username, password string) (*model.User, error) {
@@ -130,39 +387,72 @@ func (r *PostgresUserRepository) VerifyPassword(ctx context.Context, username, p
 	if err != nil {
 		return nil, err
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
-	if err != nil {
-		return nil, ErrInvalidPassword
+	if err := r.CheckPassword(ctx, user, password); err != nil {
+		return nil, err
 	}
 	return user, nil
 }
 
+func (r *PostgresUserRepository) CheckPassword(ctx context.Context, user *model.User, password string) error {
+	_, span := tracing.Start(ctx, "bcrypt.CompareHashAndPassword")
+	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	span.End()
+	if err != nil {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
 type PostgresTaskRepository struct {
 	db store.Database
 }
 
+const taskColumns = `id, name, COALESCE(description, ''), points, published_at, archived_at, created_at, updated_at,
+	verification_type, verification_config`
+
+func scanTask(row store.Row, task *model.Task) error {
+	var config []byte
+	err := row.Scan(&task.ID, &task.Name, &task.Description, &task.Points, &task.PublishedAt, &task.ArchivedAt, &task.CreatedAt, &task.UpdatedAt,
+		&task.VerificationType, &config)
+	task.VerificationConfig = config
+	return err
+}
+
 func (r *PostgresTaskRepository) GetByID(ctx context.Context, id string) (*model.Task, error) {
-	query := `SELECT id, name, description, points, created_at FROM tasks WHERE id = $1`
-	row := r.db.QueryRow(ctx, query, id)
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = $1`
+	row := querier(ctx, r.db).QueryRow(ctx, query, id)
 	var task model.Task
-	err := row.Scan(&task.ID, &task.Name, &task.Description, &task.Points, &task.CreatedAt)
-	if err != nil {
-		return nil, ErrTaskNotFound
+	if err := scanTask(row, &task); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
 	}
 	return &task, nil
 }
 
 func (r *PostgresTaskRepository) GetAll(ctx context.Context) ([]model.Task, error) {
-	query := `SELECT id, name, description, points, created_at FROM tasks ORDER BY created_at`
-	rows, err := r.db.Query(ctx, query)
+	return r.List(ctx, model.TaskFilter{})
+}
+
+func (r *PostgresTaskRepository) List(ctx context.Context, filter model.TaskFilter) ([]model.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE TRUE`
+	if !filter.IncludeArchived {
+		query += ` AND archived_at IS NULL`
+	}
+	if !filter.IncludeUnpublished {
+		query += ` AND published_at IS NOT NULL AND published_at <= CURRENT_TIMESTAMP`
+	}
+	query += ` ORDER BY created_at, id`
+	rows, err := querier(ctx, r.db).Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var tasks []model.Task
+	tasks := []model.Task{}
 	for rows.Next() {
 		var task model.Task
-		if err := rows.Scan(&task.ID, &task.Name, &task.Description, &task.Points, &task.CreatedAt); err != nil {
+		if err := scanTask(rows, &task); err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, task)
@@ -173,27 +463,126 @@ func (r *PostgresTaskRepository) GetAll(ctx context.Context) ([]model.Task, erro
 	return tasks, nil
 }
 
+func (r *PostgresTaskRepository) Create(ctx context.Context, task *model.Task) error {
+	task.ID = uuid.New().String()
+	query := `INSERT INTO tasks (id, name, description, points, published_at, verification_type, verification_config)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, task.ID, task.Name, task.Description, task.Points, task.PublishedAt,
+		task.VerificationType, []byte(task.VerificationConfig))
+	return row.Scan(&task.CreatedAt, &task.UpdatedAt)
+}
+
+func (r *PostgresTaskRepository) Update(ctx context.Context, task *model.Task) error {
+	query := `UPDATE tasks SET name = $1, description = $2, points = $3, published_at = $4,
+		verification_type = $5, verification_config = $6, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7 RETURNING updated_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, task.Name, task.Description, task.Points, task.PublishedAt,
+		task.VerificationType, []byte(task.VerificationConfig), task.ID)
+	if err := row.Scan(&task.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTaskNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresTaskRepository) Archive(ctx context.Context, id string) (*model.Task, error) {
+	query := `UPDATE tasks SET archived_at = COALESCE(archived_at, CURRENT_TIMESTAMP), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 RETURNING ` + taskColumns
+	row := querier(ctx, r.db).QueryRow(ctx, query, id)
+	var task model.Task
+	if err := scanTask(row, &task); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
 type PostgresUserTaskRepository struct {
 	db store.Database
 }
 
-func (r *PostgresUserTaskRepository) CompleteTask(ctx context.Context, userID, taskID string) error {
-	userTaskID := uuid.New().String()
-	query := `INSERT INTO user_tasks (id, user_id, task_id, completed) VALUES ($1, $2, $3, true)`
-	return r.db.Exec(ctx, query, userTaskID, userID, taskID)
+const userTaskColumns = `id, user_id, task_id, status, proof, review_note, reviewed_by, reviewed_at, created_at, updated_at`
+
+func scanUserTask(row store.Row, userTask *model.UserTask) error {
+	return row.Scan(&userTask.ID, &userTask.UserID, &userTask.TaskID, &userTask.Status, &userTask.Proof,
+		&userTask.ReviewNote, &userTask.ReviewedBy, &userTask.ReviewedAt, &userTask.CreatedAt, &userTask.UpdatedAt)
+}
+
+func (r *PostgresUserTaskRepository) Create(ctx context.Context, userTask *model.UserTask) error {
+	userTask.ID = uuid.New().String()
+	query := `INSERT INTO user_tasks (id, user_id, task_id, status, proof, review_note, reviewed_by, reviewed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, userTask.ID, userTask.UserID, userTask.TaskID, userTask.Status,
+		userTask.Proof, userTask.ReviewNote, userTask.ReviewedBy, userTask.ReviewedAt)
+	if err := row.Scan(&userTask.CreatedAt, &userTask.UpdatedAt); err != nil {
+		if errors.Is(err, store.ErrUniqueViolation) {
+			return ErrUserTaskExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresUserTaskRepository) GetByID(ctx context.Context, id string) (*model.UserTask, error) {
+	return r.getOne(ctx, `SELECT `+userTaskColumns+` FROM user_tasks WHERE id = $1`, id)
+}
+
+func (r *PostgresUserTaskRepository) GetByIDForUpdate(ctx context.Context, id string) (*model.UserTask, error) {
+	return r.getOne(ctx, `SELECT `+userTaskColumns+` FROM user_tasks WHERE id = $1 FOR UPDATE`, id)
+}
+
+func (r *PostgresUserTaskRepository) Get(ctx context.Context, userID, taskID string) (*model.UserTask, error) {
+	return r.getOne(ctx, `SELECT `+userTaskColumns+` FROM user_tasks WHERE user_id = $1 AND task_id = $2`, userID, taskID)
+}
+
+func (r *PostgresUserTaskRepository) GetForUpdate(ctx context.Context, userID, taskID string) (*model.UserTask, error) {
+	return r.getOne(ctx, `SELECT `+userTaskColumns+` FROM user_tasks WHERE user_id = $1 AND task_id = $2 FOR UPDATE`, userID, taskID)
+}
+
+func (r *PostgresUserTaskRepository) getOne(ctx context.Context, query string, args ...interface{}) (*model.UserTask, error) {
+	row := querier(ctx, r.db).QueryRow(ctx, query, args...)
+	var userTask model.UserTask
+	if err := scanUserTask(row, &userTask); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserTaskNotFound
+		}
+		return nil, err
+	}
+	return &userTask, nil
+}
+
+func (r *PostgresUserTaskRepository) Update(ctx context.Context, userTask *model.UserTask) error {
+	query := `UPDATE user_tasks SET status = $1, proof = $2, review_note = $3, reviewed_by = $4, reviewed_at = $5,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6 RETURNING updated_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, userTask.Status, userTask.Proof, userTask.ReviewNote,
+		userTask.ReviewedBy, userTask.ReviewedAt, userTask.ID)
+	if err := row.Scan(&userTask.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserTaskNotFound
+		}
+		return err
+	}
+	return nil
 }
 
 func (r *PostgresUserTaskRepository) GetCompletedTasks(ctx context.Context, userID string) ([]model.UserTask, error) {
-	query := `SELECT id, user_id, task_id, completed, created_at FROM user_tasks WHERE user_id = $1 AND completed = true`
-	rows, err := r.db.Query(ctx, query, userID)
+	query := `SELECT ` + userTaskColumns + ` FROM user_tasks WHERE user_id = $1 AND status = $2 ORDER BY created_at, id`
+	rows, err := querier(ctx, r.db).Query(ctx, query, userID, model.TaskSubmissionApproved)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var userTasks []model.UserTask
+	userTasks := []model.UserTask{}
 	for rows.Next() {
 		var userTask model.UserTask
-		if err := rows.Scan(&userTask.ID, &userTask.UserID, &userTask.TaskID, &userTask.Completed, &userTask.CreatedAt); err != nil {
+		if err := scanUserTask(rows, &userTask); err != nil {
 			return nil, err
 		}
 		userTasks = append(userTasks, userTask)
@@ -204,29 +593,175 @@ func (r *PostgresUserTaskRepository) GetCompletedTasks(ctx context.Context, user
 	return userTasks, nil
 }
 
-func (r *PostgresUserTaskRepository) IsTaskCompleted(ctx context.Context, userID, taskID string) (bool, error) {
-	var id string
-	query := `SELECT id FROM user_tasks WHERE user_id = $1 AND task_id = $2 AND completed = true`
-	row := r.db.QueryRow(ctx, query, userID, taskID)
-	err := row.Scan(&id)
+func (r *PostgresUserTaskRepository) ListByStatus(ctx context.Context, status model.TaskSubmissionStatus, cursor string, limit int) (*model.UserTaskPage, error) {
+	query := `SELECT ` + userTaskColumns + ` FROM user_tasks WHERE status = $1`
+	args := []interface{}{status}
+	if cursor != "" {
+		updatedAt, id, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND (updated_at, id) > ($2, $3)`
+		args = append(args, updatedAt, id)
+	}
+	query += ` ORDER BY updated_at, id LIMIT ` + strconv.Itoa(limit+1)
+
+	rows, err := querier(ctx, r.db).Query(ctx, query, args...)
 	if err != nil {
-		return false, nil
+		return nil, err
 	}
-	return true, nil
+	defer rows.Close()
+	page := &model.UserTaskPage{Submissions: []model.UserTask{}}
+	for rows.Next() {
+		var userTask model.UserTask
+		if err := scanUserTask(rows, &userTask); err != nil {
+			return nil, err
+		}
+		page.Submissions = append(page.Submissions, userTask)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Submissions) > limit {
+		page.Submissions = page.Submissions[:limit]
+		last := page.Submissions[limit-1]
+		page.NextCursor = encodeCursor(last.UpdatedAt, last.ID)
+	}
+	return page, nil
 }
 
-type PostgresTransactionRepository struct {
+type PostgresLedgerRepository struct {
 	db store.Database
 }
 
-func (r *PostgresTransactionRepository) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
-	tx, err := r.db.BeginTx(ctx)
+func (r *PostgresLedgerRepository) Append(ctx context.Context, entry *model.PointTransaction) (int, error) {
+	entry.ID = uuid.New().String()
+	// The insert and the balance move happen in one statement, so concurrent
+	// appends for the same user serialize on the users row instead of
+	// overwriting each other. A repeated idempotency key inserts nothing and
+	// therefore updates nothing.
+	query := `WITH entry AS (
+		INSERT INTO point_transactions (id, user_id, delta, reason, source_type, source_id, idempotency_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (idempotency_key) DO NOTHING
+		RETURNING user_id, delta, created_at
+	)
+	UPDATE users SET balance = users.balance + entry.delta, balance_reached_at = entry.created_at,
+		updated_at = CURRENT_TIMESTAMP
+	FROM entry WHERE users.id = entry.user_id
+	RETURNING users.balance, entry.created_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, entry.ID, entry.UserID, entry.Delta, entry.Reason,
+		entry.SourceType, entry.SourceID, entry.IdempotencyKey)
+	var balance int
+	if err := row.Scan(&balance, &entry.CreatedAt); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrDuplicateTransaction
+		case errors.Is(err, store.ErrCheckViolation):
+			// users_balance_non_negative: the entry would overdraw the user.
+			return 0, ErrInsufficientBalance
+		}
+		return 0, err
+	}
+	return balance, nil
+}
+
+func (r *PostgresLedgerRepository) GetByIdempotencyKey(ctx context.Context, key string) (*model.PointTransaction, error) {
+	query := `SELECT id, user_id, delta, reason, source_type, source_id, idempotency_key, created_at
+		FROM point_transactions WHERE idempotency_key = $1`
+	row := querier(ctx, r.db).QueryRow(ctx, query, key)
+	var t model.PointTransaction
+	if err := row.Scan(&t.ID, &t.UserID, &t.Delta, &t.Reason, &t.SourceType, &t.SourceID, &t.IdempotencyKey, &t.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *PostgresLedgerRepository) SumEarned(ctx context.Context, userID string, source model.PointSource) (int, error) {
+	query := `SELECT COALESCE(SUM(delta), 0) FROM point_transactions WHERE user_id = $1 AND source_type = $2`
+	row := querier(ctx, r.db).QueryRow(ctx, query, userID, source)
+	var total int
+	if err := row.Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// windowLeaderboardQuery ranks users by points earned in [$1, $2). Ties are
+// broken by who reached their total first.
+var windowLeaderboardQuery = `SELECT pt.user_id, u.username, SUM(pt.delta) AS points,
+		RANK() OVER (ORDER BY SUM(pt.delta) DESC) AS rank
+	FROM point_transactions pt JOIN users u ON u.id = pt.user_id
+	WHERE pt.created_at >= $1 AND pt.created_at < $2 AND pt.source_type IN (` + earningSourcesList() + `)
+	GROUP BY pt.user_id, u.username
+	HAVING SUM(pt.delta) > 0
+	ORDER BY points DESC, MAX(pt.created_at), pt.user_id`
+
+func earningSourcesList() string {
+	quoted := make([]string, len(model.EarningSources))
+	for i, source := range model.EarningSources {
+		quoted[i] = "'" + string(source) + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func (r *PostgresLedgerRepository) GetWindowLeaderboard(ctx context.Context, from, to time.Time, limit int) ([]model.WindowLeaderboardEntry, error) {
+	rows, err := querier(ctx, r.db).Query(ctx, windowLeaderboardQuery+` LIMIT $3`, from, to, limit)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if err := fn(ctx); err != nil {
-		tx.Rollback()
-		return err
+	defer rows.Close()
+	entries := []model.WindowLeaderboardEntry{}
+	for rows.Next() {
+		var entry model.WindowLeaderboardEntry
+		if err := rows.Scan(&entry.UserID, &entry.Username, &entry.Points, &entry.Rank); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *PostgresLedgerRepository) ListByUser(ctx context.Context, userID, cursor string, limit int) (*model.PointTransactionPage, error) {
+	query := `SELECT id, user_id, delta, reason, source_type, source_id, idempotency_key, created_at
+		FROM point_transactions WHERE user_id = $1`
+	args := []interface{}{userID}
+	if cursor != "" {
+		createdAt, id, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND (created_at, id) < ($2, $3)`
+		args = append(args, createdAt, id)
 	}
-	return tx.Commit()
-}
\ No newline at end of file
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ` + strconv.Itoa(limit+1)
+
+	rows, err := querier(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	page := &model.PointTransactionPage{Transactions: []model.PointTransaction{}}
+	for rows.Next() {
+		var t model.PointTransaction
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Delta, &t.Reason, &t.SourceType, &t.SourceID, &t.IdempotencyKey, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		page.Transactions = append(page.Transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Transactions) > limit {
+		page.Transactions = page.Transactions[:limit]
+		last := page.Transactions[limit-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"denet/internal/model"
+	"denet/internal/store"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PostgresAirdropRepository struct {
+	db store.Database
+}
+
+const airdropColumns = `id, name, snapshot_at, units_per_point, merkle_root, total_amount, recipients, created_at`
+
+func scanAirdrop(row store.Row, airdrop *model.Airdrop) error {
+	return row.Scan(&airdrop.ID, &airdrop.Name, &airdrop.SnapshotAt, &airdrop.UnitsPerPoint, &airdrop.MerkleRoot,
+		&airdrop.TotalAmount, &airdrop.Recipients, &airdrop.CreatedAt)
+}
+
+func (r *PostgresAirdropRepository) Create(ctx context.Context, airdrop *model.Airdrop) error {
+	airdrop.ID = uuid.New().String()
+	query := `INSERT INTO airdrops (id, name, snapshot_at, units_per_point, merkle_root, total_amount, recipients)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, airdrop.ID, airdrop.Name, airdrop.SnapshotAt, airdrop.UnitsPerPoint,
+		airdrop.MerkleRoot, airdrop.TotalAmount, airdrop.Recipients)
+	if err := row.Scan(&airdrop.CreatedAt); err != nil {
+		if errors.Is(err, store.ErrUniqueViolation) {
+			return ErrAirdropExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresAirdropRepository) GetByID(ctx context.Context, id string) (*model.Airdrop, error) {
+	return r.getOne(ctx, `SELECT `+airdropColumns+` FROM airdrops WHERE id = $1`, id)
+}
+
+func (r *PostgresAirdropRepository) GetLatest(ctx context.Context) (*model.Airdrop, error) {
+	return r.getOne(ctx, `SELECT `+airdropColumns+` FROM airdrops ORDER BY snapshot_at DESC, created_at DESC LIMIT 1`)
+}
+
+func (r *PostgresAirdropRepository) List(ctx context.Context) ([]model.Airdrop, error) {
+	query := `SELECT ` + airdropColumns + ` FROM airdrops ORDER BY snapshot_at DESC, created_at DESC`
+	rows, err := querier(ctx, r.db).Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	airdrops := []model.Airdrop{}
+	for rows.Next() {
+		var airdrop model.Airdrop
+		if err := scanAirdrop(rows, &airdrop); err != nil {
+			return nil, err
+		}
+		airdrops = append(airdrops, airdrop)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return airdrops, nil
+}
+
+func (r *PostgresAirdropRepository) SnapshotBalances(ctx context.Context, at time.Time) ([]model.AirdropBalance, error) {
+	query := `SELECT t.user_id, w.address, SUM(t.delta)
+		FROM point_transactions t
+		JOIN wallets w ON w.user_id = t.user_id AND w.is_primary
+		WHERE t.created_at <= $1
+		GROUP BY t.user_id, w.address
+		HAVING SUM(t.delta) > 0
+		ORDER BY t.user_id`
+	rows, err := querier(ctx, r.db).Query(ctx, query, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	balances := []model.AirdropBalance{}
+	for rows.Next() {
+		var balance model.AirdropBalance
+		if err := rows.Scan(&balance.UserID, &balance.Address, &balance.Points); err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+func (r *PostgresAirdropRepository) AddClaim(ctx context.Context, claim *model.AirdropClaim) error {
+	proof, err := json.Marshal(claim.Proof)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO airdrop_claims (airdrop_id, user_id, address, points, amount, proof) VALUES ($1, $2, $3, $4, $5, $6)`
+	return querier(ctx, r.db).Exec(ctx, query, claim.AirdropID, claim.UserID, claim.Address, claim.Points, claim.Amount, proof)
+}
+
+func (r *PostgresAirdropRepository) GetClaim(ctx context.Context, airdropID, userID string) (*model.AirdropClaim, error) {
+	query := `SELECT c.airdrop_id, a.merkle_root, c.user_id, c.address, c.points, c.amount, c.proof
+		FROM airdrop_claims c JOIN airdrops a ON a.id = c.airdrop_id
+		WHERE c.airdrop_id = $1 AND c.user_id = $2`
+	row := querier(ctx, r.db).QueryRow(ctx, query, airdropID, userID)
+	var claim model.AirdropClaim
+	var proof []byte
+	if err := row.Scan(&claim.AirdropID, &claim.MerkleRoot, &claim.UserID, &claim.Address, &claim.Points, &claim.Amount, &proof); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAirdropClaimNotFound
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(proof, &claim.Proof); err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+func (r *PostgresAirdropRepository) getOne(ctx context.Context, query string, args ...interface{}) (*model.Airdrop, error) {
+	row := querier(ctx, r.db).QueryRow(ctx, query, args...)
+	var airdrop model.Airdrop
+	if err := scanAirdrop(row, &airdrop); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAirdropNotFound
+		}
+		return nil, err
+	}
+	return &airdrop, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"denet/internal/model"
+	"denet/internal/store"
+	"errors"
+	"time"
+)
+
+type PostgresIdempotencyRepository struct {
+	db store.Database
+}
+
+func (r *PostgresIdempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyRecord) (bool, error) {
+	// An expired row, whether a finished response or an abandoned in-flight
+	// lock, is taken over as if the key had never been used.
+	query := `INSERT INTO idempotency_keys (scope, key, fingerprint, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scope, key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, status_code = NULL,
+			content_type = NULL, response_body = NULL, created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= CURRENT_TIMESTAMP
+		RETURNING created_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, record.Scope, record.Key, record.Fingerprint, record.ExpiresAt)
+	if err := row.Scan(&record.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *PostgresIdempotencyRepository) Get(ctx context.Context, scope, key string) (*model.IdempotencyRecord, error) {
+	query := `SELECT scope, key, fingerprint, status_code, COALESCE(content_type, ''), response_body, created_at, expires_at
+		FROM idempotency_keys WHERE scope = $1 AND key = $2`
+	row := querier(ctx, r.db).QueryRow(ctx, query, scope, key)
+	var record model.IdempotencyRecord
+	if err := row.Scan(&record.Scope, &record.Key, &record.Fingerprint, &record.StatusCode, &record.ContentType,
+		&record.ResponseBody, &record.CreatedAt, &record.ExpiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIdempotencyKeyNotFound
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *PostgresIdempotencyRepository) Complete(ctx context.Context, record *model.IdempotencyRecord) error {
+	query := `UPDATE idempotency_keys SET status_code = $3, content_type = $4, response_body = $5, expires_at = $6
+		WHERE scope = $1 AND key = $2`
+	return querier(ctx, r.db).Exec(ctx, query, record.Scope, record.Key, record.StatusCode, record.ContentType,
+		record.ResponseBody, record.ExpiresAt)
+}
+
+func (r *PostgresIdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status_code IS NULL`
+	return querier(ctx, r.db).Exec(ctx, query, scope, key)
+}
+
+func (r *PostgresIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	return querier(ctx, r.db).Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"denet/internal/model"
+	"denet/internal/store"
+	"errors"
+)
+
+type PostgresLoginLockoutRepository struct {
+	db store.Database
+}
+
+const loginLockoutColumns = `user_id, failed_attempts, last_failed_at, lockouts, locked_until, updated_at`
+
+func scanLoginLockout(row store.Row, lockout *model.LoginLockout) error {
+	return row.Scan(&lockout.UserID, &lockout.FailedAttempts, &lockout.LastFailedAt, &lockout.Lockouts, &lockout.LockedUntil, &lockout.UpdatedAt)
+}
+
+func (r *PostgresLoginLockoutRepository) Get(ctx context.Context, userID string) (*model.LoginLockout, error) {
+	return r.getOne(ctx, `SELECT `+loginLockoutColumns+` FROM login_lockouts WHERE user_id = $1`, userID)
+}
+
+func (r *PostgresLoginLockoutRepository) GetForUpdate(ctx context.Context, userID string) (*model.LoginLockout, error) {
+	// Creating the row first gives concurrent first failures a row to queue
+	// on rather than racing to insert it.
+	query := `INSERT INTO login_lockouts (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`
+	if err := querier(ctx, r.db).Exec(ctx, query, userID); err != nil {
+		return nil, err
+	}
+	return r.getOne(ctx, `SELECT `+loginLockoutColumns+` FROM login_lockouts WHERE user_id = $1 FOR UPDATE`, userID)
+}
+
+func (r *PostgresLoginLockoutRepository) Update(ctx context.Context, lockout *model.LoginLockout) error {
+	query := `UPDATE login_lockouts
+		SET failed_attempts = $2, last_failed_at = $3, lockouts = $4, locked_until = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1
+		RETURNING updated_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, lockout.UserID, lockout.FailedAttempts, lockout.LastFailedAt, lockout.Lockouts, lockout.LockedUntil)
+	if err := row.Scan(&lockout.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrLoginLockoutNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresLoginLockoutRepository) Delete(ctx context.Context, userID string) error {
+	return querier(ctx, r.db).Exec(ctx, `DELETE FROM login_lockouts WHERE user_id = $1`, userID)
+}
+
+func (r *PostgresLoginLockoutRepository) getOne(ctx context.Context, query string, args ...interface{}) (*model.LoginLockout, error) {
+	row := querier(ctx, r.db).QueryRow(ctx, query, args...)
+	var lockout model.LoginLockout
+	if err := scanLoginLockout(row, &lockout); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrLoginLockoutNotFound
+		}
+		return nil, err
+	}
+	return &lockout, nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"denet/internal/model"
+	"denet/internal/store"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+type PostgresReferralRepository struct {
+	db store.Database
+}
+
+const referralRuleColumns = `id, event, level, milestone_points, points, percent, active, created_at`
+
+func scanReferralRule(row store.Row, rule *model.ReferralRule) error {
+	return row.Scan(&rule.ID, &rule.Event, &rule.Level, &rule.MilestonePoints, &rule.Points, &rule.Percent, &rule.Active, &rule.CreatedAt)
+}
+
+func (r *PostgresReferralRepository) ListRules(ctx context.Context, activeOnly bool) ([]model.ReferralRule, error) {
+	query := `SELECT ` + referralRuleColumns + ` FROM referral_reward_rules`
+	if activeOnly {
+		query += ` WHERE active`
+	}
+	query += ` ORDER BY event, level, milestone_points, created_at`
+	rows, err := querier(ctx, r.db).Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rules := []model.ReferralRule{}
+	for rows.Next() {
+		var rule model.ReferralRule
+		if err := scanReferralRule(rows, &rule); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *PostgresReferralRepository) GetRule(ctx context.Context, id string) (*model.ReferralRule, error) {
+	query := `SELECT ` + referralRuleColumns + ` FROM referral_reward_rules WHERE id = $1`
+	row := querier(ctx, r.db).QueryRow(ctx, query, id)
+	var rule model.ReferralRule
+	if err := scanReferralRule(row, &rule); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReferralRuleNotFound
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *PostgresReferralRepository) CreateRule(ctx context.Context, rule *model.ReferralRule) error {
+	rule.ID = uuid.New().String()
+	query := `INSERT INTO referral_reward_rules (id, event, level, milestone_points, points, percent, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, rule.ID, rule.Event, rule.Level, rule.MilestonePoints, rule.Points, rule.Percent, rule.Active)
+	return row.Scan(&rule.CreatedAt)
+}
+
+func (r *PostgresReferralRepository) UpdateRule(ctx context.Context, rule *model.ReferralRule) error {
+	query := `UPDATE referral_reward_rules SET points = $1, percent = $2, active = $3 WHERE id = $4 RETURNING id`
+	row := querier(ctx, r.db).QueryRow(ctx, query, rule.Points, rule.Percent, rule.Active, rule.ID)
+	var id string
+	if err := row.Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrReferralRuleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresReferralRepository) ListReferees(ctx context.Context, userID, cursor string, limit int) (*model.RefereePage, error) {
+	query := `SELECT id, username, balance, created_at FROM users WHERE referrer_id = $1`
+	args := []interface{}{userID}
+	if cursor != "" {
+		createdAt, id, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND (created_at, id) < ($2, $3)`
+		args = append(args, createdAt, id)
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ` + strconv.Itoa(limit+1)
+
+	rows, err := querier(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	page := &model.RefereePage{Referees: []model.Referee{}}
+	for rows.Next() {
+		var referee model.Referee
+		if err := rows.Scan(&referee.ID, &referee.Username, &referee.Balance, &referee.JoinedAt); err != nil {
+			return nil, err
+		}
+		page.Referees = append(page.Referees, referee)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Referees) > limit {
+		page.Referees = page.Referees[:limit]
+		last := page.Referees[limit-1]
+		page.NextCursor = encodeCursor(last.JoinedAt, last.ID)
+	}
+	return page, nil
+}
+
+func (r *PostgresReferralRepository) GetTree(ctx context.Context, userID string, depth, limit int) ([]*model.ReferralTreeNode, error) {
+	query := `WITH RECURSIVE tree AS (
+		SELECT id, username, balance, referrer_id, created_at, 1 AS depth FROM users WHERE referrer_id = $1
+		UNION ALL
+		SELECT u.id, u.username, u.balance, u.referrer_id, u.created_at, t.depth + 1
+		FROM users u JOIN tree t ON u.referrer_id = t.id
+		WHERE t.depth < $2
+	)
+	SELECT id, username, balance, referrer_id, created_at, depth FROM tree
+	ORDER BY depth, created_at, id LIMIT $3`
+	rows, err := querier(ctx, r.db).Query(ctx, query, userID, depth, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var nodes []*model.ReferralTreeNode
+	for rows.Next() {
+		node := &model.ReferralTreeNode{Children: []*model.ReferralTreeNode{}}
+		if err := rows.Scan(&node.ID, &node.Username, &node.Balance, &node.ReferrerID, &node.JoinedAt, &node.Depth); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+func (r *PostgresReferralRepository) GetStats(ctx context.Context, userID string) (*model.ReferralStats, error) {
+	query := `SELECT
+		(SELECT COUNT(*) FROM users WHERE referrer_id = $1),
+		(SELECT COUNT(DISTINCT ut.user_id) FROM user_tasks ut JOIN users u ON u.id = ut.user_id
+			WHERE u.referrer_id = $1 AND ut.status = $3),
+		(SELECT COALESCE(SUM(delta), 0) FROM point_transactions WHERE user_id = $1 AND source_type = $2)`
+	row := querier(ctx, r.db).QueryRow(ctx, query, userID, model.PointSourceReferral, model.TaskSubmissionApproved)
+	var stats model.ReferralStats
+	if err := row.Scan(&stats.DirectInvites, &stats.ActiveReferees, &stats.PointsFromReferrals); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"denet/internal/model"
+	"denet/internal/store"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+type PostgresRewardRepository struct {
+	db store.Database
+}
+
+const rewardColumns = `id, name, description, cost, stock, per_user_limit, available_from, available_until,
+	archived_at, created_at, updated_at`
+
+func scanReward(row store.Row, reward *model.Reward) error {
+	return row.Scan(&reward.ID, &reward.Name, &reward.Description, &reward.Cost, &reward.Stock, &reward.PerUserLimit,
+		&reward.AvailableFrom, &reward.AvailableUntil, &reward.ArchivedAt, &reward.CreatedAt, &reward.UpdatedAt)
+}
+
+func (r *PostgresRewardRepository) GetByID(ctx context.Context, id string) (*model.Reward, error) {
+	return r.getOne(ctx, `SELECT `+rewardColumns+` FROM rewards WHERE id = $1`, id)
+}
+
+func (r *PostgresRewardRepository) GetByIDForUpdate(ctx context.Context, id string) (*model.Reward, error) {
+	return r.getOne(ctx, `SELECT `+rewardColumns+` FROM rewards WHERE id = $1 FOR UPDATE`, id)
+}
+
+func (r *PostgresRewardRepository) List(ctx context.Context, filter model.RewardFilter) ([]model.Reward, error) {
+	query := `SELECT ` + rewardColumns + ` FROM rewards WHERE TRUE`
+	args := []interface{}{}
+	if !filter.IncludeArchived {
+		query += ` AND archived_at IS NULL`
+	}
+	if filter.AvailableAt != nil {
+		args = append(args, *filter.AvailableAt)
+		query += ` AND (available_from IS NULL OR available_from <= $1)
+			AND (available_until IS NULL OR available_until > $1)`
+	}
+	query += ` ORDER BY created_at, id`
+	rows, err := querier(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rewards := []model.Reward{}
+	for rows.Next() {
+		var reward model.Reward
+		if err := scanReward(rows, &reward); err != nil {
+			return nil, err
+		}
+		rewards = append(rewards, reward)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rewards, nil
+}
+
+func (r *PostgresRewardRepository) Create(ctx context.Context, reward *model.Reward) error {
+	reward.ID = uuid.New().String()
+	query := `INSERT INTO rewards (id, name, description, cost, stock, per_user_limit, available_from, available_until)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, reward.ID, reward.Name, reward.Description, reward.Cost, reward.Stock,
+		reward.PerUserLimit, reward.AvailableFrom, reward.AvailableUntil)
+	return row.Scan(&reward.CreatedAt, &reward.UpdatedAt)
+}
+
+func (r *PostgresRewardRepository) Update(ctx context.Context, reward *model.Reward) error {
+	query := `UPDATE rewards SET name = $1, description = $2, cost = $3, stock = $4, per_user_limit = $5,
+		available_from = $6, available_until = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8 RETURNING updated_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, reward.Name, reward.Description, reward.Cost, reward.Stock,
+		reward.PerUserLimit, reward.AvailableFrom, reward.AvailableUntil, reward.ID)
+	if err := row.Scan(&reward.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRewardNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresRewardRepository) Archive(ctx context.Context, id string) (*model.Reward, error) {
+	query := `UPDATE rewards SET archived_at = COALESCE(archived_at, CURRENT_TIMESTAMP), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 RETURNING ` + rewardColumns
+	return r.getOne(ctx, query, id)
+}
+
+func (r *PostgresRewardRepository) AdjustStock(ctx context.Context, id string, delta int) error {
+	query := `UPDATE rewards SET stock = stock + $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND stock IS NOT NULL`
+	return querier(ctx, r.db).Exec(ctx, query, delta, id)
+}
+
+func (r *PostgresRewardRepository) CreateOrder(ctx context.Context, order *model.RewardOrder) error {
+	order.ID = uuid.New().String()
+	query := `INSERT INTO reward_orders (id, user_id, reward_id, cost, status) VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, updated_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, order.ID, order.UserID, order.RewardID, order.Cost, order.Status)
+	return row.Scan(&order.CreatedAt, &order.UpdatedAt)
+}
+
+const rewardOrderColumns = `id, user_id, reward_id, cost, status, note, handled_by, handled_at, created_at, updated_at`
+
+func scanRewardOrder(row store.Row, order *model.RewardOrder) error {
+	return row.Scan(&order.ID, &order.UserID, &order.RewardID, &order.Cost, &order.Status, &order.Note,
+		&order.HandledBy, &order.HandledAt, &order.CreatedAt, &order.UpdatedAt)
+}
+
+func (r *PostgresRewardRepository) GetOrderForUpdate(ctx context.Context, id string) (*model.RewardOrder, error) {
+	query := `SELECT ` + rewardOrderColumns + ` FROM reward_orders WHERE id = $1 FOR UPDATE`
+	row := querier(ctx, r.db).QueryRow(ctx, query, id)
+	var order model.RewardOrder
+	if err := scanRewardOrder(row, &order); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRewardOrderNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *PostgresRewardRepository) UpdateOrder(ctx context.Context, order *model.RewardOrder) error {
+	query := `UPDATE reward_orders SET status = $1, note = $2, handled_by = $3, handled_at = $4,
+		updated_at = CURRENT_TIMESTAMP WHERE id = $5 RETURNING updated_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, order.Status, order.Note, order.HandledBy, order.HandledAt, order.ID)
+	if err := row.Scan(&order.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRewardOrderNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresRewardRepository) CountActiveOrders(ctx context.Context, userID, rewardID string) (int, error) {
+	query := `SELECT COUNT(*) FROM reward_orders WHERE user_id = $1 AND reward_id = $2 AND status <> 'cancelled'`
+	row := querier(ctx, r.db).QueryRow(ctx, query, userID, rewardID)
+	var count int
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *PostgresRewardRepository) ListOrdersByUser(ctx context.Context, userID, cursor string, limit int) (*model.RewardOrderPage, error) {
+	query := `SELECT ` + rewardOrderColumns + ` FROM reward_orders WHERE user_id = $1`
+	args := []interface{}{userID}
+	if cursor != "" {
+		createdAt, id, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND (created_at, id) < ($2, $3)`
+		args = append(args, createdAt, id)
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ` + strconv.Itoa(limit+1)
+	return r.orderPage(ctx, query, args, limit)
+}
+
+func (r *PostgresRewardRepository) ListOrdersByStatus(ctx context.Context, status model.RewardOrderStatus, cursor string, limit int) (*model.RewardOrderPage, error) {
+	query := `SELECT ` + rewardOrderColumns + ` FROM reward_orders WHERE status = $1`
+	args := []interface{}{status}
+	if cursor != "" {
+		createdAt, id, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND (created_at, id) > ($2, $3)`
+		args = append(args, createdAt, id)
+	}
+	query += ` ORDER BY created_at, id LIMIT ` + strconv.Itoa(limit+1)
+	return r.orderPage(ctx, query, args, limit)
+}
+
+func (r *PostgresRewardRepository) orderPage(ctx context.Context, query string, args []interface{}, limit int) (*model.RewardOrderPage, error) {
+	rows, err := querier(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	page := &model.RewardOrderPage{Orders: []model.RewardOrder{}}
+	for rows.Next() {
+		var order model.RewardOrder
+		if err := scanRewardOrder(rows, &order); err != nil {
+			return nil, err
+		}
+		page.Orders = append(page.Orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Orders) > limit {
+		page.Orders = page.Orders[:limit]
+		last := page.Orders[limit-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+func (r *PostgresRewardRepository) getOne(ctx context.Context, query string, args ...interface{}) (*model.Reward, error) {
+	row := querier(ctx, r.db).QueryRow(ctx, query, args...)
+	var reward model.Reward
+	if err := scanReward(row, &reward); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRewardNotFound
+		}
+		return nil, err
+	}
+	return &reward, nil
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"denet/internal/model"
+	"denet/internal/store"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+type PostgresRoleRepository struct {
+	db store.Database
+}
+
+func (r *PostgresRoleRepository) List(ctx context.Context) ([]model.Role, error) {
+	query := `SELECT r.name, COALESCE(r.description, ''), r.is_system, r.created_at, rp.permission
+		FROM roles r LEFT JOIN role_permissions rp ON rp.role_name = r.name
+		ORDER BY r.created_at, r.name, rp.permission`
+	rows, err := querier(ctx, r.db).Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	roles := []model.Role{}
+	for rows.Next() {
+		var role model.Role
+		var permission sql.NullString
+		if err := rows.Scan(&role.Name, &role.Description, &role.IsSystem, &role.CreatedAt, &permission); err != nil {
+			return nil, err
+		}
+		if n := len(roles); n == 0 || roles[n-1].Name != role.Name {
+			role.Permissions = []string{}
+			roles = append(roles, role)
+		}
+		if permission.Valid {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, permission.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *PostgresRoleRepository) GetByName(ctx context.Context, name string) (*model.Role, error) {
+	query := `SELECT name, COALESCE(description, ''), is_system, created_at FROM roles WHERE name = $1`
+	row := querier(ctx, r.db).QueryRow(ctx, query, name)
+	var role model.Role
+	if err := row.Scan(&role.Name, &role.Description, &role.IsSystem, &role.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	permissions, err := r.scanStrings(ctx, `SELECT permission FROM role_permissions WHERE role_name = $1 ORDER BY permission`, name)
+	if err != nil {
+		return nil, err
+	}
+	role.Permissions = permissions
+	return &role, nil
+}
+
+func (r *PostgresRoleRepository) Create(ctx context.Context, role *model.Role) error {
+	query := `INSERT INTO roles (name, description) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING RETURNING created_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, role.Name, role.Description)
+	if err := row.Scan(&role.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRoleExists
+		}
+		return err
+	}
+	for _, permission := range role.Permissions {
+		query := `INSERT INTO role_permissions (role_name, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		if err := querier(ctx, r.db).Exec(ctx, query, role.Name, permission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresRoleRepository) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	return r.scanStrings(ctx, `SELECT role_name FROM user_roles WHERE user_id = $1 ORDER BY role_name`, userID)
+}
+
+func (r *PostgresRoleRepository) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
+	query := `SELECT DISTINCT rp.permission FROM user_roles ur
+		JOIN role_permissions rp ON rp.role_name = ur.role_name
+		WHERE ur.user_id = $1 ORDER BY rp.permission`
+	return r.scanStrings(ctx, query, userID)
+}
+
+func (r *PostgresRoleRepository) Grant(ctx context.Context, userID, roleName string, grantedBy *string) error {
+	query := `INSERT INTO user_roles (user_id, role_name, granted_by) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, role_name) DO NOTHING RETURNING user_id`
+	row := querier(ctx, r.db).QueryRow(ctx, query, userID, roleName, grantedBy)
+	var id string
+	if err := row.Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRoleAlreadyGranted
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresRoleRepository) Revoke(ctx context.Context, userID, roleName string) error {
+	query := `DELETE FROM user_roles WHERE user_id = $1 AND role_name = $2 RETURNING user_id`
+	row := querier(ctx, r.db).QueryRow(ctx, query, userID, roleName)
+	var id string
+	if err := row.Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRoleNotGranted
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresRoleRepository) AddAuditEntry(ctx context.Context, entry *model.RoleAuditEntry) error {
+	entry.ID = uuid.New().String()
+	query := `INSERT INTO role_audit_log (id, user_id, role_name, action, actor_id, reason) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, entry.ID, entry.UserID, entry.RoleName, entry.Action, entry.ActorID, entry.Reason)
+	return row.Scan(&entry.CreatedAt)
+}
+
+func (r *PostgresRoleRepository) ListAuditEntries(ctx context.Context, userID string) ([]model.RoleAuditEntry, error) {
+	query := `SELECT id, user_id, role_name, action, actor_id, COALESCE(reason, ''), created_at
+		FROM role_audit_log WHERE user_id = $1 ORDER BY created_at DESC, id DESC`
+	rows, err := querier(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []model.RoleAuditEntry{}
+	for rows.Next() {
+		var entry model.RoleAuditEntry
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.RoleName, &entry.Action, &entry.ActorID, &entry.Reason, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *PostgresRoleRepository) scanStrings(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := querier(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"denet/internal/model"
+	"denet/internal/store"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PostgresSeasonRepository struct {
+	db store.Database
+}
+
+const seasonColumns = `id, name, starts_at, ends_at, archived_at, created_at`
+
+func scanSeason(row store.Row, season *model.Season) error {
+	return row.Scan(&season.ID, &season.Name, &season.StartsAt, &season.EndsAt, &season.ArchivedAt, &season.CreatedAt)
+}
+
+func (r *PostgresSeasonRepository) Create(ctx context.Context, season *model.Season) error {
+	season.ID = uuid.New().String()
+	query := `INSERT INTO seasons (id, name, starts_at, ends_at) VALUES ($1, $2, $3, $4) RETURNING created_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, season.ID, season.Name, season.StartsAt, season.EndsAt)
+	return row.Scan(&season.CreatedAt)
+}
+
+func (r *PostgresSeasonRepository) GetByID(ctx context.Context, id string) (*model.Season, error) {
+	query := `SELECT ` + seasonColumns + ` FROM seasons WHERE id = $1`
+	return r.getOne(ctx, query, id)
+}
+
+func (r *PostgresSeasonRepository) GetCurrent(ctx context.Context, now time.Time) (*model.Season, error) {
+	query := `SELECT ` + seasonColumns + ` FROM seasons WHERE starts_at <= $1 AND ends_at > $1
+		ORDER BY starts_at DESC LIMIT 1`
+	return r.getOne(ctx, query, now)
+}
+
+func (r *PostgresSeasonRepository) List(ctx context.Context) ([]model.Season, error) {
+	return r.list(ctx, `SELECT `+seasonColumns+` FROM seasons ORDER BY starts_at DESC`)
+}
+
+func (r *PostgresSeasonRepository) LockEnded(ctx context.Context, now time.Time) ([]model.Season, error) {
+	query := `SELECT ` + seasonColumns + ` FROM seasons WHERE ends_at <= $1 AND archived_at IS NULL
+		ORDER BY ends_at FOR UPDATE SKIP LOCKED`
+	return r.list(ctx, query, now)
+}
+
+func (r *PostgresSeasonRepository) Archive(ctx context.Context, season *model.Season) error {
+	query := `INSERT INTO season_results (season_id, user_id, username, points, rank)
+		SELECT $3, ranked.user_id, ranked.username, ranked.points, ranked.rank
+		FROM (` + windowLeaderboardQuery + `) ranked`
+	if err := querier(ctx, r.db).Exec(ctx, query, season.StartsAt, season.EndsAt, season.ID); err != nil {
+		return err
+	}
+	row := querier(ctx, r.db).QueryRow(ctx, `UPDATE seasons SET archived_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING archived_at`, season.ID)
+	return row.Scan(&season.ArchivedAt)
+}
+
+func (r *PostgresSeasonRepository) GetResults(ctx context.Context, seasonID string, limit int) ([]model.WindowLeaderboardEntry, error) {
+	query := `SELECT user_id, username, points, rank FROM season_results WHERE season_id = $1
+		ORDER BY rank, user_id LIMIT $2`
+	rows, err := querier(ctx, r.db).Query(ctx, query, seasonID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []model.WindowLeaderboardEntry{}
+	for rows.Next() {
+		var entry model.WindowLeaderboardEntry
+		if err := rows.Scan(&entry.UserID, &entry.Username, &entry.Points, &entry.Rank); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *PostgresSeasonRepository) getOne(ctx context.Context, query string, args ...interface{}) (*model.Season, error) {
+	row := querier(ctx, r.db).QueryRow(ctx, query, args...)
+	var season model.Season
+	if err := scanSeason(row, &season); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSeasonNotFound
+		}
+		return nil, err
+	}
+	return &season, nil
+}
+
+func (r *PostgresSeasonRepository) list(ctx context.Context, query string, args ...interface{}) ([]model.Season, error) {
+	rows, err := querier(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	seasons := []model.Season{}
+	for rows.Next() {
+		var season model.Season
+		if err := scanSeason(rows, &season); err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, season)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return seasons, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"denet/internal/model"
+	"denet/internal/store"
+	"errors"
+)
+
+type PostgresSocialAccountRepository struct {
+	db store.Database
+}
+
+const socialAccountColumns = `user_id, provider, external_id, COALESCE(handle, ''), verified, linked_at`
+
+func scanSocialAccount(row store.Row, account *model.SocialAccount) error {
+	return row.Scan(&account.UserID, &account.Provider, &account.ExternalID, &account.Handle, &account.Verified, &account.LinkedAt)
+}
+
+func (r *PostgresSocialAccountRepository) Link(ctx context.Context, account *model.SocialAccount) error {
+	query := `INSERT INTO social_accounts (user_id, provider, external_id, handle, verified) VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		ON CONFLICT (user_id, provider) DO UPDATE
+		SET external_id = EXCLUDED.external_id, handle = EXCLUDED.handle, verified = EXCLUDED.verified, linked_at = CURRENT_TIMESTAMP
+		RETURNING linked_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, account.UserID, account.Provider, account.ExternalID, account.Handle, account.Verified)
+	if err := row.Scan(&account.LinkedAt); err != nil {
+		if errors.Is(err, store.ErrUniqueViolation) {
+			return ErrSocialAccountTaken
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresSocialAccountRepository) Get(ctx context.Context, userID string, provider model.SocialProvider) (*model.SocialAccount, error) {
+	query := `SELECT ` + socialAccountColumns + ` FROM social_accounts WHERE user_id = $1 AND provider = $2`
+	row := querier(ctx, r.db).QueryRow(ctx, query, userID, provider)
+	var account model.SocialAccount
+	if err := scanSocialAccount(row, &account); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSocialAccountNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *PostgresSocialAccountRepository) GetByExternalID(ctx context.Context, provider model.SocialProvider, externalID string) (*model.SocialAccount, error) {
+	query := `SELECT ` + socialAccountColumns + ` FROM social_accounts WHERE provider = $1 AND external_id = $2`
+	row := querier(ctx, r.db).QueryRow(ctx, query, provider, externalID)
+	var account model.SocialAccount
+	if err := scanSocialAccount(row, &account); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSocialAccountNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *PostgresSocialAccountRepository) ListByUser(ctx context.Context, userID string) ([]model.SocialAccount, error) {
+	query := `SELECT ` + socialAccountColumns + ` FROM social_accounts WHERE user_id = $1 ORDER BY provider`
+	rows, err := querier(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	accounts := []model.SocialAccount{}
+	for rows.Next() {
+		var account model.SocialAccount
+		if err := scanSocialAccount(rows, &account); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func (r *PostgresSocialAccountRepository) Unlink(ctx context.Context, userID string, provider model.SocialProvider) error {
+	query := `DELETE FROM social_accounts WHERE user_id = $1 AND provider = $2 RETURNING user_id`
+	row := querier(ctx, r.db).QueryRow(ctx, query, userID, provider)
+	var deleted string
+	if err := row.Scan(&deleted); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSocialAccountNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"denet/internal/model"
+	"denet/internal/store"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PostgresTokenRepository struct {
+	db store.Database
+}
+
+func (r *PostgresTokenRepository) CreateSession(ctx context.Context, session *model.Session) error {
+	session.ID = uuid.New().String()
+	query := `INSERT INTO auth_sessions (id, user_id) VALUES ($1, $2) RETURNING created_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, session.ID, session.UserID)
+	return row.Scan(&session.CreatedAt)
+}
+
+func (r *PostgresTokenRepository) RevokeSession(ctx context.Context, sessionID, reason string) error {
+	query := `UPDATE auth_sessions SET revoked_at = CURRENT_TIMESTAMP, revoke_reason = $1
+		WHERE id = $2 AND revoked_at IS NULL`
+	return querier(ctx, r.db).Exec(ctx, query, reason, sessionID)
+}
+
+func (r *PostgresTokenRepository) RevokeUserSessions(ctx context.Context, userID, reason string) error {
+	query := `UPDATE auth_sessions SET revoked_at = CURRENT_TIMESTAMP, revoke_reason = $1
+		WHERE user_id = $2 AND revoked_at IS NULL`
+	return querier(ctx, r.db).Exec(ctx, query, reason, userID)
+}
+
+func (r *PostgresTokenRepository) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	token.ID = uuid.New().String()
+	query := `INSERT INTO refresh_tokens (id, session_id, user_id, token_hash, expires_at) VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, token.ID, token.SessionID, token.UserID, token.TokenHash, token.ExpiresAt)
+	return row.Scan(&token.CreatedAt)
+}
+
+func (r *PostgresTokenRepository) GetRefreshTokenForUpdate(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	query := `SELECT rt.id, rt.session_id, rt.user_id, rt.token_hash, rt.expires_at, rt.used_at, rt.created_at,
+		s.revoked_at IS NOT NULL
+		FROM refresh_tokens rt JOIN auth_sessions s ON s.id = rt.session_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt, s`
+	row := querier(ctx, r.db).QueryRow(ctx, query, tokenHash)
+	var token model.RefreshToken
+	err := row.Scan(&token.ID, &token.SessionID, &token.UserID, &token.TokenHash, &token.ExpiresAt,
+		&token.UsedAt, &token.CreatedAt, &token.SessionRevoked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *PostgresTokenRepository) MarkRefreshTokenUsed(ctx context.Context, id string) error {
+	query := `UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1`
+	return querier(ctx, r.db).Exec(ctx, query, id)
+}
+
+func (r *PostgresTokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	// Entries are only needed until the token would have expired anyway.
+	if err := querier(ctx, r.db).Exec(ctx, `DELETE FROM revoked_access_tokens WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		return err
+	}
+	query := `INSERT INTO revoked_access_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`
+	return querier(ctx, r.db).Exec(ctx, query, jti, expiresAt)
+}
+
+func (r *PostgresTokenRepository) IsRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = $1)
+		OR EXISTS (SELECT 1 FROM auth_sessions WHERE id = $2 AND revoked_at IS NOT NULL)`
+	row := querier(ctx, r.db).QueryRow(ctx, query, jti, sessionID)
+	var revoked bool
+	if err := row.Scan(&revoked); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"denet/internal/model"
+	"denet/internal/store"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PostgresWalletRepository struct {
+	db store.Database
+}
+
+const walletColumns = `id, user_id, address, chain_id, is_primary, verified_at, created_at`
+
+func scanWallet(row store.Row, wallet *model.Wallet) error {
+	return row.Scan(&wallet.ID, &wallet.UserID, &wallet.Address, &wallet.ChainID, &wallet.IsPrimary, &wallet.VerifiedAt, &wallet.CreatedAt)
+}
+
+func (r *PostgresWalletRepository) CreateNonce(ctx context.Context, nonce *model.WalletNonce) error {
+	query := `INSERT INTO wallet_nonces (nonce, expires_at) VALUES ($1, $2)`
+	return querier(ctx, r.db).Exec(ctx, query, nonce.Nonce, nonce.ExpiresAt)
+}
+
+func (r *PostgresWalletRepository) ConsumeNonce(ctx context.Context, nonce string, now time.Time) error {
+	query := `DELETE FROM wallet_nonces WHERE nonce = $1 RETURNING expires_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, nonce)
+	var expiresAt time.Time
+	if err := row.Scan(&expiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWalletNonceNotFound
+		}
+		return err
+	}
+	if !now.Before(expiresAt) {
+		return ErrWalletNonceNotFound
+	}
+	return nil
+}
+
+func (r *PostgresWalletRepository) DeleteExpiredNonces(ctx context.Context, now time.Time) error {
+	return querier(ctx, r.db).Exec(ctx, `DELETE FROM wallet_nonces WHERE expires_at <= $1`, now)
+}
+
+func (r *PostgresWalletRepository) Create(ctx context.Context, wallet *model.Wallet) error {
+	wallet.ID = uuid.New().String()
+	query := `INSERT INTO wallets (id, user_id, address, chain_id, is_primary, verified_at) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at`
+	row := querier(ctx, r.db).QueryRow(ctx, query, wallet.ID, wallet.UserID, wallet.Address, wallet.ChainID, wallet.IsPrimary, wallet.VerifiedAt)
+	if err := row.Scan(&wallet.CreatedAt); err != nil {
+		if errors.Is(err, store.ErrUniqueViolation) {
+			return ErrWalletTaken
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresWalletRepository) GetByAddress(ctx context.Context, address string) (*model.Wallet, error) {
+	query := `SELECT ` + walletColumns + ` FROM wallets WHERE address = $1`
+	row := querier(ctx, r.db).QueryRow(ctx, query, address)
+	var wallet model.Wallet
+	if err := scanWallet(row, &wallet); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (r *PostgresWalletRepository) ListByUser(ctx context.Context, userID string) ([]model.Wallet, error) {
+	query := `SELECT ` + walletColumns + ` FROM wallets WHERE user_id = $1 ORDER BY created_at, id`
+	rows, err := querier(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	wallets := []model.Wallet{}
+	for rows.Next() {
+		var wallet model.Wallet
+		if err := scanWallet(rows, &wallet); err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, wallet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+func (r *PostgresWalletRepository) SetPrimary(ctx context.Context, userID, address string) error {
+	// The partial unique index allows one primary per user at any moment, so
+	// the old one is cleared before the new one is set.
+	if err := querier(ctx, r.db).Exec(ctx, `UPDATE wallets SET is_primary = FALSE WHERE user_id = $1 AND is_primary`, userID); err != nil {
+		return err
+	}
+	row := querier(ctx, r.db).QueryRow(ctx, `UPDATE wallets SET is_primary = TRUE WHERE user_id = $1 AND address = $2 RETURNING id`, userID, address)
+	var id string
+	if err := row.Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWalletNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresWalletRepository) Delete(ctx context.Context, userID, address string) (*model.Wallet, error) {
+	query := `DELETE FROM wallets WHERE user_id = $1 AND address = $2 RETURNING ` + walletColumns
+	row := querier(ctx, r.db).QueryRow(ctx, query, userID, address)
+	var wallet model.Wallet
+	if err := scanWallet(row, &wallet); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"denet/internal/store"
+	"denet/internal/tracing"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type txKey struct{}
+
+// txState is carried on the context for the lifetime of the outermost
+// transaction. Nested WithTransaction calls share it and open savepoints.
+type txState struct {
+	tx          store.Transaction
+	savepoints  int
+	afterCommit []func(context.Context)
+}
+
+// TxConfig controls how PostgresTransactionRepository opens and retries
+// transactions.
+type TxConfig struct {
+	Isolation  store.IsolationLevel
+	MaxRetries int
+	RetryDelay time.Duration
+}
+
+// querier returns the transaction bound to ctx, or db when the call is not
+// running inside WithTransaction. Every repository must go through it.
+func querier(ctx context.Context, db store.Database) store.Querier {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.tx
+	}
+	return db
+}
+
+// AfterCommit defers fn until the transaction bound to ctx has committed, or
+// runs it right away when ctx carries no transaction. Hooks registered inside
+// a savepoint that is rolled back, or in an attempt that is retried, are
+// dropped.
+func AfterCommit(ctx context.Context, fn func(context.Context)) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn(ctx)
+}
+
+type PostgresTransactionRepository struct {
+	db     store.Database
+	config TxConfig
+}
+
+func (r *PostgresTransactionRepository) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	return r.WithTransactionOptions(ctx, store.TxOptions{Isolation: r.config.Isolation}, fn)
+}
+
+func (r *PostgresTransactionRepository) WithTransactionOptions(ctx context.Context, opts store.TxOptions, fn func(context.Context) error) (err error) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return r.withSavepoint(ctx, state, fn)
+	}
+
+	ctx, span := tracing.Start(ctx, "transaction")
+	defer func() { tracing.End(span, err) }()
+
+	for attempt := 0; ; attempt++ {
+		err = r.run(ctx, opts, fn)
+		if !errors.Is(err, store.ErrSerializationFailure) || attempt >= r.config.MaxRetries {
+			return err
+		}
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt+1),
+			attribute.String("cause", err.Error()),
+		))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(r.config.RetryDelay * time.Duration(attempt+1)):
+		}
+	}
+}
+
+func (r *PostgresTransactionRepository) run(ctx context.Context, opts store.TxOptions, fn func(context.Context) error) (err error) {
+	tx, err := r.db.BeginTx(ctx, &opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	state := &txState{tx: tx}
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, hook := range state.afterCommit {
+		hook(ctx)
+	}
+	return nil
+}
+
+func (r *PostgresTransactionRepository) withSavepoint(ctx context.Context, state *txState, fn func(context.Context) error) (err error) {
+	ctx, span := tracing.Start(ctx, "savepoint")
+	defer func() { tracing.End(span, err) }()
+
+	state.savepoints++
+	name := fmt.Sprintf("sp_%d", state.savepoints)
+	hooks := len(state.afterCommit)
+	if err := state.tx.Exec(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			state.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			state.afterCommit = state.afterCommit[:hooks]
+			panic(p)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		state.afterCommit = state.afterCommit[:hooks]
+		if rbErr := state.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint: %v)", err, rbErr)
+		}
+		return err
+	}
+	return state.tx.Exec(ctx, "RELEASE SAVEPOINT "+name)
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"denet/internal/store"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// fakeDatabase logs the transaction control statements it is sent.
+type fakeDatabase struct {
+	store.Database
+	log []string
+}
+
+func (d *fakeDatabase) BeginTx(ctx context.Context, opts *store.TxOptions) (store.Transaction, error) {
+	d.log = append(d.log, "BEGIN")
+	return &fakeTransaction{db: d}, nil
+}
+
+type fakeTransaction struct {
+	store.Transaction
+	db *fakeDatabase
+}
+
+func (t *fakeTransaction) Exec(ctx context.Context, query string, args ...interface{}) error {
+	t.db.log = append(t.db.log, query)
+	return nil
+}
+
+func (t *fakeTransaction) Commit() error {
+	t.db.log = append(t.db.log, "COMMIT")
+	return nil
+}
+
+func (t *fakeTransaction) Rollback() error {
+	t.db.log = append(t.db.log, "ROLLBACK")
+	return nil
+}
+
+var errBoom = errors.New("boom")
+
+func newTestTransactions(maxRetries int) (*PostgresTransactionRepository, *fakeDatabase) {
+	db := &fakeDatabase{}
+	return &PostgresTransactionRepository{db: db, config: TxConfig{MaxRetries: maxRetries}}, db
+}
+
+func TestWithTransactionSavepoints(t *testing.T) {
+	txs, db := newTestTransactions(0)
+	var ran []string
+
+	err := txs.WithTransaction(context.Background(), func(ctx context.Context) error {
+		AfterCommit(ctx, func(context.Context) { ran = append(ran, "outer") })
+		if err := txs.WithTransaction(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { ran = append(ran, "released") })
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := txs.WithTransaction(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { ran = append(ran, "rolled back") })
+			return errBoom
+		}); !errors.Is(err, errBoom) {
+			t.Errorf("failed savepoint returned %v, want %v", err, errBoom)
+		}
+		if len(ran) != 0 {
+			t.Errorf("hooks ran before commit: %v", ran)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction() = %v", err)
+	}
+
+	wantLog := []string{
+		"BEGIN",
+		"SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_1",
+		"SAVEPOINT sp_2", "ROLLBACK TO SAVEPOINT sp_2",
+		"COMMIT",
+	}
+	if !reflect.DeepEqual(db.log, wantLog) {
+		t.Errorf("statements = %v, want %v", db.log, wantLog)
+	}
+	if want := []string{"outer", "released"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("hooks ran = %v, want %v", ran, want)
+	}
+}
+
+func TestWithTransactionRollsBackOnPanic(t *testing.T) {
+	for _, nested := range []bool{false, true} {
+		t.Run(fmt.Sprintf("nested=%v", nested), func(t *testing.T) {
+			txs, db := newTestTransactions(0)
+			ran := false
+			fn := func(ctx context.Context) error {
+				AfterCommit(ctx, func(context.Context) { ran = true })
+				panic("boom")
+			}
+
+			func() {
+				defer func() {
+					if p := recover(); p != "boom" {
+						t.Errorf("recovered %v, want the original panic", p)
+					}
+				}()
+				txs.WithTransaction(context.Background(), func(ctx context.Context) error {
+					if nested {
+						return txs.WithTransaction(ctx, fn)
+					}
+					return fn(ctx)
+				})
+			}()
+
+			wantLog := []string{"BEGIN", "ROLLBACK"}
+			if nested {
+				wantLog = []string{"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "ROLLBACK"}
+			}
+			if !reflect.DeepEqual(db.log, wantLog) {
+				t.Errorf("statements = %v, want %v", db.log, wantLog)
+			}
+			if ran {
+				t.Error("hook of a panicking transaction ran")
+			}
+		})
+	}
+}
+
+func TestWithTransactionRetriesSerializationFailures(t *testing.T) {
+	conflict := fmt.Errorf("%w: could not serialize access", store.ErrSerializationFailure)
+	tests := []struct {
+		name       string
+		failures   int
+		maxRetries int
+		wantErr    error
+		wantCalls  int
+	}{
+		{"succeeds first time", 0, 2, nil, 1},
+		{"succeeds on retry", 2, 2, nil, 3},
+		{"gives up", 5, 2, store.ErrSerializationFailure, 3},
+		{"other errors are not retried", -1, 2, errBoom, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txs, _ := newTestTransactions(tt.maxRetries)
+			calls := 0
+			var ran []int
+
+			err := txs.WithTransaction(context.Background(), func(ctx context.Context) error {
+				calls++
+				attempt := calls
+				AfterCommit(ctx, func(context.Context) { ran = append(ran, attempt) })
+				switch {
+				case tt.failures < 0:
+					return errBoom
+				case calls <= tt.failures:
+					return conflict
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("WithTransaction() = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("fn called %d times, want %d", calls, tt.wantCalls)
+			}
+			// Only the hooks of the attempt that committed run.
+			var want []int
+			if tt.wantErr == nil {
+				want = []int{calls}
+			}
+			if !reflect.DeepEqual(ran, want) {
+				t.Errorf("hooks ran for attempts %v, want %v", ran, want)
+			}
+		})
+	}
+}
+
+func TestAfterCommitWithoutTransaction(t *testing.T) {
+	ran := false
+	AfterCommit(context.Background(), func(context.Context) { ran = true })
+	if !ran {
+		t.Error("hook outside a transaction did not run right away")
+	}
+}
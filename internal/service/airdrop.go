@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"denet/internal/apperror"
+	"denet/internal/merkle"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/tracing"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrInvalidUnitsPerPoint = apperror.New("invalid_units_per_point", http.StatusBadRequest, "Units per point must be a positive integer and amounts must fit in uint256")
+	ErrSnapshotInFuture     = apperror.New("snapshot_in_future", http.StatusBadRequest, "Snapshot time must not be in the future")
+	ErrEmptyAirdrop         = apperror.New("empty_airdrop", http.StatusBadRequest, "No user with a primary wallet had points at the snapshot time")
+)
+
+type AirdropService interface {
+	// CreateSnapshot freezes the balances at req.SnapshotAt of users with a
+	// primary wallet, converts them to token amounts and stores the Merkle
+	// root and every recipient's proof. Amounts go to the wallet that is
+	// primary now, even when req.SnapshotAt is in the past.
+	CreateSnapshot(ctx context.Context, req *model.CreateAirdropRequest) (*model.Airdrop, error)
+	ListAirdrops(ctx context.Context) ([]model.Airdrop, error)
+	// GetClaim returns userID's allocation and proof in airdropID, or in the
+	// latest airdrop if airdropID is empty.
+	GetClaim(ctx context.Context, userID, airdropID string) (*model.AirdropClaim, error)
+}
+
+type airdropService struct {
+	uow repository.UnitOfWork
+}
+
+func NewAirdropService(uow repository.UnitOfWork) AirdropService {
+	return &airdropService{uow: uow}
+}
+
+func (s *airdropService) CreateSnapshot(ctx context.Context, req *model.CreateAirdropRequest) (_ *model.Airdrop, err error) {
+	ctx, span := tracing.Start(ctx, "AirdropService.CreateSnapshot")
+	defer func() { tracing.End(span, err) }()
+
+	unitsPerPoint, ok := new(big.Int).SetString(req.UnitsPerPoint, 10)
+	if !ok || unitsPerPoint.Sign() <= 0 {
+		return nil, ErrInvalidUnitsPerPoint
+	}
+	now := time.Now().UTC()
+	snapshotAt := now
+	if req.SnapshotAt != nil {
+		snapshotAt = req.SnapshotAt.UTC()
+	}
+	if snapshotAt.After(now) {
+		return nil, ErrSnapshotInFuture
+	}
+
+	var airdrop *model.Airdrop
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		balances, err := s.uow.Airdrops().SnapshotBalances(ctx, snapshotAt)
+		if err != nil {
+			return err
+		}
+		if len(balances) == 0 {
+			return ErrEmptyAirdrop
+		}
+
+		total := new(big.Int)
+		amounts := make([]*big.Int, len(balances))
+		leaves := make([]merkle.Hash, len(balances))
+		for i, balance := range balances {
+			amounts[i] = new(big.Int).Mul(big.NewInt(balance.Points), unitsPerPoint)
+			total.Add(total, amounts[i])
+			leaves[i], err = merkle.AddressAmountLeaf(balance.Address, amounts[i])
+			if err == merkle.ErrInvalidAmount {
+				return ErrInvalidUnitsPerPoint
+			}
+			if err != nil {
+				return fmt.Errorf("wallet of user %s: %w", balance.UserID, err)
+			}
+		}
+		tree, err := merkle.New(leaves)
+		if err != nil {
+			return err
+		}
+
+		airdrop = &model.Airdrop{
+			Name:          req.Name,
+			SnapshotAt:    snapshotAt,
+			UnitsPerPoint: unitsPerPoint.String(),
+			MerkleRoot:    tree.Root().Hex(),
+			TotalAmount:   total.String(),
+			Recipients:    len(balances),
+		}
+		if err := s.uow.Airdrops().Create(ctx, airdrop); err != nil {
+			return err
+		}
+		for i, balance := range balances {
+			proof, _ := tree.Proof(leaves[i])
+			claim := &model.AirdropClaim{
+				AirdropID: airdrop.ID,
+				UserID:    balance.UserID,
+				Address:   balance.Address,
+				Points:    balance.Points,
+				Amount:    amounts[i].String(),
+				Proof:     make([]string, len(proof)),
+			}
+			for j, sibling := range proof {
+				claim.Proof[j] = sibling.Hex()
+			}
+			if err := s.uow.Airdrops().AddClaim(ctx, claim); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return airdrop, nil
+}
+
+func (s *airdropService) ListAirdrops(ctx context.Context) (_ []model.Airdrop, err error) {
+	ctx, span := tracing.Start(ctx, "AirdropService.ListAirdrops")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Airdrops().List(ctx)
+}
+
+func (s *airdropService) GetClaim(ctx context.Context, userID, airdropID string) (_ *model.AirdropClaim, err error) {
+	ctx, span := tracing.Start(ctx, "AirdropService.GetClaim")
+	defer func() { tracing.End(span, err) }()
+
+	var airdrop *model.Airdrop
+	if airdropID == "" {
+		airdrop, err = s.uow.Airdrops().GetLatest(ctx)
+	} else {
+		airdrop, err = s.uow.Airdrops().GetByID(ctx, airdropID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.uow.Airdrops().GetClaim(ctx, airdrop.ID, userID)
+}
@@ -2,71 +2,652 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"net/http"
 	"time"
+	"denet/internal/apperror"
 	"denet/internal/model"
 	"denet/internal/repository"
+	"denet/internal/social"
+	"denet/internal/tracing"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 var (
-	ErrUserExists = errors.New("user already exists")
+	ErrInvalidCredentials = apperror.New("invalid_credentials", http.StatusUnauthorized, "Invalid credentials")
+	ErrInvalidReferralCode = apperror.New("invalid_referral_code", http.StatusBadRequest, "Invalid referral code")
+
+	ErrInvalidRefreshToken = apperror.New("invalid_refresh_token", http.StatusUnauthorized, "Invalid refresh token")
+	ErrRefreshTokenReused  = apperror.New("refresh_token_reused", http.StatusUnauthorized, "Refresh token reuse detected")
+
+	ErrTelegramLoginDisabled = apperror.New("telegram_login_disabled", http.StatusServiceUnavailable, "Telegram login is not configured")
+	ErrInvalidTelegramLogin  = apperror.New("invalid_telegram_login", http.StatusUnauthorized, "Invalid Telegram login data")
+	ErrTelegramLoginExpired  = apperror.New("telegram_login_expired", http.StatusUnauthorized, "Telegram login data has expired, sign in again")
+
+	// ErrAccountLocked carries the time the lock ends as its details, see
+	// LockedUntil.
+	ErrAccountLocked = apperror.New("account_locked", http.StatusTooManyRequests, "Account is temporarily locked after too many failed logins")
 )
 
+// TokenSigner signs access token claims; the key material lives outside the
+// service so keys can be rotated without touching it.
+type TokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+}
+
+type TokenConfig struct {
+	Signer     TokenSigner
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// TelegramAuthConfig enables the Telegram Login Widget. BotToken is the token
+// of the bot the widget is set up for; payloads older than MaxAge are
+// refused.
+type TelegramAuthConfig struct {
+	BotToken string
+	MaxAge   time.Duration
+}
+
+// LockoutConfig locks an account on the next password login after
+// MaxAttempts failed ones in a row, each less than Window after the one
+// before. The first lock lasts BaseDuration and every further one twice as
+// long as the last, up to MaxDuration. A successful login or an admin unlock
+// starts over. A MaxAttempts of zero disables locking.
+type LockoutConfig struct {
+	MaxAttempts  int
+	Window       time.Duration
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+}
+
+// duration is how long the n-th lock lasts.
+func (c LockoutConfig) duration(n int) time.Duration {
+	d := c.BaseDuration
+	for i := 1; i < n && d < c.MaxDuration; i++ {
+		d *= 2
+	}
+	return min(d, c.MaxDuration)
+}
+
 type AuthService interface {
-	Register(ctx context.Context, req *model.RegisterRequest) (*model.User, error)
+	// Register creates the user. With a non-empty idempotencyKey a retry of
+	// the same registration returns the user created the first time, once
+	// its password checks out, so the caller can issue it fresh tokens.
+	Register(ctx context.Context, req *model.RegisterRequest, idempotencyKey string) (*model.User, error)
+	// Login checks a username and password. Failed attempts count towards
+	// locking the account, after which ErrAccountLocked is returned without
+	// checking the password.
 	Login(ctx context.Context, req *model.LoginRequest) (*model.User, error)
-	GenerateToken(user *model.User, jwtSecret string) (string, error)
+	// GetLockout returns the user's failed login record, empty if there is
+	// none.
+	GetLockout(ctx context.Context, userID string) (*model.LoginLockout, error)
+	// Unlock lifts a lock and clears the failed logins of the user.
+	Unlock(ctx context.Context, userID string) error
+	// LoginWithTelegram signs in the user linked to the Telegram account the
+	// widget payload vouches for, creating one if there is none, and reports
+	// whether it did.
+	LoginWithTelegram(ctx context.Context, req *model.TelegramAuthRequest) (*model.User, bool, error)
+	// LinkTelegram attaches the Telegram account from a widget payload to an
+	// existing user.
+	LinkTelegram(ctx context.Context, userID string, authData map[string]json.RawMessage) (*model.SocialAccount, error)
+	// IssueTokens starts a new session for user and returns its first
+	// access/refresh token pair.
+	IssueTokens(ctx context.Context, user *model.User) (*model.TokenPair, error)
+	// Refresh rotates a refresh token. Presenting an already used token
+	// revokes the whole session and returns ErrRefreshTokenReused.
+	Refresh(ctx context.Context, refreshToken string) (*model.TokenPair, *model.User, error)
+	Logout(ctx context.Context, claims *model.JWTClaims) error
+	LogoutAll(ctx context.Context, userID string) error
+	IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error)
 }
 
 type authService struct {
-	uow repository.UnitOfWork
+	uow         repository.UnitOfWork
+	referrals   ReferralService
+	idempotency IdempotencyService
+	tokens      TokenConfig
+	telegram    TelegramAuthConfig
+	lockout     LockoutConfig
 }
 
-func NewAuthService(uow repository.UnitOfWork) AuthService {
-	return &authService{uow: uow}
+func NewAuthService(uow repository.UnitOfWork, referrals ReferralService, idempotency IdempotencyService, tokens TokenConfig, telegram TelegramAuthConfig, lockout LockoutConfig) AuthService {
+	return &authService{uow: uow, referrals: referrals, idempotency: idempotency, tokens: tokens, telegram: telegram, lockout: lockout}
 }
 
-func (s *authService) Register(ctx context.Context, req *model.RegisterRequest) (*model.User, error) {
-	_, err := s.uow.Users().GetByUsername(ctx, req.Username)
-	if err == nil {
-		return nil, ErrUserExists
+// registerScope holds the Idempotency-Keys of registrations. Only the ID of
+// the user created is stored for a key, never the tokens sent with it.
+const registerScope = "register"
+
+func (s *authService) Register(ctx context.Context, req *model.RegisterRequest, idempotencyKey string) (_ *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Register")
+	defer func() { tracing.End(span, err) }()
+
+	if idempotencyKey == "" {
+		return s.register(ctx, req)
 	}
-	_, err = s.uow.Users().GetByEmail(ctx, req.Email)
-	if err == nil {
-		return nil, ErrUserExists
+	if len(idempotencyKey) > model.MaxIdempotencyKeyLength {
+		return nil, ErrIdempotencyKeyTooLong
+	}
+	stored, err := s.idempotency.Begin(ctx, registerScope, idempotencyKey, registerFingerprint(req))
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		return s.replayRegister(ctx, stored, req.Password)
+	}
+
+	user, err := s.register(ctx, req)
+	if err != nil {
+		// A failed registration changed nothing and may be retried with
+		// the same key.
+		s.idempotency.Release(context.WithoutCancel(ctx), registerScope, idempotencyKey)
+		return nil, err
+	}
+	// The user exists either way; should storing the key fail, a retry is
+	// refused as a duplicate registration.
+	s.idempotency.Complete(context.WithoutCancel(ctx), registerScope, idempotencyKey,
+		http.StatusCreated, "text/plain", []byte(user.ID))
+	return user, nil
+}
+
+// registerFingerprint identifies a registration by everything but the
+// password, which is not kept outside its bcrypt hash; replayRegister checks
+// it instead.
+func registerFingerprint(req *model.RegisterRequest) string {
+	h := sha256.New()
+	for _, field := range []string{req.Username, req.Email, req.ReferralCode} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replayRegister returns the user a stored registration created, provided
+// the retry carries the same password.
+func (s *authService) replayRegister(ctx context.Context, stored *model.IdempotencyRecord, password string) (*model.User, error) {
+	user, err := s.uow.Users().GetByID(ctx, string(stored.ResponseBody))
+	if err != nil {
+		return nil, err
+	}
+	err = s.uow.Users().CheckPassword(ctx, user, password)
+	if errors.Is(err, repository.ErrInvalidPassword) {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *authService) register(ctx context.Context, req *model.RegisterRequest) (*model.User, error) {
+	if _, err := s.uow.Users().GetByUsername(ctx, req.Username); !errors.Is(err, repository.ErrUserNotFound) {
+		if err == nil {
+			return nil, repository.ErrUserExists
+		}
+		return nil, err
+	}
+	if _, err := s.uow.Users().GetByEmail(ctx, req.Email); !errors.Is(err, repository.ErrUserNotFound) {
+		if err == nil {
+			return nil, repository.ErrUserExists
+		}
+		return nil, err
+	}
+	referralCode, err := generateReferralCode()
+	if err != nil {
+		return nil, err
 	}
 	user := &model.User{
-		Username: req.Username,
-		Email:    req.Email,
-		Balance:  0,
+		Username:     req.Username,
+		Email:        req.Email,
+		Balance:      0,
+		ReferralCode: referralCode,
+	}
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.uow.Users().CreateWithPassword(ctx, user, req.Password); err != nil {
+			return err
+		}
+		if err := s.uow.Roles().Grant(ctx, user.ID, model.RoleUser, nil); err != nil {
+			return err
+		}
+		return s.linkReferrer(ctx, user, req.ReferralCode)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// linkReferrer links a new user to the owner of code, if one was given.
+func (s *authService) linkReferrer(ctx context.Context, user *model.User, code string) error {
+	if code == "" {
+		return nil
+	}
+	referrer, err := s.referrals.LinkByCode(ctx, user.ID, code)
+	if errors.Is(err, repository.ErrReferralCodeNotFound) {
+		return ErrInvalidReferralCode
+	}
+	if err != nil {
+		return err
+	}
+	user.ReferrerID = &referrer.ID
+	return nil
+}
+
+func (s *authService) Login(ctx context.Context, req *model.LoginRequest) (_ *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.uow.Users().GetByUsername(ctx, req.Username)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The attempt is counted as failed before the password is compared, in
+	// the same transaction as the lock check, so that concurrent guesses
+	// cannot all pass the check before any failure is recorded. A locked
+	// account costs no bcrypt comparison.
+	now := time.Now().UTC()
+	if err := s.reserveLoginAttempt(ctx, user.ID, now); err != nil {
+		return nil, err
+	}
+
+	err = s.uow.Users().CheckPassword(ctx, user, req.Password)
+	if errors.Is(err, repository.ErrInvalidPassword) {
+		return nil, ErrInvalidCredentials
 	}
-	err = s.uow.Users().CreateWithPassword(ctx, user, req.Password)
 	if err != nil {
 		return nil, err
 	}
+	if s.lockout.MaxAttempts > 0 {
+		if err := s.uow.LoginLockouts().Delete(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
 	return user, nil
 }
 
-func (s *authService) Login(ctx context.Context, req *model.LoginRequest) (*model.User, error) {
-	user, err := s.uow.Users().VerifyPassword(ctx, req.Username, req.Password)
+// reserveLoginAttempt counts a password login as failed until it succeeds.
+// The attempt after MaxAttempts counted ones locks the account and is
+// refused, so at most MaxAttempts passwords are compared between locks
+// however many logins run at once.
+func (s *authService) reserveLoginAttempt(ctx context.Context, userID string, now time.Time) error {
+	if s.lockout.MaxAttempts <= 0 {
+		return nil
+	}
+	var lockedUntil *time.Time
+	err := s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		lockout, err := s.uow.LoginLockouts().GetForUpdate(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if lockout.IsLocked(now) {
+			lockedUntil = lockout.LockedUntil
+			return nil
+		}
+		if lockout.LastFailedAt != nil && now.Sub(*lockout.LastFailedAt) > s.lockout.Window {
+			lockout.FailedAttempts = 0
+		}
+		lockout.FailedAttempts++
+		lockout.LastFailedAt = &now
+		if lockout.FailedAttempts > s.lockout.MaxAttempts {
+			lockout.Lockouts++
+			until := now.Add(s.lockout.duration(lockout.Lockouts))
+			lockout.LockedUntil = &until
+			lockout.FailedAttempts = 0
+			lockedUntil = &until
+		}
+		return s.uow.LoginLockouts().Update(ctx, lockout)
+	})
+	if err != nil {
+		return err
+	}
+	if lockedUntil != nil {
+		return ErrAccountLocked.WithDetails(lockedUntil.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// LockedUntil returns when the lock reported by an ErrAccountLocked error
+// ends.
+func LockedUntil(err error) (time.Time, bool) {
+	if !errors.Is(err, ErrAccountLocked) {
+		return time.Time{}, false
+	}
+	until, parseErr := time.Parse(time.RFC3339, apperror.From(err).Details)
+	if parseErr != nil {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+func (s *authService) GetLockout(ctx context.Context, userID string) (_ *model.LoginLockout, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.GetLockout")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.uow.Users().GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	lockout, err := s.uow.LoginLockouts().Get(ctx, userID)
+	if errors.Is(err, repository.ErrLoginLockoutNotFound) {
+		return &model.LoginLockout{UserID: userID}, nil
+	}
+	return lockout, err
+}
+
+func (s *authService) Unlock(ctx context.Context, userID string) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Unlock")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.uow.Users().GetByID(ctx, userID); err != nil {
+		return err
+	}
+	return s.uow.LoginLockouts().Delete(ctx, userID)
+}
+
+func (s *authService) LoginWithTelegram(ctx context.Context, req *model.TelegramAuthRequest) (_ *model.User, _ bool, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.LoginWithTelegram")
+	defer func() { tracing.End(span, err) }()
+
+	login, err := s.verifyTelegram(req.AuthData)
+	if err != nil {
+		return nil, false, err
+	}
+
+	user, err := s.telegramUser(ctx, login)
+	if err == nil || !errors.Is(err, repository.ErrSocialAccountNotFound) {
+		return user, false, err
+	}
+
+	user, err = s.registerTelegram(ctx, login, req.ReferralCode)
+	if errors.Is(err, repository.ErrSocialAccountTaken) {
+		// A concurrent sign-in with the same account created the user first.
+		user, err = s.telegramUser(ctx, login)
+		return user, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return user, true, nil
+}
+
+func (s *authService) LinkTelegram(ctx context.Context, userID string, authData map[string]json.RawMessage) (_ *model.SocialAccount, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.LinkTelegram")
+	defer func() { tracing.End(span, err) }()
+
+	login, err := s.verifyTelegram(authData)
+	if err != nil {
+		return nil, err
+	}
+	account := &model.SocialAccount{
+		UserID:     userID,
+		Provider:   model.SocialTelegram,
+		ExternalID: login.ID,
+		Handle:     login.Username,
+		Verified:   true,
+	}
+	if err := s.uow.SocialAccounts().Link(ctx, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (s *authService) verifyTelegram(authData map[string]json.RawMessage) (*social.TelegramLogin, error) {
+	if s.telegram.BotToken == "" {
+		return nil, ErrTelegramLoginDisabled
+	}
+	login, err := social.VerifyTelegramLogin(s.telegram.BotToken, authData, s.telegram.MaxAge, time.Now())
+	switch {
+	case errors.Is(err, social.ErrTelegramLoginExpired):
+		return nil, ErrTelegramLoginExpired
+	case err != nil:
+		return nil, ErrInvalidTelegramLogin
+	}
+	return login, nil
+}
+
+// telegramUser returns the user linked to the Telegram account, keeping the
+// stored handle current.
+func (s *authService) telegramUser(ctx context.Context, login *social.TelegramLogin) (*model.User, error) {
+	account, err := s.uow.SocialAccounts().GetByExternalID(ctx, model.SocialTelegram, login.ID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Handle != login.Username {
+		account.Handle = login.Username
+		if err := s.uow.SocialAccounts().Link(ctx, account); err != nil {
+			return nil, err
+		}
+	}
+	return s.uow.Users().GetByID(ctx, account.UserID)
+}
+
+// registerTelegram creates a user without email or password for a Telegram
+// account. The username is the Telegram one when it is free.
+func (s *authService) registerTelegram(ctx context.Context, login *social.TelegramLogin, referralCode string) (*model.User, error) {
+	ownCode, err := generateReferralCode()
+	if err != nil {
+		return nil, err
+	}
+	user := &model.User{ReferralCode: ownCode}
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.createWithFreeUsername(ctx, user, telegramUsernames(login)); err != nil {
+			return err
+		}
+		if err := s.uow.Roles().Grant(ctx, user.ID, model.RoleUser, nil); err != nil {
+			return err
+		}
+		err := s.uow.SocialAccounts().Link(ctx, &model.SocialAccount{
+			UserID:     user.ID,
+			Provider:   model.SocialTelegram,
+			ExternalID: login.ID,
+			Handle:     login.Username,
+			Verified:   true,
+		})
+		if err != nil {
+			return err
+		}
+		return s.linkReferrer(ctx, user, referralCode)
+	})
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *authService) GenerateToken(user *model.User, jwtSecret string) (string, error) {
+// createWithFreeUsername creates user under the first candidate username not
+// taken yet. Each attempt runs in its own savepoint so that a collision does
+// not abort the surrounding transaction.
+func (s *authService) createWithFreeUsername(ctx context.Context, user *model.User, candidates []string) error {
+	for _, username := range candidates {
+		user.Username = username
+		err := s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+			return s.uow.Users().Create(ctx, user)
+		})
+		if !errors.Is(err, repository.ErrUserExists) {
+			return err
+		}
+	}
+	return repository.ErrUserExists
+}
+
+// telegramUsernames lists the usernames to try for a Telegram user, the
+// Telegram username first. Suffixing the numeric ID keeps the last candidates
+// unique.
+func telegramUsernames(login *social.TelegramLogin) []string {
+	if login.Username == "" {
+		return []string{"tg_" + login.ID}
+	}
+	return []string{login.Username, login.Username + "_" + login.ID, "tg_" + login.ID}
+}
+
+func (s *authService) IssueTokens(ctx context.Context, user *model.User) (_ *model.TokenPair, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.IssueTokens")
+	defer func() { tracing.End(span, err) }()
+
+	var pair *model.TokenPair
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		session := &model.Session{UserID: user.ID}
+		if err := s.uow.Tokens().CreateSession(ctx, session); err != nil {
+			return err
+		}
+		var err error
+		pair, err = s.issuePair(ctx, user, session.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (_ *model.TokenPair, _ *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Refresh")
+	defer func() { tracing.End(span, err) }()
+
+	var (
+		pair   *model.TokenPair
+		user   *model.User
+		reused bool
+	)
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		token, err := s.uow.Tokens().GetRefreshTokenForUpdate(ctx, hashToken(refreshToken))
+		if err != nil {
+			if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if token.SessionRevoked || time.Now().UTC().After(token.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+		if token.UsedAt != nil {
+			// The revocation has to be committed, so the error is reported
+			// after the transaction instead of rolling it back.
+			reused = true
+			return s.uow.Tokens().RevokeSession(ctx, token.SessionID, model.RevokeReasonReuse)
+		}
+		if err := s.uow.Tokens().MarkRefreshTokenUsed(ctx, token.ID); err != nil {
+			return err
+		}
+		user, err = s.uow.Users().GetByID(ctx, token.UserID)
+		if err != nil {
+			return err
+		}
+		pair, err = s.issuePair(ctx, user, token.SessionID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if reused {
+		return nil, nil, ErrRefreshTokenReused
+	}
+	return pair, user, nil
+}
+
+func (s *authService) Logout(ctx context.Context, claims *model.JWTClaims) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Logout")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		if claims.SessionID != "" {
+			if err := s.uow.Tokens().RevokeSession(ctx, claims.SessionID, model.RevokeReasonLogout); err != nil {
+				return err
+			}
+		}
+		if claims.ID == "" || claims.ExpiresAt == nil {
+			return nil
+		}
+		return s.uow.Tokens().RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time)
+	})
+}
+
+func (s *authService) LogoutAll(ctx context.Context, userID string) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.LogoutAll")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Tokens().RevokeUserSessions(ctx, userID, model.RevokeReasonLogoutAll)
+}
+
+func (s *authService) IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	return s.uow.Tokens().IsRevoked(ctx, jti, sessionID)
+}
+
+func (s *authService) issuePair(ctx context.Context, user *model.User, sessionID string) (*model.TokenPair, error) {
+	now := time.Now().UTC()
+	accessToken, accessExpiresAt, err := s.generateAccessToken(ctx, user, sessionID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(raw)
+	stored := &model.RefreshToken{
+		SessionID: sessionID,
+		UserID:    user.ID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: now.Add(s.tokens.RefreshTTL),
+	}
+	if err := s.uow.Tokens().CreateRefreshToken(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	return &model.TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+func (s *authService) generateAccessToken(ctx context.Context, user *model.User, sessionID string, now time.Time) (string, time.Time, error) {
+	roles, err := s.uow.Roles().GetUserRoles(ctx, user.ID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	permissions, err := s.uow.Roles().GetUserPermissions(ctx, user.ID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := now.Add(s.tokens.AccessTTL)
 	claims := &model.JWTClaims{
-		UserID:   user.ID,
-		Username: user.Username,
+		UserID:      user.ID,
+		Username:    user.Username,
+		Roles:       roles,
+		Permissions: permissions,
+		SessionID:   sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        uuid.New().String(),
+			Subject:   user.ID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(jwtSecret))
-}
\ No newline at end of file
+	signed, err := s.tokens.Signer.Sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// hashToken is what gets stored for refresh tokens; the tokens are random,
+// so a plain SHA-256 is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
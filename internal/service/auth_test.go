@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestAuthService(lockout LockoutConfig) (*authService, *fakeStore) {
+	uow := newFakeStore()
+	return &authService{
+		uow:         uow,
+		referrals:   &fakeReferrals{},
+		idempotency: NewIdempotencyService(uow, time.Hour),
+		lockout:     lockout,
+	}, uow
+}
+
+func TestRegisterIdempotencyKey(t *testing.T) {
+	s, uow := newTestAuthService(LockoutConfig{})
+	ctx := context.Background()
+	req := &model.RegisterRequest{Username: "alice", Email: "alice@example.com", Password: "secret1"}
+
+	user, err := s.Register(ctx, req, "k1")
+	if err != nil {
+		t.Fatalf("Register() = %v", err)
+	}
+	replayed, err := s.Register(ctx, req, "k1")
+	if err != nil {
+		t.Fatalf("retried Register() = %v", err)
+	}
+	if replayed.ID != user.ID || len(uow.users) != 1 {
+		t.Errorf("retry returned user %s with %d users stored, want %s again", replayed.ID, len(uow.users), user.ID)
+	}
+	if record := uow.idempotency[registerScope+" k1"]; string(record.ResponseBody) != user.ID {
+		t.Errorf("stored response = %q, want only the user ID", record.ResponseBody)
+	}
+
+	tests := []struct {
+		name string
+		req  model.RegisterRequest
+		key  string
+		want error
+	}{
+		{"wrong password", model.RegisterRequest{Username: "alice", Email: "alice@example.com", Password: "guess"}, "k1", ErrIdempotencyKeyMismatch},
+		{"other email", model.RegisterRequest{Username: "alice", Email: "eve@example.com", Password: "secret1"}, "k1", ErrIdempotencyKeyMismatch},
+		{"new key", *req, "k2", repository.ErrUserExists},
+		{"no key", *req, "", repository.ErrUserExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Register(ctx, &tt.req, tt.key); !errors.Is(err, tt.want) {
+				t.Errorf("Register() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	// The failed registration under k2 does not hold on to the key.
+	if _, ok := uow.idempotency[registerScope+" k2"]; ok {
+		t.Error("key of a failed registration was kept")
+	}
+}
+
+var testLockout = LockoutConfig{MaxAttempts: 3, Window: 15 * time.Minute, BaseDuration: time.Minute, MaxDuration: time.Hour}
+
+// newTestLoginService returns an auth service locking after testLockout over
+// a store in which alice signs in with "secret1".
+func newTestLoginService(t *testing.T) (*authService, *fakeStore, string) {
+	s, uow := newTestAuthService(testLockout)
+	user := &model.User{Username: "alice", Email: "alice@example.com"}
+	if err := uow.Users().CreateWithPassword(context.Background(), user, "secret1"); err != nil {
+		t.Fatal(err)
+	}
+	return s, uow, user.ID
+}
+
+func TestLoginLockout(t *testing.T) {
+	s, uow, userID := newTestLoginService(t)
+	ctx := context.Background()
+	wrong := &model.LoginRequest{Username: "alice", Password: "guess"}
+	right := &model.LoginRequest{Username: "alice", Password: "secret1"}
+
+	for i := 0; i < testLockout.MaxAttempts; i++ {
+		if _, err := s.Login(ctx, wrong); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("failed login %d = %v, want %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+	// The next attempt locks the account, right password or not.
+	_, err := s.Login(ctx, right)
+	until, ok := LockedUntil(err)
+	if !ok {
+		t.Fatalf("login after %d failures = %v, want %v", testLockout.MaxAttempts, err, ErrAccountLocked)
+	}
+	if d := time.Until(until); d <= 0 || d > testLockout.BaseDuration {
+		t.Errorf("locked until %s, want within %s", until, testLockout.BaseDuration)
+	}
+	if _, err := s.Login(ctx, right); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("login while locked = %v, want %v", err, ErrAccountLocked)
+	}
+	lockout := uow.lockouts[userID]
+	if lockout.Lockouts != 1 || lockout.FailedAttempts != 0 || lockout.LockedUntil.Location() != time.UTC {
+		t.Errorf("lockout = %+v, want one lock in UTC and the count reset", lockout)
+	}
+
+	// Once the lock ends the right password gets in and clears the record.
+	past := time.Now().UTC().Add(-time.Second)
+	lockout.LockedUntil = &past
+	uow.lockouts[userID] = lockout
+	if _, err := s.Login(ctx, right); err != nil {
+		t.Fatalf("login after the lock = %v", err)
+	}
+	if _, ok := uow.lockouts[userID]; ok {
+		t.Error("lockout record kept after a successful login")
+	}
+}
+
+func TestLoginLockoutReservesAttempts(t *testing.T) {
+	s, uow, userID := newTestLoginService(t)
+	ctx := context.Background()
+	right := &model.LoginRequest{Username: "alice", Password: "secret1"}
+
+	// Attempts still comparing the password are counted as failed already,
+	// so one more than the limit is refused before its password is checked.
+	now := time.Now().UTC()
+	uow.lockouts[userID] = model.LoginLockout{UserID: userID, FailedAttempts: testLockout.MaxAttempts, LastFailedAt: &now}
+	if _, err := s.Login(ctx, right); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("login with %d attempts in flight = %v, want %v", testLockout.MaxAttempts, err, ErrAccountLocked)
+	}
+	if lockout := uow.lockouts[userID]; lockout.LockedUntil == nil {
+		t.Errorf("lockout = %+v, want the lock kept although the login was refused", lockout)
+	}
+}
+
+func TestLoginLockoutCount(t *testing.T) {
+	long := time.Now().UTC().Add(-time.Hour)
+	recent := time.Now().UTC().Add(-time.Minute)
+	tests := []struct {
+		name         string
+		prior        model.LoginLockout
+		wantAttempts int
+		wantLockFor  time.Duration
+	}{
+		{"first failure", model.LoginLockout{}, 1, 0},
+		{"recent failures add up", model.LoginLockout{FailedAttempts: 2, LastFailedAt: &recent}, 3, 0},
+		{"old failures are forgotten", model.LoginLockout{FailedAttempts: 3, LastFailedAt: &long}, 1, 0},
+		{"second lock lasts twice as long", model.LoginLockout{FailedAttempts: 3, LastFailedAt: &recent, Lockouts: 1, LockedUntil: &long}, 0, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, uow, userID := newTestLoginService(t)
+			if tt.prior != (model.LoginLockout{}) {
+				tt.prior.UserID = userID
+				uow.lockouts[userID] = tt.prior
+			}
+
+			_, err := s.Login(context.Background(), &model.LoginRequest{Username: "alice", Password: "guess"})
+			lockout := uow.lockouts[userID]
+			if lockout.FailedAttempts != tt.wantAttempts {
+				t.Errorf("failed attempts = %d, want %d", lockout.FailedAttempts, tt.wantAttempts)
+			}
+			if tt.wantLockFor == 0 {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("Login() = %v, want %v", err, ErrInvalidCredentials)
+				}
+				return
+			}
+			until, ok := LockedUntil(err)
+			if d := time.Until(until); !ok || d <= tt.wantLockFor-time.Minute || d > tt.wantLockFor {
+				t.Errorf("Login() = %v, want locked for %s", err, tt.wantLockFor)
+			}
+		})
+	}
+}
+
+func TestLoginWithoutLockout(t *testing.T) {
+	s, uow := newTestAuthService(LockoutConfig{})
+	ctx := context.Background()
+	uow.Users().CreateWithPassword(ctx, &model.User{Username: "alice"}, "secret1")
+
+	for i := 0; i < 10; i++ {
+		if _, err := s.Login(ctx, &model.LoginRequest{Username: "alice", Password: "guess"}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("failed login %d = %v, want %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+	if _, err := s.Login(ctx, &model.LoginRequest{Username: "alice", Password: "secret1"}); err != nil || len(uow.lockouts) != 0 {
+		t.Errorf("Login() = %v with %d lockout records, want no locking", err, len(uow.lockouts))
+	}
+}
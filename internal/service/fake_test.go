@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/store"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// fakeStore is an in-memory UnitOfWork for service tests. Only the
+// repository methods the tests reach are implemented; the embedded nil
+// interfaces make any other call panic. Transactions roll the whole store
+// back when fn fails, nested ones included.
+type fakeStore struct {
+	repository.UnitOfWork
+
+	users     map[string]model.User
+	tasks     map[string]model.Task
+	userTasks map[string]model.UserTask
+	ledger    []model.PointTransaction
+	rewards   map[string]model.Reward
+	orders    map[string]model.RewardOrder
+	lockouts  map[string]model.LoginLockout
+	// idempotency is keyed by scope and key joined with a space.
+	idempotency map[string]model.IdempotencyRecord
+	nextID      int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		users:       make(map[string]model.User),
+		tasks:       make(map[string]model.Task),
+		userTasks:   make(map[string]model.UserTask),
+		rewards:     make(map[string]model.Reward),
+		orders:      make(map[string]model.RewardOrder),
+		lockouts:    make(map[string]model.LoginLockout),
+		idempotency: make(map[string]model.IdempotencyRecord),
+	}
+}
+
+func (s *fakeStore) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s-%d", prefix, s.nextID)
+}
+
+// balance returns the user's balance and checks that it matches their ledger.
+func (s *fakeStore) balance(userID string) (int, error) {
+	sum := 0
+	for _, entry := range s.ledger {
+		if entry.UserID == userID {
+			sum += entry.Delta
+		}
+	}
+	if balance := s.users[userID].Balance; balance != sum {
+		return balance, fmt.Errorf("balance %d does not match ledger total %d", balance, sum)
+	}
+	return sum, nil
+}
+
+func (s *fakeStore) Users() repository.UserRepository                 { return fakeUsers{s: s} }
+func (s *fakeStore) Tasks() repository.TaskRepository                 { return fakeTasks{s: s} }
+func (s *fakeStore) UserTasks() repository.UserTaskRepository         { return fakeUserTasks{s: s} }
+func (s *fakeStore) Ledger() repository.LedgerRepository              { return fakeLedger{s: s} }
+func (s *fakeStore) Transactions() repository.TransactionRepository   { return fakeTransactions{s: s} }
+func (s *fakeStore) Idempotency() repository.IdempotencyRepository    { return fakeIdempotency{s: s} }
+func (s *fakeStore) Roles() repository.RoleRepository                 { return fakeRoles{} }
+func (s *fakeStore) Rewards() repository.RewardRepository             { return fakeRewards{s: s} }
+func (s *fakeStore) LoginLockouts() repository.LoginLockoutRepository { return fakeLockouts{s: s} }
+
+type fakeTransactions struct {
+	repository.TransactionRepository
+	s *fakeStore
+}
+
+func (t fakeTransactions) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	return t.WithTransactionOptions(ctx, store.TxOptions{}, fn)
+}
+
+func (t fakeTransactions) WithTransactionOptions(ctx context.Context, opts store.TxOptions, fn func(context.Context) error) error {
+	saved := *t.s
+	saved.users = maps.Clone(t.s.users)
+	saved.tasks = maps.Clone(t.s.tasks)
+	saved.userTasks = maps.Clone(t.s.userTasks)
+	saved.ledger = append([]model.PointTransaction(nil), t.s.ledger...)
+	saved.idempotency = maps.Clone(t.s.idempotency)
+	saved.rewards = maps.Clone(t.s.rewards)
+	saved.orders = maps.Clone(t.s.orders)
+	saved.lockouts = maps.Clone(t.s.lockouts)
+	if err := fn(ctx); err != nil {
+		*t.s = saved
+		return err
+	}
+	return nil
+}
+
+type fakeUsers struct {
+	repository.UserRepository
+	s *fakeStore
+}
+
+func (r fakeUsers) GetByID(ctx context.Context, id string) (*model.User, error) {
+	user, ok := r.s.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r fakeUsers) GetByIDForUpdate(ctx context.Context, id string) (*model.User, error) {
+	return r.GetByID(ctx, id)
+}
+
+func (r fakeUsers) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	for _, user := range r.s.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r fakeUsers) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	for _, user := range r.s.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+// CreateWithPassword stores the password as is; CheckPassword compares it.
+func (r fakeUsers) CreateWithPassword(ctx context.Context, user *model.User, password string) error {
+	user.ID = r.s.newID("user")
+	user.PasswordHash = password
+	r.s.users[user.ID] = *user
+	return nil
+}
+
+func (r fakeUsers) CheckPassword(ctx context.Context, user *model.User, password string) error {
+	if user.PasswordHash != password {
+		return repository.ErrInvalidPassword
+	}
+	return nil
+}
+
+type fakeTasks struct {
+	repository.TaskRepository
+	s *fakeStore
+}
+
+func (r fakeTasks) GetByID(ctx context.Context, id string) (*model.Task, error) {
+	task, ok := r.s.tasks[id]
+	if !ok {
+		return nil, repository.ErrTaskNotFound
+	}
+	return &task, nil
+}
+
+type fakeUserTasks struct {
+	repository.UserTaskRepository
+	s *fakeStore
+}
+
+func (r fakeUserTasks) Create(ctx context.Context, userTask *model.UserTask) error {
+	if _, err := r.Get(ctx, userTask.UserID, userTask.TaskID); err == nil {
+		return repository.ErrUserTaskExists
+	}
+	userTask.ID = r.s.newID("submission")
+	r.s.userTasks[userTask.ID] = *userTask
+	return nil
+}
+
+func (r fakeUserTasks) GetByID(ctx context.Context, id string) (*model.UserTask, error) {
+	userTask, ok := r.s.userTasks[id]
+	if !ok {
+		return nil, repository.ErrUserTaskNotFound
+	}
+	return &userTask, nil
+}
+
+func (r fakeUserTasks) GetByIDForUpdate(ctx context.Context, id string) (*model.UserTask, error) {
+	return r.GetByID(ctx, id)
+}
+
+func (r fakeUserTasks) GetForUpdate(ctx context.Context, userID, taskID string) (*model.UserTask, error) {
+	return r.Get(ctx, userID, taskID)
+}
+
+func (r fakeUserTasks) Get(ctx context.Context, userID, taskID string) (*model.UserTask, error) {
+	for _, userTask := range r.s.userTasks {
+		if userTask.UserID == userID && userTask.TaskID == taskID {
+			return &userTask, nil
+		}
+	}
+	return nil, repository.ErrUserTaskNotFound
+}
+
+func (r fakeUserTasks) Update(ctx context.Context, userTask *model.UserTask) error {
+	if _, ok := r.s.userTasks[userTask.ID]; !ok {
+		return repository.ErrUserTaskNotFound
+	}
+	r.s.userTasks[userTask.ID] = *userTask
+	return nil
+}
+
+// fakeLedger enforces what the database does: unique idempotency keys and
+// users_balance_non_negative.
+type fakeLedger struct {
+	repository.LedgerRepository
+	s *fakeStore
+}
+
+func (r fakeLedger) Append(ctx context.Context, entry *model.PointTransaction) (int, error) {
+	if _, err := r.GetByIdempotencyKey(ctx, entry.IdempotencyKey); err == nil {
+		return 0, repository.ErrDuplicateTransaction
+	}
+	user, ok := r.s.users[entry.UserID]
+	if !ok {
+		return 0, repository.ErrUserNotFound
+	}
+	if user.Balance+entry.Delta < 0 {
+		return 0, repository.ErrInsufficientBalance
+	}
+	user.Balance += entry.Delta
+	r.s.users[user.ID] = user
+	entry.ID = r.s.newID("entry")
+	entry.CreatedAt = time.Now().UTC()
+	r.s.ledger = append(r.s.ledger, *entry)
+	return user.Balance, nil
+}
+
+func (r fakeLedger) GetByIdempotencyKey(ctx context.Context, key string) (*model.PointTransaction, error) {
+	for _, entry := range r.s.ledger {
+		if entry.IdempotencyKey == key {
+			return &entry, nil
+		}
+	}
+	return nil, repository.ErrTransactionNotFound
+}
+
+// ListByUser ignores the cursor and returns the newest limit entries.
+func (r fakeLedger) ListByUser(ctx context.Context, userID, cursor string, limit int) (*model.PointTransactionPage, error) {
+	page := &model.PointTransactionPage{Transactions: []model.PointTransaction{}}
+	for i := len(r.s.ledger) - 1; i >= 0 && len(page.Transactions) < limit; i-- {
+		if r.s.ledger[i].UserID == userID {
+			page.Transactions = append(page.Transactions, r.s.ledger[i])
+		}
+	}
+	return page, nil
+}
+
+// fakeReferrals records the referral events instead of paying shares.
+type fakeReferrals struct {
+	ReferralService
+	earned  []string
+	revoked []string
+}
+
+func (f *fakeReferrals) OnPointsEarned(ctx context.Context, userID string, points int, sourceKey string) error {
+	f.earned = append(f.earned, sourceKey)
+	return nil
+}
+
+func (f *fakeReferrals) OnPointsRevoked(ctx context.Context, sourceKey string) error {
+	f.revoked = append(f.revoked, sourceKey)
+	return nil
+}
+
+type fakeIdempotency struct {
+	repository.IdempotencyRepository
+	s *fakeStore
+}
+
+func (r fakeIdempotency) Reserve(ctx context.Context, record *model.IdempotencyRecord) (bool, error) {
+	existing, ok := r.s.idempotency[record.Scope+" "+record.Key]
+	if ok && existing.ExpiresAt.After(time.Now().UTC()) {
+		return false, nil
+	}
+	r.s.idempotency[record.Scope+" "+record.Key] = *record
+	return true, nil
+}
+
+func (r fakeIdempotency) Get(ctx context.Context, scope, key string) (*model.IdempotencyRecord, error) {
+	record, ok := r.s.idempotency[scope+" "+key]
+	if !ok {
+		return nil, repository.ErrIdempotencyKeyNotFound
+	}
+	return &record, nil
+}
+
+func (r fakeIdempotency) Complete(ctx context.Context, record *model.IdempotencyRecord) error {
+	stored := r.s.idempotency[record.Scope+" "+record.Key]
+	stored.StatusCode, stored.ContentType, stored.ResponseBody = record.StatusCode, record.ContentType, record.ResponseBody
+	stored.ExpiresAt = record.ExpiresAt
+	r.s.idempotency[record.Scope+" "+record.Key] = stored
+	return nil
+}
+
+func (r fakeIdempotency) Release(ctx context.Context, scope, key string) error {
+	if record, ok := r.s.idempotency[scope+" "+key]; ok && !record.Completed() {
+		delete(r.s.idempotency, scope+" "+key)
+	}
+	return nil
+}
+
+// fakeRoles accepts every grant.
+type fakeRoles struct {
+	repository.RoleRepository
+}
+
+func (fakeRoles) Grant(ctx context.Context, userID, roleName string, grantedBy *string) error {
+	return nil
+}
+
+type fakeRewards struct {
+	repository.RewardRepository
+	s *fakeStore
+}
+
+func (r fakeRewards) GetByIDForUpdate(ctx context.Context, id string) (*model.Reward, error) {
+	reward, ok := r.s.rewards[id]
+	if !ok {
+		return nil, repository.ErrRewardNotFound
+	}
+	return &reward, nil
+}
+
+// AdjustStock replaces the stock pointer rather than writing through it, so
+// that rolled back copies keep their value.
+func (r fakeRewards) AdjustStock(ctx context.Context, id string, delta int) error {
+	reward, ok := r.s.rewards[id]
+	if !ok {
+		return repository.ErrRewardNotFound
+	}
+	if reward.Stock != nil {
+		stock := *reward.Stock + delta
+		reward.Stock = &stock
+	}
+	r.s.rewards[id] = reward
+	return nil
+}
+
+func (r fakeRewards) CreateOrder(ctx context.Context, order *model.RewardOrder) error {
+	order.ID = r.s.newID("order")
+	r.s.orders[order.ID] = *order
+	return nil
+}
+
+func (r fakeRewards) GetOrderForUpdate(ctx context.Context, id string) (*model.RewardOrder, error) {
+	order, ok := r.s.orders[id]
+	if !ok {
+		return nil, repository.ErrRewardOrderNotFound
+	}
+	return &order, nil
+}
+
+func (r fakeRewards) UpdateOrder(ctx context.Context, order *model.RewardOrder) error {
+	r.s.orders[order.ID] = *order
+	return nil
+}
+
+func (r fakeRewards) CountActiveOrders(ctx context.Context, userID, rewardID string) (int, error) {
+	count := 0
+	for _, order := range r.s.orders {
+		if order.UserID == userID && order.RewardID == rewardID && order.Status != model.RewardOrderCancelled {
+			count++
+		}
+	}
+	return count, nil
+}
+
+type fakeLockouts struct {
+	repository.LoginLockoutRepository
+	s *fakeStore
+}
+
+func (r fakeLockouts) Get(ctx context.Context, userID string) (*model.LoginLockout, error) {
+	lockout, ok := r.s.lockouts[userID]
+	if !ok {
+		return nil, repository.ErrLoginLockoutNotFound
+	}
+	return &lockout, nil
+}
+
+func (r fakeLockouts) GetForUpdate(ctx context.Context, userID string) (*model.LoginLockout, error) {
+	if _, ok := r.s.lockouts[userID]; !ok {
+		r.s.lockouts[userID] = model.LoginLockout{UserID: userID}
+	}
+	return r.Get(ctx, userID)
+}
+
+func (r fakeLockouts) Update(ctx context.Context, lockout *model.LoginLockout) error {
+	if _, ok := r.s.lockouts[lockout.UserID]; !ok {
+		return repository.ErrLoginLockoutNotFound
+	}
+	lockout.UpdatedAt = time.Now()
+	r.s.lockouts[lockout.UserID] = *lockout
+	return nil
+}
+
+func (r fakeLockouts) Delete(ctx context.Context, userID string) error {
+	delete(r.s.lockouts, userID)
+	return nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"denet/internal/apperror"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyInFlight = apperror.New("idempotency_key_in_flight", http.StatusConflict,
+		"A request with this Idempotency-Key is still being processed")
+	ErrIdempotencyKeyMismatch = apperror.New("idempotency_key_mismatch", http.StatusUnprocessableEntity,
+		"Idempotency-Key was already used with a different request")
+	ErrIdempotencyKeyTooLong = apperror.New("idempotency_key_too_long", http.StatusBadRequest,
+		"Idempotency-Key header is too long")
+)
+
+// inFlightTimeout bounds how long a reservation survives a request that never
+// finished, e.g. because the process died while handling it.
+const inFlightTimeout = time.Minute
+
+type IdempotencyService interface {
+	// Begin reserves key within scope for a request with the given
+	// fingerprint. It returns a stored record when the response should be
+	// replayed instead, and nil when the caller now owns the key.
+	Begin(ctx context.Context, scope, key, fingerprint string) (*model.IdempotencyRecord, error)
+	Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, scope, key string) error
+	PurgeExpired(ctx context.Context) error
+}
+
+type idempotencyService struct {
+	uow repository.UnitOfWork
+	ttl time.Duration
+}
+
+func NewIdempotencyService(uow repository.UnitOfWork, ttl time.Duration) IdempotencyService {
+	return &idempotencyService{uow: uow, ttl: ttl}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, scope, key, fingerprint string) (*model.IdempotencyRecord, error) {
+	record := &model.IdempotencyRecord{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().UTC().Add(inFlightTimeout),
+	}
+	reserved, err := s.uow.Idempotency().Reserve(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := s.uow.Idempotency().Get(ctx, scope, key)
+	if errors.Is(err, repository.ErrIdempotencyKeyNotFound) {
+		// Released or purged between the two queries; try once more.
+		if reserved, err = s.uow.Idempotency().Reserve(ctx, record); err != nil {
+			return nil, err
+		}
+		if reserved {
+			return nil, nil
+		}
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+	if !existing.Completed() {
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	return existing, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error {
+	return s.uow.Idempotency().Complete(ctx, &model.IdempotencyRecord{
+		Scope:        scope,
+		Key:          key,
+		StatusCode:   &statusCode,
+		ContentType:  contentType,
+		ResponseBody: body,
+		ExpiresAt:    time.Now().UTC().Add(s.ttl),
+	})
+}
+
+func (s *idempotencyService) Release(ctx context.Context, scope, key string) error {
+	return s.uow.Idempotency().Release(ctx, scope, key)
+}
+
+func (s *idempotencyService) PurgeExpired(ctx context.Context) error {
+	return s.uow.Idempotency().DeleteExpired(ctx, time.Now().UTC())
+}
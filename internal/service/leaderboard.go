@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"denet/internal/apperror"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/tracing"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrUnknownLeaderboardWindow = apperror.New("unknown_leaderboard_window", http.StatusBadRequest, "Unknown leaderboard window")
+	ErrNoActiveSeason           = apperror.New("no_active_season", http.StatusNotFound, "No active season")
+)
+
+type LeaderboardService interface {
+	// GetWindowLeaderboard ranks users by points earned in the current day,
+	// week or month (UTC), or in a season. An empty seasonID selects the
+	// season running now.
+	GetWindowLeaderboard(ctx context.Context, window, seasonID string, limit int) (*model.WindowLeaderboard, error)
+	ListSeasons(ctx context.Context) ([]model.Season, error)
+	CreateSeason(ctx context.Context, req *model.CreateSeasonRequest) (*model.Season, error)
+	// GetSeasonResults returns the archived standings of a closed season, or
+	// the live standings while it has not been archived yet.
+	GetSeasonResults(ctx context.Context, seasonID string, limit int) (*model.WindowLeaderboard, error)
+	// ArchiveEndedSeasons freezes the final standings of every season that
+	// has ended and returns how many were archived.
+	ArchiveEndedSeasons(ctx context.Context) (int, error)
+}
+
+type leaderboardService struct {
+	uow repository.UnitOfWork
+}
+
+func NewLeaderboardService(uow repository.UnitOfWork) LeaderboardService {
+	return &leaderboardService{uow: uow}
+}
+
+func (s *leaderboardService) GetWindowLeaderboard(ctx context.Context, window, seasonID string, limit int) (_ *model.WindowLeaderboard, err error) {
+	ctx, span := tracing.Start(ctx, "LeaderboardService.GetWindowLeaderboard")
+	defer func() { tracing.End(span, err) }()
+
+	limit = clampLeaderboardLimit(limit)
+	now := time.Now().UTC()
+
+	if window == model.LeaderboardSeason {
+		var (
+			season *model.Season
+			err    error
+		)
+		if seasonID == "" {
+			season, err = s.uow.Seasons().GetCurrent(ctx, now)
+			if errors.Is(err, repository.ErrSeasonNotFound) {
+				return nil, ErrNoActiveSeason
+			}
+		} else {
+			season, err = s.uow.Seasons().GetByID(ctx, seasonID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return s.seasonStandings(ctx, season, limit)
+	}
+
+	from, to, err := windowBounds(window, now)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.uow.Ledger().GetWindowLeaderboard(ctx, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &model.WindowLeaderboard{
+		Window:  window,
+		From:    from,
+		To:      to,
+		Entries: entries,
+	}, nil
+}
+
+func (s *leaderboardService) ListSeasons(ctx context.Context) (_ []model.Season, err error) {
+	ctx, span := tracing.Start(ctx, "LeaderboardService.ListSeasons")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Seasons().List(ctx)
+}
+
+func (s *leaderboardService) CreateSeason(ctx context.Context, req *model.CreateSeasonRequest) (_ *model.Season, err error) {
+	ctx, span := tracing.Start(ctx, "LeaderboardService.CreateSeason")
+	defer func() { tracing.End(span, err) }()
+
+	season := &model.Season{
+		Name:     req.Name,
+		StartsAt: req.StartsAt.UTC(),
+		EndsAt:   req.EndsAt.UTC(),
+	}
+	if err := s.uow.Seasons().Create(ctx, season); err != nil {
+		return nil, err
+	}
+	return season, nil
+}
+
+func (s *leaderboardService) GetSeasonResults(ctx context.Context, seasonID string, limit int) (_ *model.WindowLeaderboard, err error) {
+	ctx, span := tracing.Start(ctx, "LeaderboardService.GetSeasonResults")
+	defer func() { tracing.End(span, err) }()
+
+	season, err := s.uow.Seasons().GetByID(ctx, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	return s.seasonStandings(ctx, season, clampLeaderboardLimit(limit))
+}
+
+func (s *leaderboardService) ArchiveEndedSeasons(ctx context.Context) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "LeaderboardService.ArchiveEndedSeasons")
+	defer func() { tracing.End(span, err) }()
+
+	archived := 0
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		seasons, err := s.uow.Seasons().LockEnded(ctx, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		for i := range seasons {
+			if err := s.uow.Seasons().Archive(ctx, &seasons[i]); err != nil {
+				return err
+			}
+		}
+		archived = len(seasons)
+		return nil
+	})
+	return archived, err
+}
+
+func (s *leaderboardService) seasonStandings(ctx context.Context, season *model.Season, limit int) (*model.WindowLeaderboard, error) {
+	board := &model.WindowLeaderboard{
+		Window: model.LeaderboardSeason,
+		From:   season.StartsAt,
+		To:     season.EndsAt,
+		Season: season,
+		Final:  season.ArchivedAt != nil,
+	}
+	var err error
+	if board.Final {
+		board.Entries, err = s.uow.Seasons().GetResults(ctx, season.ID, limit)
+	} else {
+		board.Entries, err = s.uow.Ledger().GetWindowLeaderboard(ctx, season.StartsAt, season.EndsAt, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+// windowBounds returns the UTC calendar period containing now. Weeks start
+// on Monday.
+func windowBounds(window string, now time.Time) (time.Time, time.Time, error) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch window {
+	case model.LeaderboardDaily:
+		return day, day.AddDate(0, 0, 1), nil
+	case model.LeaderboardWeekly:
+		offset := (int(day.Weekday()) + 6) % 7
+		start := day.AddDate(0, 0, -offset)
+		return start, start.AddDate(0, 0, 7), nil
+	case model.LeaderboardMonthly:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, time.Time{}, ErrUnknownLeaderboardWindow
+}
+
+func clampLeaderboardLimit(limit int) int {
+	if limit <= 0 || limit > 100 {
+		return 10
+	}
+	return limit
+}
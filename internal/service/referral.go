@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"denet/internal/apperror"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/tracing"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+)
+
+var (
+	ErrSelfReferral = apperror.New("self_referral", http.StatusBadRequest, "User cannot refer themselves")
+)
+
+// referralCodeAlphabet leaves out characters that are easy to confuse when a
+// code is typed by hand (0/O, 1/I/L).
+const (
+	referralCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+	referralCodeLength   = 8
+
+	maxReferralTreeDepth = 10
+	maxReferralTreeNodes = 1000
+)
+
+type ReferralService interface {
+	// Link records referrerID as the referrer of userID and pays the signup
+	// rewards. It must be called inside the caller's transaction, if any.
+	Link(ctx context.Context, userID, referrerID string) error
+	// LinkByCode resolves a referral code and links to its owner, returning
+	// the referrer.
+	LinkByCode(ctx context.Context, userID, code string) (*model.User, error)
+	// OnPointsEarned pays earning and milestone rewards up the referral chain
+	// after userID received points for a task. sourceKey identifies the
+	// originating ledger entry and makes the payouts idempotent.
+	OnPointsEarned(ctx context.Context, userID string, points int, sourceKey string) error
+	// OnPointsRevoked takes back the earning shares paid for sourceKey.
+	// Milestone rewards already reached are kept.
+	OnPointsRevoked(ctx context.Context, sourceKey string) error
+	SetReferralCode(ctx context.Context, userID, code string) error
+
+	GetReferees(ctx context.Context, userID, cursor string, limit int) (*model.RefereePage, error)
+	GetTree(ctx context.Context, userID string, depth int) (*model.ReferralTree, error)
+	GetStats(ctx context.Context, userID string) (*model.ReferralStats, error)
+
+	ListRules(ctx context.Context) ([]model.ReferralRule, error)
+	CreateRule(ctx context.Context, req *model.CreateReferralRuleRequest) (*model.ReferralRule, error)
+	UpdateRule(ctx context.Context, id string, req *model.UpdateReferralRuleRequest) (*model.ReferralRule, error)
+}
+
+type referralService struct {
+	uow repository.UnitOfWork
+}
+
+func NewReferralService(uow repository.UnitOfWork) ReferralService {
+	return &referralService{uow: uow}
+}
+
+func (s *referralService) Link(ctx context.Context, userID, referrerID string) (err error) {
+	ctx, span := tracing.Start(ctx, "ReferralService.Link")
+	defer func() { tracing.End(span, err) }()
+
+	if userID == referrerID {
+		return ErrSelfReferral
+	}
+	if err := s.uow.Users().SetReferrer(ctx, userID, referrerID); err != nil {
+		return err
+	}
+	return s.payRules(ctx, userID, model.ReferralEventSignup, func(rule model.ReferralRule) (int, string, string) {
+		return rule.Points,
+			fmt.Sprintf("referral signup bonus (level %d)", rule.Level),
+			"referral:signup:" + rule.ID + ":" + userID
+	})
+}
+
+func (s *referralService) LinkByCode(ctx context.Context, userID, code string) (_ *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "ReferralService.LinkByCode")
+	defer func() { tracing.End(span, err) }()
+
+	referrer, err := s.uow.Users().GetByReferralCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Link(ctx, userID, referrer.ID); err != nil {
+		return nil, err
+	}
+	return referrer, nil
+}
+
+func (s *referralService) OnPointsEarned(ctx context.Context, userID string, points int, sourceKey string) (err error) {
+	ctx, span := tracing.Start(ctx, "ReferralService.OnPointsEarned")
+	defer func() { tracing.End(span, err) }()
+
+	err = s.payRules(ctx, userID, model.ReferralEventEarning, func(rule model.ReferralRule) (int, string, string) {
+		return int(math.Floor(float64(points) * rule.Percent / 100)),
+			fmt.Sprintf("referral earning share %.2f%% (level %d)", rule.Percent, rule.Level),
+			"referral:earning:" + rule.ID + ":" + sourceKey
+	})
+	if err != nil {
+		return err
+	}
+
+	earned, err := s.uow.Ledger().SumEarned(ctx, userID, model.PointSourceTask)
+	if err != nil {
+		return err
+	}
+	return s.payRules(ctx, userID, model.ReferralEventMilestone, func(rule model.ReferralRule) (int, string, string) {
+		if rule.MilestonePoints == nil || earned < *rule.MilestonePoints {
+			return 0, "", ""
+		}
+		return rule.Points,
+			fmt.Sprintf("referral milestone %d points (level %d)", *rule.MilestonePoints, rule.Level),
+			"referral:milestone:" + rule.ID + ":" + userID
+	})
+}
+
+func (s *referralService) OnPointsRevoked(ctx context.Context, sourceKey string) (err error) {
+	ctx, span := tracing.Start(ctx, "ReferralService.OnPointsRevoked")
+	defer func() { tracing.End(span, err) }()
+
+	// Inactive rules are included: a share paid before a rule was switched
+	// off is still taken back.
+	rules, err := s.uow.Referrals().ListRules(ctx, false)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.Event != model.ReferralEventEarning {
+			continue
+		}
+		err := reverseLedgerEntry(ctx, s.uow,
+			"referral:earning:"+rule.ID+":"+sourceKey,
+			"referral:earning-revoke:"+rule.ID+":"+sourceKey,
+			fmt.Sprintf("referral earning share revoked (level %d)", rule.Level))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// payRules credits the ancestors of refereeID for every active rule of event.
+// amount returns the points, ledger reason and idempotency key for a rule;
+// zero points skips it.
+func (s *referralService) payRules(ctx context.Context, refereeID, event string, amount func(model.ReferralRule) (int, string, string)) error {
+	rules, err := s.uow.Referrals().ListRules(ctx, true)
+	if err != nil {
+		return err
+	}
+	maxLevel := 0
+	for _, rule := range rules {
+		if rule.Event == event && rule.Level > maxLevel {
+			maxLevel = rule.Level
+		}
+	}
+	if maxLevel == 0 {
+		return nil
+	}
+
+	ancestors, err := s.uow.Users().GetReferralAncestors(ctx, refereeID, maxLevel)
+	if err != nil {
+		return err
+	}
+	byLevel := make(map[int]string, len(ancestors))
+	for _, ancestor := range ancestors {
+		byLevel[ancestor.Level] = ancestor.UserID
+	}
+
+	for _, rule := range rules {
+		beneficiaryID, ok := byLevel[rule.Level]
+		if rule.Event != event || !ok {
+			continue
+		}
+		points, reason, key := amount(rule)
+		if points <= 0 {
+			continue
+		}
+		_, err := s.uow.Ledger().Append(ctx, &model.PointTransaction{
+			UserID:         beneficiaryID,
+			Delta:          points,
+			Reason:         reason,
+			SourceType:     model.PointSourceReferral,
+			SourceID:       &refereeID,
+			IdempotencyKey: key,
+		})
+		if err != nil && !errors.Is(err, repository.ErrDuplicateTransaction) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *referralService) SetReferralCode(ctx context.Context, userID, code string) (err error) {
+	ctx, span := tracing.Start(ctx, "ReferralService.SetReferralCode")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Users().SetReferralCode(ctx, userID, code)
+}
+
+func (s *referralService) GetReferees(ctx context.Context, userID, cursor string, limit int) (_ *model.RefereePage, err error) {
+	ctx, span := tracing.Start(ctx, "ReferralService.GetReferees")
+	defer func() { tracing.End(span, err) }()
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if _, err := s.uow.Users().GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.uow.Referrals().ListReferees(ctx, userID, cursor, limit)
+}
+
+func (s *referralService) GetTree(ctx context.Context, userID string, depth int) (_ *model.ReferralTree, err error) {
+	ctx, span := tracing.Start(ctx, "ReferralService.GetTree")
+	defer func() { tracing.End(span, err) }()
+
+	if depth <= 0 {
+		depth = 3
+	}
+	if depth > maxReferralTreeDepth {
+		depth = maxReferralTreeDepth
+	}
+	if _, err := s.uow.Users().GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	nodes, err := s.uow.Referrals().GetTree(ctx, userID, depth, maxReferralTreeNodes+1)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &model.ReferralTree{
+		UserID:   userID,
+		Depth:    depth,
+		Referees: []*model.ReferralTreeNode{},
+	}
+	if len(nodes) > maxReferralTreeNodes {
+		nodes = nodes[:maxReferralTreeNodes]
+		tree.Truncated = true
+	}
+	tree.Total = len(nodes)
+
+	// Nodes arrive ordered by depth, so every parent is indexed before its
+	// children.
+	byID := make(map[string]*model.ReferralTreeNode, len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+		if node.Depth == 1 {
+			tree.Referees = append(tree.Referees, node)
+			continue
+		}
+		if parent, ok := byID[node.ReferrerID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return tree, nil
+}
+
+func (s *referralService) GetStats(ctx context.Context, userID string) (_ *model.ReferralStats, err error) {
+	ctx, span := tracing.Start(ctx, "ReferralService.GetStats")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.uow.Users().GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.uow.Referrals().GetStats(ctx, userID)
+}
+
+func (s *referralService) ListRules(ctx context.Context) (_ []model.ReferralRule, err error) {
+	ctx, span := tracing.Start(ctx, "ReferralService.ListRules")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Referrals().ListRules(ctx, false)
+}
+
+func (s *referralService) CreateRule(ctx context.Context, req *model.CreateReferralRuleRequest) (_ *model.ReferralRule, err error) {
+	ctx, span := tracing.Start(ctx, "ReferralService.CreateRule")
+	defer func() { tracing.End(span, err) }()
+
+	rule := &model.ReferralRule{
+		Event:           req.Event,
+		Level:           req.Level,
+		MilestonePoints: req.MilestonePoints,
+		Points:          req.Points,
+		Percent:         req.Percent,
+		Active:          true,
+	}
+	if err := s.uow.Referrals().CreateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *referralService) UpdateRule(ctx context.Context, id string, req *model.UpdateReferralRuleRequest) (_ *model.ReferralRule, err error) {
+	ctx, span := tracing.Start(ctx, "ReferralService.UpdateRule")
+	defer func() { tracing.End(span, err) }()
+
+	var rule *model.ReferralRule
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		rule, err = s.uow.Referrals().GetRule(ctx, id)
+		if err != nil {
+			return err
+		}
+		if req.Points != nil {
+			rule.Points = *req.Points
+		}
+		if req.Percent != nil {
+			rule.Percent = *req.Percent
+		}
+		if req.Active != nil {
+			rule.Active = *req.Active
+		}
+		return s.uow.Referrals().UpdateRule(ctx, rule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func generateReferralCode() (string, error) {
+	raw := make([]byte, referralCodeLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := make([]byte, referralCodeLength)
+	for i, b := range raw {
+		code[i] = referralCodeAlphabet[int(b)%len(referralCodeAlphabet)]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"denet/internal/apperror"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/tracing"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrSubmissionNotPending  = apperror.New("submission_not_pending", http.StatusConflict, "Task submission is not pending review")
+	ErrSubmissionNotApproved = apperror.New("submission_not_approved", http.StatusConflict, "Task submission is not approved")
+)
+
+// TaskReviewService is the moderator side of task verification: it works the
+// queue of submissions that verifiers left pending and revokes approvals.
+type TaskReviewService interface {
+	ListSubmissions(ctx context.Context, status model.TaskSubmissionStatus, cursor string, limit int) (*model.UserTaskPage, error)
+	Approve(ctx context.Context, id, reviewerID, note string) (*model.UserTask, error)
+	Reject(ctx context.Context, id, reviewerID, note string) (*model.UserTask, error)
+	// Revoke withdraws an approval and claws back the task points together
+	// with the referral earning shares paid on them. Points already spent
+	// are not taken back, see reverseLedgerEntry.
+	Revoke(ctx context.Context, id, reviewerID, note string) (*model.UserTask, error)
+}
+
+type taskReviewService struct {
+	uow       repository.UnitOfWork
+	referrals ReferralService
+}
+
+func NewTaskReviewService(uow repository.UnitOfWork, referrals ReferralService) TaskReviewService {
+	return &taskReviewService{uow: uow, referrals: referrals}
+}
+
+func (s *taskReviewService) ListSubmissions(ctx context.Context, status model.TaskSubmissionStatus, cursor string, limit int) (_ *model.UserTaskPage, err error) {
+	ctx, span := tracing.Start(ctx, "TaskReviewService.ListSubmissions")
+	defer func() { tracing.End(span, err) }()
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.uow.UserTasks().ListByStatus(ctx, status, cursor, limit)
+}
+
+func (s *taskReviewService) Approve(ctx context.Context, id, reviewerID, note string) (_ *model.UserTask, err error) {
+	ctx, span := tracing.Start(ctx, "TaskReviewService.Approve")
+	defer func() { tracing.End(span, err) }()
+
+	return s.review(ctx, id, reviewerID, note, model.TaskSubmissionPending, ErrSubmissionNotPending,
+		func(ctx context.Context, submission *model.UserTask) error {
+			submission.Status = model.TaskSubmissionApproved
+			task, err := s.uow.Tasks().GetByID(ctx, submission.TaskID)
+			if err != nil {
+				return err
+			}
+			return creditSubmission(ctx, s.uow, s.referrals, submission, task)
+		})
+}
+
+func (s *taskReviewService) Reject(ctx context.Context, id, reviewerID, note string) (_ *model.UserTask, err error) {
+	ctx, span := tracing.Start(ctx, "TaskReviewService.Reject")
+	defer func() { tracing.End(span, err) }()
+
+	return s.review(ctx, id, reviewerID, note, model.TaskSubmissionPending, ErrSubmissionNotPending,
+		func(ctx context.Context, submission *model.UserTask) error {
+			submission.Status = model.TaskSubmissionRejected
+			return nil
+		})
+}
+
+func (s *taskReviewService) Revoke(ctx context.Context, id, reviewerID, note string) (_ *model.UserTask, err error) {
+	ctx, span := tracing.Start(ctx, "TaskReviewService.Revoke")
+	defer func() { tracing.End(span, err) }()
+
+	return s.review(ctx, id, reviewerID, note, model.TaskSubmissionApproved, ErrSubmissionNotApproved,
+		func(ctx context.Context, submission *model.UserTask) error {
+			submission.Status = model.TaskSubmissionRevoked
+			return clawBackSubmission(ctx, s.uow, s.referrals, submission)
+		})
+}
+
+// review locks the submission, checks that it is in from and lets apply move
+// it on, recording the reviewer.
+func (s *taskReviewService) review(ctx context.Context, id, reviewerID, note string, from model.TaskSubmissionStatus, wrongState error,
+	apply func(ctx context.Context, submission *model.UserTask) error) (*model.UserTask, error) {
+	var submission *model.UserTask
+	err := s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		submission, err = s.uow.UserTasks().GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if submission.Status != from {
+			return wrongState
+		}
+		if err := apply(ctx, submission); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		submission.ReviewedBy = &reviewerID
+		submission.ReviewedAt = &now
+		submission.ReviewNote = nil
+		if note != "" {
+			submission.ReviewNote = &note
+		}
+		return s.uow.UserTasks().Update(ctx, submission)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return submission, nil
+}
+
+// taskLedgerKey identifies the ledger entry crediting a task to a user. It
+// predates verification, so approvals of tasks completed before it reuse it.
+func taskLedgerKey(userID, taskID string) string {
+	return "task:" + userID + ":" + taskID
+}
+
+// creditSubmission pays the task's points for an approved submission and the
+// referral shares on them. It must run inside the caller's transaction.
+func creditSubmission(ctx context.Context, uow repository.UnitOfWork, referrals ReferralService, submission *model.UserTask, task *model.Task) error {
+	key := taskLedgerKey(submission.UserID, task.ID)
+	_, err := uow.Ledger().Append(ctx, &model.PointTransaction{
+		UserID:         submission.UserID,
+		Delta:          task.Points,
+		Reason:         "task completed: " + task.Name,
+		SourceType:     model.PointSourceTask,
+		SourceID:       &task.ID,
+		IdempotencyKey: key,
+	})
+	if errors.Is(err, repository.ErrDuplicateTransaction) {
+		return ErrTaskAlreadyCompleted
+	}
+	if err != nil {
+		return err
+	}
+	return referrals.OnPointsEarned(ctx, submission.UserID, task.Points, key)
+}
+
+// clawBackSubmission reverses what creditSubmission paid. It must run inside
+// the caller's transaction.
+func clawBackSubmission(ctx context.Context, uow repository.UnitOfWork, referrals ReferralService, submission *model.UserTask) error {
+	key := taskLedgerKey(submission.UserID, submission.TaskID)
+	if err := reverseLedgerEntry(ctx, uow, key, "task-revoke:"+submission.UserID+":"+submission.TaskID, "task completion revoked"); err != nil {
+		return err
+	}
+	return referrals.OnPointsRevoked(ctx, key)
+}
+
+// reverseLedgerEntry appends the opposite of the entry recorded under key. A
+// debit takes back no more than the user still holds, so that balances never
+// go negative: points already spent, on rewards for instance, stay spent and
+// the entry's reason records how many. A missing entry or one that was
+// already reversed is not an error.
+func reverseLedgerEntry(ctx context.Context, uow repository.UnitOfWork, key, reverseKey, reason string) error {
+	original, err := uow.Ledger().GetByIdempotencyKey(ctx, key)
+	if errors.Is(err, repository.ErrTransactionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	delta := -original.Delta
+	if delta < 0 {
+		user, err := uow.Users().GetByIDForUpdate(ctx, original.UserID)
+		if err != nil {
+			return err
+		}
+		if user.Balance < -delta {
+			reason = fmt.Sprintf("%s (%d points already spent)", reason, -delta-user.Balance)
+			delta = -user.Balance
+		}
+	}
+	_, err = uow.Ledger().Append(ctx, &model.PointTransaction{
+		UserID:         original.UserID,
+		Delta:          delta,
+		Reason:         reason,
+		SourceType:     original.SourceType,
+		SourceID:       original.SourceID,
+		IdempotencyKey: reverseKey,
+	})
+	if err != nil && !errors.Is(err, repository.ErrDuplicateTransaction) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"denet/internal/model"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newTestReviewService returns a review service over a store in which alice
+// submitted the follow task, worth 50 points, with the given status.
+func newTestReviewService(status model.TaskSubmissionStatus) (*taskReviewService, *fakeStore, *fakeReferrals, string) {
+	uow := newFakeStore()
+	uow.users["alice"] = model.User{ID: "alice", Username: "alice"}
+	uow.tasks["follow"] = model.Task{ID: "follow", Name: "Follow us", Points: 50}
+	submission := &model.UserTask{UserID: "alice", TaskID: "follow", Status: status}
+	uow.UserTasks().Create(context.Background(), submission)
+	if status == model.TaskSubmissionApproved {
+		task := uow.tasks["follow"]
+		creditSubmission(context.Background(), uow, &fakeReferrals{}, submission, &task)
+	}
+	referrals := &fakeReferrals{}
+	return &taskReviewService{uow: uow, referrals: referrals}, uow, referrals, submission.ID
+}
+
+func TestReviewApproveAndReject(t *testing.T) {
+	ctx := context.Background()
+
+	s, uow, referrals, id := newTestReviewService(model.TaskSubmissionPending)
+	approved, err := s.Approve(ctx, id, "mod", "looks good")
+	if err != nil {
+		t.Fatalf("Approve() = %v", err)
+	}
+	if approved.Status != model.TaskSubmissionApproved || approved.ReviewedBy == nil || *approved.ReviewedBy != "mod" ||
+		approved.ReviewNote == nil || *approved.ReviewNote != "looks good" {
+		t.Errorf("approved submission = %+v, want approved by mod with the note", approved)
+	}
+	if balance, err := uow.balance("alice"); err != nil || balance != 50 {
+		t.Errorf("balance after approval = %d (%v), want 50", balance, err)
+	}
+	if len(referrals.earned) != 1 {
+		t.Errorf("referral earnings paid %d times, want once", len(referrals.earned))
+	}
+	if _, err := s.Approve(ctx, id, "mod", ""); !errors.Is(err, ErrSubmissionNotPending) {
+		t.Errorf("second Approve() = %v, want %v", err, ErrSubmissionNotPending)
+	}
+
+	s, uow, _, id = newTestReviewService(model.TaskSubmissionPending)
+	rejected, err := s.Reject(ctx, id, "mod", "no proof")
+	if err != nil {
+		t.Fatalf("Reject() = %v", err)
+	}
+	if rejected.Status != model.TaskSubmissionRejected || len(uow.ledger) != 0 {
+		t.Errorf("rejected submission = %+v with %d ledger entries, want rejected and nothing paid", rejected, len(uow.ledger))
+	}
+	if _, err := s.Revoke(ctx, id, "mod", ""); !errors.Is(err, ErrSubmissionNotApproved) {
+		t.Errorf("Revoke() of a rejected submission = %v, want %v", err, ErrSubmissionNotApproved)
+	}
+}
+
+func TestReviewRevokeClawsBack(t *testing.T) {
+	tests := []struct {
+		name        string
+		spent       int
+		wantBalance int
+		wantDelta   int
+	}{
+		{"points unspent", 0, 0, -50},
+		{"points partly spent", 30, 0, -20},
+		{"points all spent", 50, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, uow, referrals, id := newTestReviewService(model.TaskSubmissionApproved)
+			if tt.spent > 0 {
+				uow.Ledger().Append(ctx, &model.PointTransaction{UserID: "alice", Delta: -tt.spent,
+					SourceType: model.PointSourceRedemption, IdempotencyKey: "redemption:1"})
+			}
+
+			revoked, err := s.Revoke(ctx, id, "mod", "fake account")
+			if err != nil {
+				t.Fatalf("Revoke() = %v", err)
+			}
+			if revoked.Status != model.TaskSubmissionRevoked {
+				t.Errorf("status = %s, want revoked", revoked.Status)
+			}
+			if balance, err := uow.balance("alice"); err != nil || balance != tt.wantBalance {
+				t.Errorf("balance = %d (%v), want %d", balance, err, tt.wantBalance)
+			}
+			reversal := uow.ledger[len(uow.ledger)-1]
+			if reversal.IdempotencyKey != "task-revoke:alice:follow" || reversal.Delta != tt.wantDelta {
+				t.Errorf("reversal = %+v, want %d under the revoke key", reversal, tt.wantDelta)
+			}
+			if spentNote := tt.spent > 0; strings.Contains(reversal.Reason, "already spent") != spentNote {
+				t.Errorf("reversal reason = %q, want spent points noted: %v", reversal.Reason, spentNote)
+			}
+			if len(referrals.revoked) != 1 || referrals.revoked[0] != "task:alice:follow" {
+				t.Errorf("referral shares revoked for %v, want the task entry", referrals.revoked)
+			}
+		})
+	}
+}
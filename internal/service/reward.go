@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"denet/internal/apperror"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/tracing"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrRewardUnavailable     = apperror.New("reward_unavailable", http.StatusConflict, "Reward is not available")
+	ErrRewardOutOfStock      = apperror.New("reward_out_of_stock", http.StatusConflict, "Reward is out of stock")
+	ErrRewardLimitReached    = apperror.New("reward_limit_reached", http.StatusConflict, "Redemption limit for this reward reached")
+	ErrRewardOrderNotPending = apperror.New("reward_order_not_pending", http.StatusConflict, "Reward order is not pending")
+	ErrInvalidRewardWindow   = apperror.New("invalid_reward_window", http.StatusBadRequest, "available_until must be after available_from")
+)
+
+type RewardService interface {
+	// ListAvailable returns the rewards that can be redeemed now, including
+	// sold out ones.
+	ListAvailable(ctx context.Context) ([]model.Reward, error)
+	List(ctx context.Context, filter model.RewardFilter) ([]model.Reward, error)
+	GetReward(ctx context.Context, id string) (*model.Reward, error)
+	CreateReward(ctx context.Context, req *model.CreateRewardRequest) (*model.Reward, error)
+	UpdateReward(ctx context.Context, id string, req *model.UpdateRewardRequest) (*model.Reward, error)
+	ArchiveReward(ctx context.Context, id string) (*model.Reward, error)
+
+	// Redeem debits the reward's cost from the user and reserves one item of
+	// stock in a single transaction, leaving a pending order.
+	Redeem(ctx context.Context, userID, rewardID string) (*model.RewardOrder, error)
+	ListUserOrders(ctx context.Context, userID, cursor string, limit int) (*model.RewardOrderPage, error)
+	ListOrders(ctx context.Context, status model.RewardOrderStatus, cursor string, limit int) (*model.RewardOrderPage, error)
+	Fulfil(ctx context.Context, orderID, actorID, note string) (*model.RewardOrder, error)
+	// Cancel refunds a pending order and puts its item back in stock.
+	Cancel(ctx context.Context, orderID, actorID, note string) (*model.RewardOrder, error)
+}
+
+type rewardService struct {
+	uow repository.UnitOfWork
+}
+
+func NewRewardService(uow repository.UnitOfWork) RewardService {
+	return &rewardService{uow: uow}
+}
+
+func (s *rewardService) ListAvailable(ctx context.Context) (_ []model.Reward, err error) {
+	ctx, span := tracing.Start(ctx, "RewardService.ListAvailable")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now().UTC()
+	return s.uow.Rewards().List(ctx, model.RewardFilter{AvailableAt: &now})
+}
+
+func (s *rewardService) List(ctx context.Context, filter model.RewardFilter) (_ []model.Reward, err error) {
+	ctx, span := tracing.Start(ctx, "RewardService.List")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Rewards().List(ctx, filter)
+}
+
+func (s *rewardService) GetReward(ctx context.Context, id string) (_ *model.Reward, err error) {
+	ctx, span := tracing.Start(ctx, "RewardService.GetReward")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Rewards().GetByID(ctx, id)
+}
+
+func (s *rewardService) CreateReward(ctx context.Context, req *model.CreateRewardRequest) (_ *model.Reward, err error) {
+	ctx, span := tracing.Start(ctx, "RewardService.CreateReward")
+	defer func() { tracing.End(span, err) }()
+
+	reward := &model.Reward{
+		Name:           req.Name,
+		Description:    req.Description,
+		Cost:           req.Cost,
+		Stock:          req.Stock,
+		PerUserLimit:   req.PerUserLimit,
+		AvailableFrom:  req.AvailableFrom,
+		AvailableUntil: req.AvailableUntil,
+	}
+	if err := checkRewardWindow(reward); err != nil {
+		return nil, err
+	}
+	if err := s.uow.Rewards().Create(ctx, reward); err != nil {
+		return nil, err
+	}
+	return reward, nil
+}
+
+func (s *rewardService) UpdateReward(ctx context.Context, id string, req *model.UpdateRewardRequest) (_ *model.Reward, err error) {
+	ctx, span := tracing.Start(ctx, "RewardService.UpdateReward")
+	defer func() { tracing.End(span, err) }()
+
+	var reward *model.Reward
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		reward, err = s.uow.Rewards().GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if req.Name != nil {
+			reward.Name = *req.Name
+		}
+		if req.Description != nil {
+			reward.Description = *req.Description
+		}
+		if req.Cost != nil {
+			reward.Cost = *req.Cost
+		}
+		if req.ClearStock {
+			reward.Stock = nil
+		} else if req.Stock != nil {
+			reward.Stock = req.Stock
+		}
+		if req.ClearPerUserLimit {
+			reward.PerUserLimit = nil
+		} else if req.PerUserLimit != nil {
+			reward.PerUserLimit = req.PerUserLimit
+		}
+		if req.ClearWindow {
+			reward.AvailableFrom, reward.AvailableUntil = nil, nil
+		}
+		if req.AvailableFrom != nil {
+			reward.AvailableFrom = req.AvailableFrom
+		}
+		if req.AvailableUntil != nil {
+			reward.AvailableUntil = req.AvailableUntil
+		}
+		if err := checkRewardWindow(reward); err != nil {
+			return err
+		}
+		return s.uow.Rewards().Update(ctx, reward)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reward, nil
+}
+
+func (s *rewardService) ArchiveReward(ctx context.Context, id string) (_ *model.Reward, err error) {
+	ctx, span := tracing.Start(ctx, "RewardService.ArchiveReward")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Rewards().Archive(ctx, id)
+}
+
+func (s *rewardService) Redeem(ctx context.Context, userID, rewardID string) (_ *model.RewardOrder, err error) {
+	ctx, span := tracing.Start(ctx, "RewardService.Redeem")
+	defer func() { tracing.End(span, err) }()
+
+	var order *model.RewardOrder
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		// The reward is locked before the user, the same order Cancel takes
+		// them in.
+		reward, err := s.uow.Rewards().GetByIDForUpdate(ctx, rewardID)
+		if err != nil {
+			return err
+		}
+		if !reward.IsAvailable(time.Now().UTC()) {
+			return ErrRewardUnavailable
+		}
+		if reward.Stock != nil && *reward.Stock == 0 {
+			return ErrRewardOutOfStock
+		}
+		if reward.PerUserLimit != nil {
+			count, err := s.uow.Rewards().CountActiveOrders(ctx, userID, rewardID)
+			if err != nil {
+				return err
+			}
+			if count >= *reward.PerUserLimit {
+				return ErrRewardLimitReached
+			}
+		}
+		user, err := s.uow.Users().GetByIDForUpdate(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.Balance < reward.Cost {
+			return repository.ErrInsufficientBalance
+		}
+
+		if err := s.uow.Rewards().AdjustStock(ctx, rewardID, -1); err != nil {
+			return err
+		}
+		order = &model.RewardOrder{
+			UserID:   userID,
+			RewardID: rewardID,
+			Cost:     reward.Cost,
+			Status:   model.RewardOrderPending,
+		}
+		if err := s.uow.Rewards().CreateOrder(ctx, order); err != nil {
+			return err
+		}
+		_, err = s.uow.Ledger().Append(ctx, &model.PointTransaction{
+			UserID:         userID,
+			Delta:          -reward.Cost,
+			Reason:         "redeemed " + reward.Name,
+			SourceType:     model.PointSourceRedemption,
+			SourceID:       &order.ID,
+			IdempotencyKey: "redemption:" + order.ID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *rewardService) ListUserOrders(ctx context.Context, userID, cursor string, limit int) (_ *model.RewardOrderPage, err error) {
+	ctx, span := tracing.Start(ctx, "RewardService.ListUserOrders")
+	defer func() { tracing.End(span, err) }()
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.uow.Rewards().ListOrdersByUser(ctx, userID, cursor, limit)
+}
+
+func (s *rewardService) ListOrders(ctx context.Context, status model.RewardOrderStatus, cursor string, limit int) (_ *model.RewardOrderPage, err error) {
+	ctx, span := tracing.Start(ctx, "RewardService.ListOrders")
+	defer func() { tracing.End(span, err) }()
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.uow.Rewards().ListOrdersByStatus(ctx, status, cursor, limit)
+}
+
+func (s *rewardService) Fulfil(ctx context.Context, orderID, actorID, note string) (_ *model.RewardOrder, err error) {
+	ctx, span := tracing.Start(ctx, "RewardService.Fulfil")
+	defer func() { tracing.End(span, err) }()
+
+	return s.handleOrder(ctx, orderID, actorID, note, func(ctx context.Context, order *model.RewardOrder) error {
+		order.Status = model.RewardOrderFulfilled
+		return nil
+	})
+}
+
+func (s *rewardService) Cancel(ctx context.Context, orderID, actorID, note string) (_ *model.RewardOrder, err error) {
+	ctx, span := tracing.Start(ctx, "RewardService.Cancel")
+	defer func() { tracing.End(span, err) }()
+
+	return s.handleOrder(ctx, orderID, actorID, note, func(ctx context.Context, order *model.RewardOrder) error {
+		order.Status = model.RewardOrderCancelled
+		if _, err := s.uow.Rewards().GetByIDForUpdate(ctx, order.RewardID); err != nil {
+			return err
+		}
+		if err := s.uow.Rewards().AdjustStock(ctx, order.RewardID, 1); err != nil {
+			return err
+		}
+		_, err := s.uow.Ledger().Append(ctx, &model.PointTransaction{
+			UserID:         order.UserID,
+			Delta:          order.Cost,
+			Reason:         "reward order cancelled",
+			SourceType:     model.PointSourceRedemption,
+			SourceID:       &order.ID,
+			IdempotencyKey: "redemption-refund:" + order.ID,
+		})
+		return err
+	})
+}
+
+// handleOrder locks a pending order and lets apply settle it, recording who
+// did.
+func (s *rewardService) handleOrder(ctx context.Context, orderID, actorID, note string,
+	apply func(ctx context.Context, order *model.RewardOrder) error) (*model.RewardOrder, error) {
+	var order *model.RewardOrder
+	err := s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		order, err = s.uow.Rewards().GetOrderForUpdate(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if order.Status != model.RewardOrderPending {
+			return ErrRewardOrderNotPending
+		}
+		if err := apply(ctx, order); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		order.HandledBy = &actorID
+		order.HandledAt = &now
+		if note != "" {
+			order.Note = &note
+		}
+		return s.uow.Rewards().UpdateOrder(ctx, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func checkRewardWindow(reward *model.Reward) error {
+	if reward.AvailableFrom != nil && reward.AvailableUntil != nil && !reward.AvailableUntil.After(*reward.AvailableFrom) {
+		return ErrInvalidRewardWindow
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestRewardService returns a reward service over a store in which alice
+// holds balance points and the mug costs 100 with stock left.
+func newTestRewardService(balance, stock int) (*rewardService, *fakeStore) {
+	uow := newFakeStore()
+	uow.users["alice"] = model.User{ID: "alice", Username: "alice"}
+	if balance > 0 {
+		uow.Ledger().Append(context.Background(), &model.PointTransaction{UserID: "alice", Delta: balance,
+			SourceType: model.PointSourceAdmin, IdempotencyKey: "grant"})
+	}
+	limit := 2
+	uow.rewards["mug"] = model.Reward{ID: "mug", Name: "Mug", Cost: 100, Stock: &stock, PerUserLimit: &limit}
+	return &rewardService{uow: uow}, uow
+}
+
+func TestRedeem(t *testing.T) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name    string
+		balance int
+		stock   int
+		prepare func(uow *fakeStore)
+		wantErr error
+	}{
+		{"redeemed", 250, 5, nil, nil},
+		{"not enough points", 99, 5, nil, repository.ErrInsufficientBalance},
+		{"out of stock", 250, 0, nil, ErrRewardOutOfStock},
+		{"limit reached", 500, 5, func(uow *fakeStore) {
+			uow.orders["o1"] = model.RewardOrder{ID: "o1", UserID: "alice", RewardID: "mug", Status: model.RewardOrderFulfilled}
+			uow.orders["o2"] = model.RewardOrder{ID: "o2", UserID: "alice", RewardID: "mug", Status: model.RewardOrderPending}
+		}, ErrRewardLimitReached},
+		{"archived", 250, 5, func(uow *fakeStore) {
+			reward := uow.rewards["mug"]
+			reward.ArchivedAt = &past
+			uow.rewards["mug"] = reward
+		}, ErrRewardUnavailable},
+		{"unknown reward", 250, 5, func(uow *fakeStore) { delete(uow.rewards, "mug") }, repository.ErrRewardNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, uow := newTestRewardService(tt.balance, tt.stock)
+			if tt.prepare != nil {
+				tt.prepare(uow)
+			}
+			orders := len(uow.orders)
+
+			order, err := s.Redeem(ctx, "alice", "mug")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Redeem() = %v, want %v", err, tt.wantErr)
+			}
+
+			balance, ledgerErr := uow.balance("alice")
+			if ledgerErr != nil {
+				t.Error(ledgerErr)
+			}
+			if tt.wantErr != nil {
+				// Nothing of a refused redemption is kept.
+				if balance != tt.balance || len(uow.orders) != orders {
+					t.Errorf("balance %d with %d orders after a refused redemption, want %d with %d", balance, len(uow.orders), tt.balance, orders)
+				}
+				if reward, ok := uow.rewards["mug"]; ok && *reward.Stock != tt.stock {
+					t.Errorf("stock = %d, want %d", *reward.Stock, tt.stock)
+				}
+				return
+			}
+			if order.Status != model.RewardOrderPending || order.Cost != 100 {
+				t.Errorf("order = %+v, want a pending order costing 100", order)
+			}
+			if balance != tt.balance-100 || *uow.rewards["mug"].Stock != tt.stock-1 {
+				t.Errorf("balance %d and stock %d, want %d and %d", balance, *uow.rewards["mug"].Stock, tt.balance-100, tt.stock-1)
+			}
+		})
+	}
+}
+
+func TestCancelRefunds(t *testing.T) {
+	ctx := context.Background()
+	s, uow := newTestRewardService(250, 5)
+	order, err := s.Redeem(ctx, "alice", "mug")
+	if err != nil {
+		t.Fatalf("Redeem() = %v", err)
+	}
+
+	cancelled, err := s.Cancel(ctx, order.ID, "admin", "out of mugs")
+	if err != nil {
+		t.Fatalf("Cancel() = %v", err)
+	}
+	if cancelled.Status != model.RewardOrderCancelled || cancelled.HandledBy == nil || *cancelled.HandledBy != "admin" {
+		t.Errorf("cancelled order = %+v, want cancelled by admin", cancelled)
+	}
+	if balance, err := uow.balance("alice"); err != nil || balance != 250 {
+		t.Errorf("balance = %d (%v), want the cost refunded to 250", balance, err)
+	}
+	if stock := *uow.rewards["mug"].Stock; stock != 5 {
+		t.Errorf("stock = %d, want the item back at 5", stock)
+	}
+
+	for _, settle := range []func(context.Context, string, string, string) (*model.RewardOrder, error){s.Cancel, s.Fulfil} {
+		if _, err := settle(ctx, order.ID, "admin", ""); !errors.Is(err, ErrRewardOrderNotPending) {
+			t.Errorf("settling a cancelled order = %v, want %v", err, ErrRewardOrderNotPending)
+		}
+	}
+	if balance, _ := uow.balance("alice"); balance != 250 {
+		t.Errorf("balance = %d after settling again, want 250", balance)
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"denet/internal/apperror"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/tracing"
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrCannotRevokeOwnAdmin = apperror.New("cannot_revoke_own_admin", http.StatusBadRequest, "Cannot revoke own admin role")
+)
+
+type RoleService interface {
+	ListRoles(ctx context.Context) ([]model.Role, error)
+	CreateRole(ctx context.Context, req *model.CreateRoleRequest) (*model.Role, error)
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	GrantRole(ctx context.Context, actorID, userID string, req *model.GrantRoleRequest) error
+	RevokeRole(ctx context.Context, actorID, userID, roleName, reason string) error
+	GetAuditLog(ctx context.Context, userID string) ([]model.RoleAuditEntry, error)
+	// EnsureAdmins grants the admin role to the given users if they exist.
+	// It is used to bootstrap the first administrators from configuration.
+	EnsureAdmins(ctx context.Context, userIDs []string) error
+}
+
+type roleService struct {
+	uow repository.UnitOfWork
+}
+
+func NewRoleService(uow repository.UnitOfWork) RoleService {
+	return &roleService{uow: uow}
+}
+
+func (s *roleService) ListRoles(ctx context.Context) (_ []model.Role, err error) {
+	ctx, span := tracing.Start(ctx, "RoleService.ListRoles")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Roles().List(ctx)
+}
+
+func (s *roleService) CreateRole(ctx context.Context, req *model.CreateRoleRequest) (_ *model.Role, err error) {
+	ctx, span := tracing.Start(ctx, "RoleService.CreateRole")
+	defer func() { tracing.End(span, err) }()
+
+	role := &model.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		return s.uow.Roles().Create(ctx, role)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *roleService) GetUserRoles(ctx context.Context, userID string) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "RoleService.GetUserRoles")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.uow.Users().GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.uow.Roles().GetUserRoles(ctx, userID)
+}
+
+func (s *roleService) GrantRole(ctx context.Context, actorID, userID string, req *model.GrantRoleRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "RoleService.GrantRole")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.uow.Users().GetByID(ctx, userID); err != nil {
+			return err
+		}
+		if _, err := s.uow.Roles().GetByName(ctx, req.Role); err != nil {
+			return err
+		}
+		if err := s.uow.Roles().Grant(ctx, userID, req.Role, &actorID); err != nil {
+			return err
+		}
+		return s.uow.Roles().AddAuditEntry(ctx, &model.RoleAuditEntry{
+			UserID:   userID,
+			RoleName: req.Role,
+			Action:   model.RoleActionGrant,
+			ActorID:  &actorID,
+			Reason:   req.Reason,
+		})
+	})
+}
+
+func (s *roleService) RevokeRole(ctx context.Context, actorID, userID, roleName, reason string) (err error) {
+	ctx, span := tracing.Start(ctx, "RoleService.RevokeRole")
+	defer func() { tracing.End(span, err) }()
+
+	if actorID == userID && roleName == model.RoleAdmin {
+		return ErrCannotRevokeOwnAdmin
+	}
+	return s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.uow.Roles().Revoke(ctx, userID, roleName); err != nil {
+			return err
+		}
+		return s.uow.Roles().AddAuditEntry(ctx, &model.RoleAuditEntry{
+			UserID:   userID,
+			RoleName: roleName,
+			Action:   model.RoleActionRevoke,
+			ActorID:  &actorID,
+			Reason:   reason,
+		})
+	})
+}
+
+func (s *roleService) GetAuditLog(ctx context.Context, userID string) (_ []model.RoleAuditEntry, err error) {
+	ctx, span := tracing.Start(ctx, "RoleService.GetAuditLog")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Roles().ListAuditEntries(ctx, userID)
+}
+
+func (s *roleService) EnsureAdmins(ctx context.Context, userIDs []string) (err error) {
+	ctx, span := tracing.Start(ctx, "RoleService.EnsureAdmins")
+	defer func() { tracing.End(span, err) }()
+
+	for _, userID := range userIDs {
+		err := s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+			if _, err := s.uow.Users().GetByID(ctx, userID); err != nil {
+				return err
+			}
+			if err := s.uow.Roles().Grant(ctx, userID, model.RoleAdmin, nil); err != nil {
+				return err
+			}
+			return s.uow.Roles().AddAuditEntry(ctx, &model.RoleAuditEntry{
+				UserID:   userID,
+				RoleName: model.RoleAdmin,
+				Action:   model.RoleActionGrant,
+				Reason:   "bootstrap from configuration",
+			})
+		})
+		if err != nil && !errors.Is(err, repository.ErrRoleAlreadyGranted) {
+			return err
+		}
+	}
+	return nil
+}
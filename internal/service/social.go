@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"denet/internal/apperror"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/tracing"
+	"net/http"
+)
+
+var (
+	ErrUnknownSocialProvider = apperror.New("unknown_social_provider", http.StatusBadRequest, "Unknown social provider")
+	ErrTelegramLinkViaWidget = apperror.New("telegram_link_via_widget", http.StatusBadRequest, "Link Telegram through the Telegram login widget")
+)
+
+type SocialService interface {
+	ListAccounts(ctx context.Context, userID string) ([]model.SocialAccount, error)
+	LinkAccount(ctx context.Context, userID string, provider model.SocialProvider, req *model.LinkSocialAccountRequest) (*model.SocialAccount, error)
+	UnlinkAccount(ctx context.Context, userID string, provider model.SocialProvider) error
+}
+
+type socialService struct {
+	uow repository.UnitOfWork
+}
+
+func NewSocialService(uow repository.UnitOfWork) SocialService {
+	return &socialService{uow: uow}
+}
+
+func (s *socialService) ListAccounts(ctx context.Context, userID string) (_ []model.SocialAccount, err error) {
+	ctx, span := tracing.Start(ctx, "SocialService.ListAccounts")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.uow.Users().GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.uow.SocialAccounts().ListByUser(ctx, userID)
+}
+
+func (s *socialService) LinkAccount(ctx context.Context, userID string, provider model.SocialProvider, req *model.LinkSocialAccountRequest) (_ *model.SocialAccount, err error) {
+	ctx, span := tracing.Start(ctx, "SocialService.LinkAccount")
+	defer func() { tracing.End(span, err) }()
+
+	if !knownSocialProvider(provider) {
+		return nil, ErrUnknownSocialProvider
+	}
+	// A Telegram account signs its owner in, so it is only linked once the
+	// widget proved the user holds it.
+	if provider == model.SocialTelegram {
+		return nil, ErrTelegramLinkViaWidget
+	}
+	// Nothing proves the user holds an account linked by ID, so it stays
+	// unverified and its tasks go to manual review.
+	account := &model.SocialAccount{
+		UserID:     userID,
+		Provider:   provider,
+		ExternalID: req.ExternalID,
+		Handle:     req.Handle,
+	}
+	if err := s.uow.SocialAccounts().Link(ctx, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (s *socialService) UnlinkAccount(ctx context.Context, userID string, provider model.SocialProvider) (err error) {
+	ctx, span := tracing.Start(ctx, "SocialService.UnlinkAccount")
+	defer func() { tracing.End(span, err) }()
+
+	if !knownSocialProvider(provider) {
+		return ErrUnknownSocialProvider
+	}
+	return s.uow.SocialAccounts().Unlink(ctx, userID, provider)
+}
+
+func knownSocialProvider(provider model.SocialProvider) bool {
+	for _, known := range model.SocialProviders {
+		if provider == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"denet/internal/apperror"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/tracing"
+	"denet/internal/verifier"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownVerificationType   = apperror.New("unknown_verification_type", http.StatusBadRequest, "Unknown verification type")
+	ErrInvalidVerificationConfig = apperror.New("invalid_verification_config", http.StatusBadRequest, "Verification config must be a JSON object")
+)
+
+type TaskService interface {
+	ListAvailable(ctx context.Context) ([]model.Task, error)
+	List(ctx context.Context, filter model.TaskFilter) ([]model.Task, error)
+	GetTask(ctx context.Context, id string) (*model.Task, error)
+	CreateTask(ctx context.Context, req *model.CreateTaskRequest) (*model.Task, error)
+	UpdateTask(ctx context.Context, id string, req *model.UpdateTaskRequest) (*model.Task, error)
+	ArchiveTask(ctx context.Context, id string) (*model.Task, error)
+}
+
+type taskService struct {
+	uow       repository.UnitOfWork
+	verifiers *verifier.Registry
+}
+
+func NewTaskService(uow repository.UnitOfWork, verifiers *verifier.Registry) TaskService {
+	return &taskService{uow: uow, verifiers: verifiers}
+}
+
+func (s *taskService) ListAvailable(ctx context.Context) (_ []model.Task, err error) {
+	ctx, span := tracing.Start(ctx, "TaskService.ListAvailable")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Tasks().GetAll(ctx)
+}
+
+func (s *taskService) List(ctx context.Context, filter model.TaskFilter) (_ []model.Task, err error) {
+	ctx, span := tracing.Start(ctx, "TaskService.List")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Tasks().List(ctx, filter)
+}
+
+func (s *taskService) GetTask(ctx context.Context, id string) (_ *model.Task, err error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetTask")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Tasks().GetByID(ctx, id)
+}
+
+func (s *taskService) CreateTask(ctx context.Context, req *model.CreateTaskRequest) (_ *model.Task, err error) {
+	ctx, span := tracing.Start(ctx, "TaskService.CreateTask")
+	defer func() { tracing.End(span, err) }()
+
+	publishedAt := req.PublishedAt
+	if publishedAt == nil {
+		now := time.Now().UTC()
+		publishedAt = &now
+	}
+	task := &model.Task{
+		Name:               req.Name,
+		Description:        req.Description,
+		Points:             req.Points,
+		PublishedAt:        publishedAt,
+		VerificationType:   req.VerificationType,
+		VerificationConfig: req.VerificationConfig,
+	}
+	if task.VerificationType == "" {
+		task.VerificationType = model.VerificationAuto
+	}
+	if err := s.checkVerification(task); err != nil {
+		return nil, err
+	}
+	if err := s.uow.Tasks().Create(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *taskService) UpdateTask(ctx context.Context, id string, req *model.UpdateTaskRequest) (_ *model.Task, err error) {
+	ctx, span := tracing.Start(ctx, "TaskService.UpdateTask")
+	defer func() { tracing.End(span, err) }()
+
+	var task *model.Task
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		task, err = s.uow.Tasks().GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if req.Name != nil {
+			task.Name = *req.Name
+		}
+		if req.Description != nil {
+			task.Description = *req.Description
+		}
+		if req.Points != nil {
+			task.Points = *req.Points
+		}
+		if req.PublishedAt != nil {
+			task.PublishedAt = req.PublishedAt
+		}
+		if req.VerificationType != nil {
+			task.VerificationType = *req.VerificationType
+		}
+		if req.VerificationConfig != nil {
+			task.VerificationConfig = req.VerificationConfig
+		}
+		if err := s.checkVerification(task); err != nil {
+			return err
+		}
+		return s.uow.Tasks().Update(ctx, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *taskService) ArchiveTask(ctx context.Context, id string) (_ *model.Task, err error) {
+	ctx, span := tracing.Start(ctx, "TaskService.ArchiveTask")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Tasks().Archive(ctx, id)
+}
+
+// checkVerification rejects verification types nobody registered and
+// normalizes an empty config to an empty object.
+func (s *taskService) checkVerification(task *model.Task) error {
+	if _, ok := s.verifiers.Get(task.VerificationType); !ok {
+		return ErrUnknownVerificationType.WithDetails("known types: " + strings.Join(s.verifiers.Kinds(), ", "))
+	}
+	if len(task.VerificationConfig) == 0 || string(task.VerificationConfig) == "null" {
+		task.VerificationConfig = json.RawMessage("{}")
+		return nil
+	}
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(task.VerificationConfig, &config); err != nil {
+		return ErrInvalidVerificationConfig
+	}
+	return nil
+}
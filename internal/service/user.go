@@ -3,58 +3,178 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"denet/internal/apperror"
 	"denet/internal/model"
 	"denet/internal/repository"
+	"denet/internal/tracing"
+	"denet/internal/verifier"
+	"time"
+)
+
+var (
+	ErrTaskAlreadyCompleted  = apperror.New("task_already_completed", http.StatusConflict, "Task already completed")
+	ErrTaskAlreadySubmitted  = apperror.New("task_already_submitted", http.StatusConflict, "Task is already pending review")
+	ErrTaskCompletionRevoked = apperror.New("task_completion_revoked", http.StatusConflict, "Task completion was revoked")
+	ErrTaskRejected          = apperror.New("task_rejected", http.StatusUnprocessableEntity, "Task verification failed")
 )
 
 type UserService interface {
-	CompleteTask(ctx context.Context, userID, taskID string) error
-	SetReferrer(ctx context.Context, userID, referrerID string) error
+	// CompleteTask submits the task for verification and credits its points
+	// if the task's verifier approves it right away. A rejection is recorded,
+	// so the task can be submitted again, and returned as ErrTaskRejected
+	// carrying the verifier's note.
+	CompleteTask(ctx context.Context, userID, taskID, proof string) (*model.UserTask, error)
+	SetReferrer(ctx context.Context, userID string, req *model.SetReferrerRequest) error
 	GetUserStatus(ctx context.Context, userID string) (*model.UserStatus, error)
-	GetLeaderboard(ctx context.Context, limit int) ([]model.LeaderboardUser, error)
+	GetLeaderboard(ctx context.Context, cursor string, limit int) (*model.LeaderboardPage, error)
+	GetRank(ctx context.Context, userID string, neighbours int) (*model.UserRank, error)
+	GetTransactions(ctx context.Context, userID, cursor string, limit int) (*model.PointTransactionPage, error)
 }
 
 type userService struct {
-	uow repository.UnitOfWork
+	uow       repository.UnitOfWork
+	referrals ReferralService
+	verifiers *verifier.Registry
 }
 
-func NewUserService(uow repository.UnitOfWork) UserService {
-	return &userService{uow: uow}
+func NewUserService(uow repository.UnitOfWork, referrals ReferralService, verifiers *verifier.Registry) UserService {
+	return &userService{uow: uow, referrals: referrals, verifiers: verifiers}
 }
 
-func (s *userService) CompleteTask(ctx context.Context, userID, taskID string) error {
-	user, err := s.uow.Users().GetByID(ctx, userID)
-	if err != nil {
-		return err
+func (s *userService) CompleteTask(ctx context.Context, userID, taskID, proof string) (_ *model.UserTask, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.CompleteTask")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.uow.Users().GetByID(ctx, userID); err != nil {
+		return nil, err
 	}
 	task, err := s.uow.Tasks().GetByID(ctx, taskID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if !task.IsAvailable(time.Now()) {
+		return nil, repository.ErrTaskNotFound
 	}
-	completed, err := s.uow.UserTasks().IsTaskCompleted(ctx, userID, taskID)
+	taskVerifier, ok := s.verifiers.Get(task.VerificationType)
+	if !ok {
+		return nil, fmt.Errorf("task %s: unknown verification type %q", task.ID, task.VerificationType)
+	}
+
+	// Fail fast before asking the verifier, which may call out to another
+	// service; the state is checked again under lock below.
+	existing, err := s.uow.UserTasks().Get(ctx, userID, taskID)
+	if err != nil && !errors.Is(err, repository.ErrUserTaskNotFound) {
+		return nil, err
+	}
+	if err := checkResubmission(existing); err != nil {
+		return nil, err
+	}
+
+	verifyCtx, verifySpan := tracing.Start(ctx, "verifier."+task.VerificationType)
+	verdict, err := taskVerifier.Verify(verifyCtx, verifier.Submission{UserID: userID, Task: task, Proof: proof})
+	tracing.End(verifySpan, err)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if completed {
-		return errors.New("task already completed")
+	switch verdict.Status {
+	case model.TaskSubmissionPending, model.TaskSubmissionApproved, model.TaskSubmissionRejected:
+	default:
+		return nil, fmt.Errorf("task %s: verifier returned status %q", task.ID, verdict.Status)
 	}
-	return s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
-		if err := s.uow.UserTasks().CompleteTask(ctx, userID, taskID); err != nil {
+
+	var submission *model.UserTask
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		existing, err := s.uow.UserTasks().GetForUpdate(ctx, userID, taskID)
+		if err != nil && !errors.Is(err, repository.ErrUserTaskNotFound) {
+			return err
+		}
+		if err := checkResubmission(existing); err != nil {
 			return err
 		}
-		newBalance := user.Balance + task.Points
-		return s.uow.Users().UpdateBalance(ctx, userID, newBalance)
+
+		submission = &model.UserTask{UserID: userID, TaskID: taskID}
+		if existing != nil {
+			submission = existing
+		}
+		submission.Status = verdict.Status
+		submission.Proof = nil
+		if proof != "" {
+			submission.Proof = &proof
+		}
+		submission.ReviewNote = nil
+		if verdict.Note != "" {
+			submission.ReviewNote = &verdict.Note
+		}
+		// Automatic decisions carry no reviewer.
+		submission.ReviewedBy = nil
+		submission.ReviewedAt = nil
+		if verdict.Status != model.TaskSubmissionPending {
+			now := time.Now().UTC()
+			submission.ReviewedAt = &now
+		}
+
+		if existing == nil {
+			err = s.uow.UserTasks().Create(ctx, submission)
+		} else {
+			err = s.uow.UserTasks().Update(ctx, submission)
+		}
+		if errors.Is(err, repository.ErrUserTaskExists) {
+			return ErrTaskAlreadySubmitted
+		}
+		if err != nil {
+			return err
+		}
+		if verdict.Status == model.TaskSubmissionApproved {
+			return creditSubmission(ctx, s.uow, s.referrals, submission, task)
+		}
+		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	if submission.Status == model.TaskSubmissionRejected {
+		return nil, ErrTaskRejected.WithDetails(verdict.Note)
+	}
+	return submission, nil
 }
 
-func (s *userService) SetReferrer(ctx context.Context, userID, referrerID string) error {
-	if userID == referrerID {
-		return errors.New("user cannot refer themselves")
+// checkResubmission allows a new submission only for a task the user has not
+// submitted yet or whose last submission was rejected.
+func checkResubmission(existing *model.UserTask) error {
+	if existing == nil {
+		return nil
 	}
-	return s.uow.Users().SetReferrer(ctx, userID, referrerID)
+	switch existing.Status {
+	case model.TaskSubmissionApproved:
+		return ErrTaskAlreadyCompleted
+	case model.TaskSubmissionPending:
+		return ErrTaskAlreadySubmitted
+	case model.TaskSubmissionRevoked:
+		return ErrTaskCompletionRevoked
+	}
+	return nil
 }
 
-func (s *userService) GetUserStatus(ctx context.Context, userID string) (*model.UserStatus, error) {
+func (s *userService) SetReferrer(ctx context.Context, userID string, req *model.SetReferrerRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.SetReferrer")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		if req.ReferralCode != "" {
+			_, err := s.referrals.LinkByCode(ctx, userID, req.ReferralCode)
+			return err
+		}
+		return s.referrals.Link(ctx, userID, req.ReferrerID)
+	})
+}
+
+func (s *userService) GetUserStatus(ctx context.Context, userID string) (_ *model.UserStatus, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserStatus")
+	defer func() { tracing.End(span, err) }()
+
 	user, err := s.uow.Users().GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -70,9 +190,45 @@ func (s *userService) GetUserStatus(ctx context.Context, userID string) (*model.
 	}, nil
 }
 
-func (s *userService) GetLeaderboard(ctx context.Context, limit int) ([]model.LeaderboardUser, error) {
+func (s *userService) GetLeaderboard(ctx context.Context, cursor string, limit int) (_ *model.LeaderboardPage, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetLeaderboard")
+	defer func() { tracing.End(span, err) }()
+
 	if limit <= 0 || limit > 100 {
 		limit = 10
 	}
-	return s.uow.Users().GetLeaderboard(ctx, limit)
-}
\ No newline at end of file
+	return s.uow.Users().GetLeaderboard(ctx, cursor, limit)
+}
+
+func (s *userService) GetRank(ctx context.Context, userID string, neighbours int) (_ *model.UserRank, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetRank")
+	defer func() { tracing.End(span, err) }()
+
+	if neighbours < 0 || neighbours > 50 {
+		neighbours = 5
+	}
+	rank, err := s.uow.Users().GetRank(ctx, userID, neighbours)
+	if err != nil {
+		return nil, err
+	}
+	// Share of the other users ranked below this one: 100 for the leader,
+	// 0 for the last place.
+	rank.Percentile = 100
+	if rank.Total > 1 {
+		rank.Percentile = math.Round(float64(rank.Total-rank.User.Rank)/float64(rank.Total-1)*10000) / 100
+	}
+	return rank, nil
+}
+
+func (s *userService) GetTransactions(ctx context.Context, userID, cursor string, limit int) (_ *model.PointTransactionPage, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetTransactions")
+	defer func() { tracing.End(span, err) }()
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if _, err := s.uow.Users().GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.uow.Ledger().ListByUser(ctx, userID, cursor, limit)
+}
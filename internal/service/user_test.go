@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"denet/internal/apperror"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/verifier"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestUserService() (*userService, *fakeStore, *fakeReferrals) {
+	uow := newFakeStore()
+	published := time.Now().Add(-time.Hour)
+	uow.users["alice"] = model.User{ID: "alice", Username: "alice"}
+	uow.tasks["follow"] = model.Task{ID: "follow", Name: "Follow us", Points: 50,
+		PublishedAt: &published, VerificationType: model.VerificationAuto}
+	referrals := &fakeReferrals{}
+	return &userService{uow: uow, referrals: referrals, verifiers: verifier.NewRegistry()}, uow, referrals
+}
+
+func TestCompleteTaskAppendsToLedger(t *testing.T) {
+	s, uow, referrals := newTestUserService()
+	ctx := context.Background()
+
+	if _, err := s.CompleteTask(ctx, "alice", "follow", ""); err != nil {
+		t.Fatalf("CompleteTask() = %v", err)
+	}
+	if _, err := s.CompleteTask(ctx, "alice", "follow", ""); !errors.Is(err, ErrTaskAlreadyCompleted) {
+		t.Fatalf("second CompleteTask() = %v, want %v", err, ErrTaskAlreadyCompleted)
+	}
+
+	if len(uow.ledger) != 1 {
+		t.Fatalf("ledger has %d entries, want 1", len(uow.ledger))
+	}
+	entry := uow.ledger[0]
+	if entry.Delta != 50 || entry.SourceType != model.PointSourceTask || entry.IdempotencyKey != "task:alice:follow" {
+		t.Errorf("ledger entry = %+v, want +50 from the task", entry)
+	}
+	if balance, err := uow.balance("alice"); err != nil || balance != 50 {
+		t.Errorf("balance = %d (%v), want 50", balance, err)
+	}
+	if len(referrals.earned) != 1 || referrals.earned[0] != entry.IdempotencyKey {
+		t.Errorf("referral earnings paid for %v, want the task entry", referrals.earned)
+	}
+}
+
+func TestCompleteTaskCreditedBeforeVerification(t *testing.T) {
+	s, uow, _ := newTestUserService()
+	ctx := context.Background()
+	// A completion recorded before submissions were tracked holds the
+	// ledger key but no submission.
+	uow.Ledger().Append(ctx, &model.PointTransaction{UserID: "alice", Delta: 50,
+		SourceType: model.PointSourceTask, IdempotencyKey: "task:alice:follow"})
+
+	if _, err := s.CompleteTask(ctx, "alice", "follow", ""); !errors.Is(err, ErrTaskAlreadyCompleted) {
+		t.Fatalf("CompleteTask() = %v, want %v", err, ErrTaskAlreadyCompleted)
+	}
+	if len(uow.userTasks) != 0 {
+		t.Errorf("submission was kept although crediting it failed: %v", uow.userTasks)
+	}
+	if balance, _ := uow.balance("alice"); balance != 50 {
+		t.Errorf("balance = %d, want 50", balance)
+	}
+}
+
+func TestCompleteTaskRejected(t *testing.T) {
+	s, uow, _ := newTestUserService()
+	ctx := context.Background()
+	attempts := 0
+	s.verifiers.Register("quiz", verifier.Func(func(context.Context, verifier.Submission) (model.TaskVerdict, error) {
+		attempts++
+		if attempts == 1 {
+			return model.TaskVerdict{Status: model.TaskSubmissionRejected, Note: "wrong answer"}, nil
+		}
+		return model.TaskVerdict{Status: model.TaskSubmissionApproved}, nil
+	}))
+	task := uow.tasks["follow"]
+	task.VerificationType = "quiz"
+	uow.tasks["follow"] = task
+
+	_, err := s.CompleteTask(ctx, "alice", "follow", "42")
+	if !errors.Is(err, ErrTaskRejected) || apperror.From(err).Details != "wrong answer" {
+		t.Fatalf("CompleteTask() = %v, want %v carrying the note", err, ErrTaskRejected)
+	}
+	stored, _ := uow.UserTasks().Get(ctx, "alice", "follow")
+	if stored == nil || stored.Status != model.TaskSubmissionRejected || len(uow.ledger) != 0 {
+		t.Fatalf("stored submission = %+v with %d ledger entries, want a rejection and nothing paid", stored, len(uow.ledger))
+	}
+
+	// A rejected task can be submitted again.
+	submission, err := s.CompleteTask(ctx, "alice", "follow", "43")
+	if err != nil || submission.Status != model.TaskSubmissionApproved || submission.ID != stored.ID {
+		t.Errorf("resubmission = %+v, %v; want the same submission approved", submission, err)
+	}
+}
+
+func TestGetTransactions(t *testing.T) {
+	s, uow, _ := newTestUserService()
+	ctx := context.Background()
+	for i := 0; i < 25; i++ {
+		uow.Ledger().Append(ctx, &model.PointTransaction{UserID: "alice", Delta: i + 1,
+			SourceType: model.PointSourceAdmin, IdempotencyKey: uow.newID("grant")})
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		wantCount int
+	}{
+		{"default limit", 0, 20},
+		{"explicit limit", 5, 5},
+		{"limit above maximum", 500, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := s.GetTransactions(ctx, "alice", "", tt.limit)
+			if err != nil {
+				t.Fatalf("GetTransactions() = %v", err)
+			}
+			if len(page.Transactions) != tt.wantCount {
+				t.Errorf("got %d transactions, want %d", len(page.Transactions), tt.wantCount)
+			}
+			if page.Transactions[0].Delta != 25 {
+				t.Errorf("first transaction = %+v, want the newest", page.Transactions[0])
+			}
+		})
+	}
+
+	if _, err := s.GetTransactions(ctx, "bob", "", 10); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("GetTransactions() for an unknown user = %v, want %v", err, repository.ErrUserNotFound)
+	}
+}
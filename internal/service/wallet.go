@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"denet/internal/apperror"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/siwe"
+	"denet/internal/tracing"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidWalletAddress   = apperror.New("invalid_wallet_address", http.StatusBadRequest, "Invalid wallet address")
+	ErrInvalidWalletMessage   = apperror.New("invalid_wallet_message", http.StatusBadRequest, "Invalid Sign-In with Ethereum message")
+	ErrInvalidWalletSignature = apperror.New("invalid_wallet_signature", http.StatusUnauthorized, "Wallet signature is invalid for this service")
+	ErrWalletNotLinked        = apperror.New("wallet_not_linked", http.StatusUnauthorized, "No account is linked to this wallet")
+)
+
+const (
+	walletNonceAlphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	walletNonceLength   = 17
+
+	// walletClockSkew is how far in the future a message may claim to have
+	// been issued.
+	walletClockSkew = time.Minute
+)
+
+// WalletConfig says which Sign-In with Ethereum messages are meant for this
+// service: Domain must match the message's, and ChainIDs, if set, lists the
+// chains accepted.
+type WalletConfig struct {
+	Domain   string
+	ChainIDs []int64
+	NonceTTL time.Duration
+}
+
+type WalletService interface {
+	IssueNonce(ctx context.Context) (*model.WalletNonce, error)
+	// LinkWallet verifies the signed message and links its address to
+	// userID. The first wallet becomes the primary one.
+	LinkWallet(ctx context.Context, userID string, req *model.WalletSignatureRequest) (*model.Wallet, error)
+	ListWallets(ctx context.Context, userID string) ([]model.Wallet, error)
+	SetPrimary(ctx context.Context, userID, address string) error
+	// UnlinkWallet removes a wallet; if it was the primary one, the oldest
+	// remaining wallet takes over.
+	UnlinkWallet(ctx context.Context, userID, address string) error
+	// Authenticate verifies the signed message and returns the user its
+	// address is linked to.
+	Authenticate(ctx context.Context, req *model.WalletSignatureRequest) (*model.User, error)
+	PurgeExpiredNonces(ctx context.Context) error
+}
+
+type walletService struct {
+	uow    repository.UnitOfWork
+	config WalletConfig
+}
+
+func NewWalletService(uow repository.UnitOfWork, config WalletConfig) WalletService {
+	return &walletService{uow: uow, config: config}
+}
+
+func (s *walletService) IssueNonce(ctx context.Context) (_ *model.WalletNonce, err error) {
+	ctx, span := tracing.Start(ctx, "WalletService.IssueNonce")
+	defer func() { tracing.End(span, err) }()
+
+	raw := make([]byte, walletNonceLength)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, walletNonceLength)
+	for i, b := range raw {
+		nonce[i] = walletNonceAlphabet[int(b)%len(walletNonceAlphabet)]
+	}
+	issued := &model.WalletNonce{
+		Nonce:     string(nonce),
+		ExpiresAt: time.Now().UTC().Add(s.config.NonceTTL),
+	}
+	if err := s.uow.Wallets().CreateNonce(ctx, issued); err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+func (s *walletService) LinkWallet(ctx context.Context, userID string, req *model.WalletSignatureRequest) (_ *model.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, "WalletService.LinkWallet")
+	defer func() { tracing.End(span, err) }()
+
+	var wallet *model.Wallet
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		msg, err := s.verify(ctx, req)
+		if err != nil {
+			return err
+		}
+		wallets, err := s.uow.Wallets().ListByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		wallet = &model.Wallet{
+			UserID:     userID,
+			Address:    msg.Address,
+			ChainID:    msg.ChainID,
+			IsPrimary:  len(wallets) == 0,
+			VerifiedAt: time.Now().UTC(),
+		}
+		return s.uow.Wallets().Create(ctx, wallet)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+func (s *walletService) ListWallets(ctx context.Context, userID string) (_ []model.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, "WalletService.ListWallets")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.uow.Users().GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.uow.Wallets().ListByUser(ctx, userID)
+}
+
+func (s *walletService) SetPrimary(ctx context.Context, userID, address string) (err error) {
+	ctx, span := tracing.Start(ctx, "WalletService.SetPrimary")
+	defer func() { tracing.End(span, err) }()
+
+	if !siwe.IsAddress(address) {
+		return ErrInvalidWalletAddress
+	}
+	return s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		return s.uow.Wallets().SetPrimary(ctx, userID, siwe.ChecksumAddress(address))
+	})
+}
+
+func (s *walletService) UnlinkWallet(ctx context.Context, userID, address string) (err error) {
+	ctx, span := tracing.Start(ctx, "WalletService.UnlinkWallet")
+	defer func() { tracing.End(span, err) }()
+
+	if !siwe.IsAddress(address) {
+		return ErrInvalidWalletAddress
+	}
+	return s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		removed, err := s.uow.Wallets().Delete(ctx, userID, siwe.ChecksumAddress(address))
+		if err != nil {
+			return err
+		}
+		if !removed.IsPrimary {
+			return nil
+		}
+		remaining, err := s.uow.Wallets().ListByUser(ctx, userID)
+		if err != nil || len(remaining) == 0 {
+			return err
+		}
+		return s.uow.Wallets().SetPrimary(ctx, userID, remaining[0].Address)
+	})
+}
+
+func (s *walletService) Authenticate(ctx context.Context, req *model.WalletSignatureRequest) (_ *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "WalletService.Authenticate")
+	defer func() { tracing.End(span, err) }()
+
+	var user *model.User
+	err = s.uow.Transactions().WithTransaction(ctx, func(ctx context.Context) error {
+		msg, err := s.verify(ctx, req)
+		if err != nil {
+			return err
+		}
+		wallet, err := s.uow.Wallets().GetByAddress(ctx, msg.Address)
+		if errors.Is(err, repository.ErrWalletNotFound) {
+			return ErrWalletNotLinked
+		}
+		if err != nil {
+			return err
+		}
+		user, err = s.uow.Users().GetByID(ctx, wallet.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *walletService) PurgeExpiredNonces(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "WalletService.PurgeExpiredNonces")
+	defer func() { tracing.End(span, err) }()
+
+	return s.uow.Wallets().DeleteExpiredNonces(ctx, time.Now().UTC())
+}
+
+// verify checks that the message was signed by its address for this service
+// and is current, then uses up its nonce. It must run inside the caller's
+// transaction so that the nonce is kept if the caller fails.
+func (s *walletService) verify(ctx context.Context, req *model.WalletSignatureRequest) (*siwe.Message, error) {
+	msg, err := siwe.Parse(req.Message)
+	if err != nil {
+		return nil, ErrInvalidWalletMessage
+	}
+	now := time.Now().UTC()
+	switch {
+	case msg.Domain != s.config.Domain:
+		return nil, ErrInvalidWalletSignature.WithDetails("message is for domain " + msg.Domain)
+	case msg.Version != "1":
+		return nil, ErrInvalidWalletMessage.WithDetails("unsupported version " + msg.Version)
+	case !s.chainAllowed(msg.ChainID):
+		return nil, ErrInvalidWalletSignature.WithDetails("chain " + strconv.FormatInt(msg.ChainID, 10) + " is not accepted")
+	case msg.IssuedAt.After(now.Add(walletClockSkew)):
+		return nil, ErrInvalidWalletSignature.WithDetails("message is issued in the future")
+	case msg.ExpirationTime != nil && !now.Before(*msg.ExpirationTime):
+		return nil, ErrInvalidWalletSignature.WithDetails("message has expired")
+	case msg.NotBefore != nil && now.Before(*msg.NotBefore):
+		return nil, ErrInvalidWalletSignature.WithDetails("message is not valid yet")
+	}
+	if err := msg.Verify(req.Message, req.Signature); err != nil {
+		return nil, ErrInvalidWalletSignature
+	}
+	if err := s.uow.Wallets().ConsumeNonce(ctx, msg.Nonce, now); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (s *walletService) chainAllowed(chainID int64) bool {
+	if len(s.config.ChainIDs) == 0 {
+		return true
+	}
+	for _, allowed := range s.config.ChainIDs {
+		if chainID == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+// Package siwe parses Sign-In with Ethereum (EIP-4361) messages and checks
+// their EIP-191 personal_sign signatures offline. Only externally owned
+// accounts are supported; contract wallets (EIP-1271) would need a chain
+// call.
+package siwe
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	ErrMalformedMessage   = errors.New("siwe: malformed message")
+	ErrMalformedSignature = errors.New("siwe: malformed signature")
+	ErrSignatureMismatch  = errors.New("siwe: signature does not match address")
+)
+
+const headerSuffix = " wants you to sign in with your Ethereum account:"
+
+// Message is a parsed EIP-4361 message. Optional fields are zero when absent.
+type Message struct {
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// Parse reads a message in the EIP-4361 text format. The address must be
+// EIP-55 checksummed.
+func Parse(text string) (*Message, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(lines) < 2 || !strings.HasSuffix(lines[0], headerSuffix) {
+		return nil, ErrMalformedMessage
+	}
+	msg := &Message{Domain: strings.TrimSuffix(lines[0], headerSuffix)}
+	if i := strings.Index(msg.Domain, "://"); i >= 0 {
+		msg.Domain = msg.Domain[i+3:]
+	}
+	msg.Address = lines[1]
+	if !IsAddress(msg.Address) || ChecksumAddress(msg.Address) != msg.Address {
+		return nil, ErrMalformedMessage
+	}
+
+	i := 2
+	for i < len(lines) && lines[i] == "" {
+		i++
+	}
+	if i < len(lines) && !strings.HasPrefix(lines[i], "URI: ") {
+		msg.Statement = lines[i]
+		i++
+		for i < len(lines) && lines[i] == "" {
+			i++
+		}
+	}
+
+	var err error
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "URI: "):
+			msg.URI = strings.TrimPrefix(line, "URI: ")
+		case strings.HasPrefix(line, "Version: "):
+			msg.Version = strings.TrimPrefix(line, "Version: ")
+		case strings.HasPrefix(line, "Chain ID: "):
+			msg.ChainID, err = strconv.ParseInt(strings.TrimPrefix(line, "Chain ID: "), 10, 64)
+		case strings.HasPrefix(line, "Nonce: "):
+			msg.Nonce = strings.TrimPrefix(line, "Nonce: ")
+		case strings.HasPrefix(line, "Issued At: "):
+			msg.IssuedAt, err = time.Parse(time.RFC3339Nano, strings.TrimPrefix(line, "Issued At: "))
+		case strings.HasPrefix(line, "Expiration Time: "):
+			msg.ExpirationTime, err = parseOptionalTime(strings.TrimPrefix(line, "Expiration Time: "))
+		case strings.HasPrefix(line, "Not Before: "):
+			msg.NotBefore, err = parseOptionalTime(strings.TrimPrefix(line, "Not Before: "))
+		case strings.HasPrefix(line, "Request ID: "):
+			msg.RequestID = strings.TrimPrefix(line, "Request ID: ")
+		case line == "Resources:":
+			for i+1 < len(lines) && strings.HasPrefix(lines[i+1], "- ") {
+				i++
+				msg.Resources = append(msg.Resources, strings.TrimPrefix(lines[i], "- "))
+			}
+		default:
+			return nil, ErrMalformedMessage
+		}
+		if err != nil {
+			return nil, ErrMalformedMessage
+		}
+	}
+	if msg.URI == "" || msg.Version == "" || msg.ChainID == 0 || len(msg.Nonce) < 8 || msg.IssuedAt.IsZero() {
+		return nil, ErrMalformedMessage
+	}
+	return msg, nil
+}
+
+func parseOptionalTime(value string) (*time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RecoverAddress returns the checksummed address whose key produced the
+// personal_sign signature over text. signature is 65 hex bytes r || s || v
+// with v either 0/1 or 27/28, optionally 0x-prefixed.
+func RecoverAddress(text, signature string) (string, error) {
+	sig, err := hex.DecodeString(strings.TrimPrefix(signature, "0x"))
+	if err != nil || len(sig) != 65 {
+		return "", ErrMalformedSignature
+	}
+	v := sig[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if v > 1 {
+		return "", ErrMalformedSignature
+	}
+	// RecoverCompact wants the recovery code first, offset by 27 for an
+	// uncompressed key.
+	compact := make([]byte, 65)
+	compact[0] = 27 + v
+	copy(compact[1:], sig[:64])
+
+	pub, _, err := ecdsa.RecoverCompact(compact, personalHash(text))
+	if err != nil {
+		return "", ErrSignatureMismatch
+	}
+	return ChecksumAddress(hex.EncodeToString(keccak256(pub.SerializeUncompressed()[1:])[12:])), nil
+}
+
+// Verify checks that msg's address signed text, the message as it was
+// presented to the wallet.
+func (m *Message) Verify(text, signature string) error {
+	address, err := RecoverAddress(text, signature)
+	if err != nil {
+		return err
+	}
+	if address != m.Address {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// personalHash is the EIP-191 version 0x45 digest wallets sign for
+// personal_sign.
+func personalHash(text string) []byte {
+	return keccak256([]byte("\x19Ethereum Signed Message:\n" + strconv.Itoa(len(text)) + text))
+}
+
+func keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// IsAddress reports whether s is a 0x-prefixed 20-byte hex address in any
+// case.
+func IsAddress(s string) bool {
+	if len(s) != 42 || !strings.HasPrefix(s, "0x") {
+		return false
+	}
+	_, err := hex.DecodeString(s[2:])
+	return err == nil
+}
+
+// ChecksumAddress returns the EIP-55 mixed-case form of a hex address, with
+// or without the 0x prefix.
+func ChecksumAddress(address string) string {
+	lower := strings.ToLower(strings.TrimPrefix(address, "0x"))
+	hash := hex.EncodeToString(keccak256([]byte(lower)))
+	out := []byte(lower)
+	for i, c := range out {
+		if c >= 'a' && c <= 'f' && hash[i] >= '8' {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
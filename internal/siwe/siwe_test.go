@@ -0,0 +1,208 @@
+package siwe
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// The example message of EIP-4361.
+const specMessage = `service.invalid wants you to sign in with your Ethereum account:
+0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2
+
+I accept the ServiceOrg Terms of Service: https://service.invalid/tos
+
+URI: https://service.invalid/login
+Version: 1
+Chain ID: 1
+Nonce: 32891756
+Issued At: 2021-09-30T16:25:24Z
+Resources:
+- ipfs://bafybeiemxf5abjwjbikoz4mc3a3dla6ual3jsgpdr4cjr3oz3evfyavhwq/
+- https://example.com/my-web2-claim.json`
+
+func TestParse(t *testing.T) {
+	msg, err := Parse(specMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Message{
+		Domain:    "service.invalid",
+		Address:   "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2",
+		Statement: "I accept the ServiceOrg Terms of Service: https://service.invalid/tos",
+		URI:       "https://service.invalid/login",
+		Version:   "1",
+		ChainID:   1,
+		Nonce:     "32891756",
+		IssuedAt:  time.Date(2021, 9, 30, 16, 25, 24, 0, time.UTC),
+		Resources: []string{
+			"ipfs://bafybeiemxf5abjwjbikoz4mc3a3dla6ual3jsgpdr4cjr3oz3evfyavhwq/",
+			"https://example.com/my-web2-claim.json",
+		},
+	}
+	if msg.Domain != want.Domain || msg.Address != want.Address || msg.Statement != want.Statement ||
+		msg.URI != want.URI || msg.Version != want.Version || msg.ChainID != want.ChainID ||
+		msg.Nonce != want.Nonce || !msg.IssuedAt.Equal(want.IssuedAt) ||
+		strings.Join(msg.Resources, " ") != strings.Join(want.Resources, " ") {
+		t.Errorf("Parse() = %+v\nwant %+v", *msg, want)
+	}
+	if msg.ExpirationTime != nil || msg.NotBefore != nil || msg.RequestID != "" {
+		t.Errorf("absent optional fields parsed: %+v", *msg)
+	}
+}
+
+func TestParseOptionalFields(t *testing.T) {
+	text := "https://example.com wants you to sign in with your Ethereum account:\r\n" +
+		"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266\r\n\r\n" +
+		"URI: https://example.com\r\nVersion: 1\r\nChain ID: 137\r\nNonce: abcdefgh12\r\n" +
+		"Issued At: 2024-05-01T10:00:00.5Z\r\nExpiration Time: 2024-05-01T10:10:00Z\r\n" +
+		"Not Before: 2024-05-01T09:59:00Z\r\nRequest ID: req-1"
+	msg, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Domain != "example.com" {
+		t.Errorf("Domain = %q, want the scheme stripped", msg.Domain)
+	}
+	if msg.Statement != "" || msg.ChainID != 137 || msg.RequestID != "req-1" {
+		t.Errorf("Parse() = %+v", *msg)
+	}
+	if msg.ExpirationTime == nil || !msg.ExpirationTime.Equal(time.Date(2024, 5, 1, 10, 10, 0, 0, time.UTC)) {
+		t.Errorf("ExpirationTime = %v", msg.ExpirationTime)
+	}
+	if msg.NotBefore == nil || !msg.NotBefore.Equal(time.Date(2024, 5, 1, 9, 59, 0, 0, time.UTC)) {
+		t.Errorf("NotBefore = %v", msg.NotBefore)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace string
+		with    string
+	}{
+		{"missing header", " wants you to sign in with your Ethereum account:", ""},
+		{"address not checksummed", "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2", "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"},
+		{"address too short", "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2", "0xC02aaA39"},
+		{"missing uri", "URI: https://service.invalid/login\n", ""},
+		{"missing version", "Version: 1\n", ""},
+		{"chain id not a number", "Chain ID: 1", "Chain ID: one"},
+		{"nonce too short", "Nonce: 32891756", "Nonce: 1234567"},
+		{"issued at not a time", "Issued At: 2021-09-30T16:25:24Z", "Issued At: yesterday"},
+		{"unknown field", "Version: 1", "Version: 1\nColour: blue"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := strings.Replace(specMessage, tt.replace, tt.with, 1)
+			if text == specMessage {
+				t.Fatal("replacement did not apply")
+			}
+			if _, err := Parse(text); !errors.Is(err, ErrMalformedMessage) {
+				t.Errorf("Parse() error = %v, want ErrMalformedMessage", err)
+			}
+		})
+	}
+}
+
+// The first development account of Hardhat and Anvil.
+const (
+	testPrivateKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+	testAddress    = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+)
+
+// personalSign signs text the way a wallet does, returning r || s || v with
+// v = 27 or 28.
+func personalSign(t *testing.T, text string) []byte {
+	t.Helper()
+	key, err := hex.DecodeString(testPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compact := ecdsa.SignCompact(secp256k1.PrivKeyFromBytes(key), personalHash(text), false)
+	return append(compact[1:], compact[0])
+}
+
+func TestRecoverAddress(t *testing.T) {
+	const text = "Sign in to example.com"
+	sig := personalSign(t, text)
+	zeroBased := append([]byte(nil), sig...)
+	zeroBased[64] -= 27
+
+	tests := []struct {
+		name      string
+		text      string
+		signature string
+		want      string
+		wantErr   error
+	}{
+		{"v 27/28", text, "0x" + hex.EncodeToString(sig), testAddress, nil},
+		{"v 0/1", text, "0x" + hex.EncodeToString(zeroBased), testAddress, nil},
+		{"no prefix", text, hex.EncodeToString(sig), testAddress, nil},
+		{"other text", text + ".", "0x" + hex.EncodeToString(sig), "", nil},
+		{"too short", text, "0x" + hex.EncodeToString(sig[:64]), "", ErrMalformedSignature},
+		{"not hex", text, "0xzz" + hex.EncodeToString(sig[1:]), "", ErrMalformedSignature},
+		{"bad v", text, "0x" + hex.EncodeToString(append(sig[:64:64], 29)), "", ErrMalformedSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RecoverAddress(tt.text, tt.signature)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.want == "" {
+				// A signature over other text recovers some other key, if any.
+				if err == nil && got == testAddress {
+					t.Errorf("recovered %s from a signature over other text", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("RecoverAddress() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	text := "example.com wants you to sign in with your Ethereum account:\n" + testAddress + "\n\n" +
+		"URI: https://example.com\nVersion: 1\nChain ID: 1\nNonce: 0123456789abcdef\nIssued At: 2024-05-01T10:00:00Z"
+	msg, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := "0x" + hex.EncodeToString(personalSign(t, text))
+	if err := msg.Verify(text, sig); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+
+	other := *msg
+	other.Address = "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+	if err := other.Verify(text, sig); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("Verify() for another address = %v, want ErrSignatureMismatch", err)
+	}
+}
+
+// The test vectors of EIP-55.
+func TestChecksumAddress(t *testing.T) {
+	for _, want := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		if got := ChecksumAddress(strings.ToLower(want)); got != want {
+			t.Errorf("ChecksumAddress(%s) = %s", strings.ToLower(want), got)
+		}
+		if got := ChecksumAddress(strings.ToUpper(want[2:])); got != want {
+			t.Errorf("ChecksumAddress(%s) = %s", strings.ToUpper(want[2:]), got)
+		}
+	}
+}
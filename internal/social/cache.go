@@ -0,0 +1,79 @@
+package social
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// cacheMaxEntries bounds the cache; when it is full, expired entries are
+// dropped and, failing that, the whole cache is.
+const cacheMaxEntries = 10000
+
+type cacheEntry struct {
+	member    bool
+	expiresAt time.Time
+}
+
+// CachedChecker remembers answers of the wrapped checker so that repeated
+// submissions and retries do not spend platform rate limits. Negative answers
+// get their own, usually shorter, TTL so that a user who just joined is not
+// kept waiting. Errors are not cached.
+type CachedChecker struct {
+	checker     MembershipChecker
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func NewCachedChecker(checker MembershipChecker, ttl, negativeTTL time.Duration) *CachedChecker {
+	return &CachedChecker{
+		checker:     checker,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     make(map[string]cacheEntry),
+	}
+}
+
+func (c *CachedChecker) IsMember(ctx context.Context, target, externalID string) (bool, error) {
+	key := target + "\x00" + externalID
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.member, nil
+	}
+
+	member, err := c.checker.IsMember(ctx, target, externalID)
+	if err != nil {
+		return false, err
+	}
+	ttl := c.ttl
+	if !member {
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 {
+		return member, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= cacheMaxEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= cacheMaxEntries {
+			c.entries = make(map[string]cacheEntry)
+		}
+	}
+	c.entries[key] = cacheEntry{member: member, expiresAt: now.Add(ttl)}
+	return member, nil
+}
@@ -0,0 +1,77 @@
+package social
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// HTTPClient is the part of *http.Client the platform clients use, so that
+// they can be pointed at fakes or wrapped with instrumentation.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// MembershipChecker reports whether the platform user externalID belongs to
+// target: a channel, an account they follow or a guild.
+type MembershipChecker interface {
+	IsMember(ctx context.Context, target, externalID string) (bool, error)
+}
+
+// ErrUnavailable wraps failures talking to a platform. The caller cannot tell
+// whether the user is a member and should try again later.
+var ErrUnavailable = errors.New("social platform unavailable")
+
+// ErrInconclusive is returned when a platform answered but its answer does
+// not tell whether the user is a member. Retrying will not help; a person has
+// to check.
+var ErrInconclusive = errors.New("membership is inconclusive")
+
+// maxResponseBytes bounds how much of a platform response is read.
+const maxResponseBytes = 1 << 20
+
+// getJSON sends req and decodes the body into out. The status code is
+// returned whatever it is, and the body is only decoded into out on 2xx;
+// transport failures are wrapped in ErrUnavailable.
+func getJSON(client HTTPClient, req *http.Request, out interface{}) (int, error) {
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL may carry a token, as Telegram's does, so only its host
+		// makes it into the error.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, fmt.Errorf("%w: %s %s: %v", ErrUnavailable, req.Method, req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Error bodies are decoded too when the platform describes errors
+		// in JSON; a body that does not parse is ignored.
+		_ = json.Unmarshal(body, out)
+		return resp.StatusCode, nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return resp.StatusCode, fmt.Errorf("%w: decode response: %v", ErrUnavailable, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// inconclusive reports an answer that leaves membership open.
+func inconclusive(platform, reason string) error {
+	return fmt.Errorf("%w: %s: %s", ErrInconclusive, platform, reason)
+}
+
+// unexpectedStatus reports a status the caller has no answer for.
+func unexpectedStatus(platform string, status int) error {
+	return fmt.Errorf("%w: %s responded with status %d", ErrUnavailable, platform, status)
+}
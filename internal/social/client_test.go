@@ -0,0 +1,146 @@
+package social
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"denet/internal/social/socialtest"
+)
+
+func TestClients(t *testing.T) {
+	telegram := socialtest.NewTelegram("bot-token")
+	twitter := socialtest.NewTwitter("bearer-token")
+	discord := socialtest.NewDiscord("discord-token")
+	for _, s := range []*socialtest.Server{telegram, twitter, discord} {
+		t.Cleanup(s.Close)
+	}
+
+	platforms := []struct {
+		name   string
+		server *socialtest.Server
+		client MembershipChecker
+		wrong  MembershipChecker
+	}{
+		{
+			name:   "telegram",
+			server: telegram,
+			client: &TelegramClient{BaseURL: telegram.URL, Token: "bot-token", HTTP: telegram.Client()},
+			wrong:  &TelegramClient{BaseURL: telegram.URL, Token: "revoked", HTTP: telegram.Client()},
+		},
+		{
+			name:   "twitter",
+			server: twitter,
+			client: &TwitterClient{BaseURL: twitter.URL, BearerToken: "bearer-token", HTTP: twitter.Client()},
+			wrong:  &TwitterClient{BaseURL: twitter.URL, BearerToken: "revoked", HTTP: twitter.Client()},
+		},
+		{
+			name:   "discord",
+			server: discord,
+			client: &DiscordClient{BaseURL: discord.URL, BotToken: "discord-token", HTTP: discord.Client()},
+			wrong:  &DiscordClient{BaseURL: discord.URL, BotToken: "revoked", HTTP: discord.Client()},
+		},
+	}
+
+	ctx := context.Background()
+	for _, p := range platforms {
+		t.Run(p.name, func(t *testing.T) {
+			p.server.AddMember("target", "42")
+			p.server.AddMember("other", "7")
+
+			for _, tt := range []struct {
+				externalID string
+				want       bool
+			}{{"42", true}, {"7", false}, {"1", false}} {
+				got, err := p.client.IsMember(ctx, "target", tt.externalID)
+				if err != nil || got != tt.want {
+					t.Errorf("IsMember(target, %s) = %v, %v, want %v", tt.externalID, got, err, tt.want)
+				}
+			}
+
+			p.server.RemoveMember("target", "42")
+			if got, err := p.client.IsMember(ctx, "target", "42"); err != nil || got {
+				t.Errorf("IsMember after leaving = %v, %v, want false", got, err)
+			}
+
+			if _, err := p.wrong.IsMember(ctx, "target", "7"); !errors.Is(err, ErrUnavailable) {
+				t.Errorf("IsMember with a bad token error = %v, want ErrUnavailable", err)
+			}
+
+			p.server.SetUnavailable(true)
+			defer p.server.SetUnavailable(false)
+			if _, err := p.client.IsMember(ctx, "other", "7"); !errors.Is(err, ErrUnavailable) {
+				t.Errorf("IsMember while down error = %v, want ErrUnavailable", err)
+			}
+		})
+	}
+}
+
+func TestTwitterClientInconclusive(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"forbidden", http.StatusForbidden, `{"errors":[{"code":63,"message":"User has been suspended."}]}`},
+		{"no relationship", http.StatusOK, `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			t.Cleanup(server.Close)
+			client := &TwitterClient{BaseURL: server.URL, BearerToken: "bearer-token", HTTP: server.Client()}
+
+			if _, err := client.IsMember(context.Background(), "target", "42"); !errors.Is(err, ErrInconclusive) {
+				t.Errorf("IsMember() error = %v, want ErrInconclusive", err)
+			}
+		})
+	}
+}
+
+func TestCachedChecker(t *testing.T) {
+	server := socialtest.NewDiscord("discord-token")
+	t.Cleanup(server.Close)
+	server.AddMember("guild", "42")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cached := NewCachedChecker(&DiscordClient{BaseURL: server.URL, BotToken: "discord-token", HTTP: server.Client()}, time.Hour, time.Minute)
+	cached.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	check := func(externalID string, want bool, wantRequests int) {
+		t.Helper()
+		got, err := cached.IsMember(ctx, "guild", externalID)
+		if err != nil || got != want {
+			t.Errorf("IsMember(%s) = %v, %v, want %v", externalID, got, err, want)
+		}
+		if n := server.Requests(); n != wantRequests {
+			t.Errorf("requests = %d, want %d", n, wantRequests)
+		}
+	}
+
+	check("42", true, 1)
+	check("7", false, 2)
+	check("42", true, 2)
+	check("7", false, 2)
+
+	// A user who joins is seen once the shorter negative TTL runs out.
+	server.AddMember("guild", "7")
+	now = now.Add(2 * time.Minute)
+	check("7", true, 3)
+	check("42", true, 3)
+
+	// Errors are not cached.
+	server.SetUnavailable(true)
+	if _, err := cached.IsMember(ctx, "guild", "1"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("IsMember while down error = %v, want ErrUnavailable", err)
+	}
+	server.SetUnavailable(false)
+	check("1", false, 5)
+}
@@ -0,0 +1,49 @@
+package social
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// discordUnknownMember is the JSON error code Discord returns for a user who
+// is not in the guild.
+const discordUnknownMember = 10007
+
+// DiscordClient checks guild membership with a bot token. The bot must be in
+// the guild and have the server members intent.
+type DiscordClient struct {
+	BaseURL  string
+	BotToken string
+	HTTP     HTTPClient
+}
+
+// IsMember reports whether the Discord user externalID is in the guild
+// target.
+func (c *DiscordClient) IsMember(ctx context.Context, target, externalID string) (bool, error) {
+	endpoint := strings.TrimSuffix(c.BaseURL, "/") + "/guilds/" + url.PathEscape(target) + "/members/" + url.PathEscape(externalID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bot "+c.BotToken)
+
+	// Only the error code is of interest; a member object has none.
+	var resp struct {
+		Code int `json:"code"`
+	}
+	status, err := getJSON(c.HTTP, req, &resp)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case status == http.StatusOK:
+		return true, nil
+	case status == http.StatusNotFound && resp.Code == discordUnknownMember:
+		return false, nil
+	}
+	// An unknown guild lands here too: it means the task is misconfigured,
+	// not that the user left.
+	return false, unexpectedStatus("discord", status)
+}
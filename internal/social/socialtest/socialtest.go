@@ -0,0 +1,144 @@
+// Package socialtest provides in-process fakes of the Telegram, X and Discord
+// APIs the social verifiers call, so that the verification flow can run
+// without network access. Point a client's BaseURL at Server.URL.
+package socialtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Server is a fake platform API holding memberships: which users are in which
+// channel or guild, or follow which account.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	members     map[string]map[string]bool
+	unavailable bool
+	requests    int
+}
+
+func newServer(handler func(s *Server, w http.ResponseWriter, r *http.Request)) *Server {
+	s := &Server{members: make(map[string]map[string]bool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		unavailable := s.unavailable
+		s.mu.Unlock()
+		if unavailable {
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		handler(s, w, r)
+	}))
+	return s
+}
+
+// AddMember makes externalID a member of target.
+func (s *Server) AddMember(target, externalID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.members[target] == nil {
+		s.members[target] = make(map[string]bool)
+	}
+	s.members[target][externalID] = true
+}
+
+func (s *Server) RemoveMember(target, externalID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.members[target], externalID)
+}
+
+// SetUnavailable makes every request fail with 503 until it is reset.
+func (s *Server) SetUnavailable(unavailable bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unavailable = unavailable
+}
+
+// Requests returns how many requests the server received.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) isMember(target, externalID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.members[target][externalID]
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// NewTelegram fakes the Bot API getChatMember method for a bot with token.
+func NewTelegram(token string) *Server {
+	return newServer(func(s *Server, w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bot"+token+"/getChatMember" {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"ok": false, "error_code": 401, "description": "Unauthorized"})
+			return
+		}
+		chatID, userID := r.URL.Query().Get("chat_id"), r.URL.Query().Get("user_id")
+		status := "left"
+		if s.isMember(chatID, userID) {
+			status = "member"
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"ok":     true,
+			"result": map[string]interface{}{"status": status, "user": map[string]string{"id": userID}},
+		})
+	})
+}
+
+// NewTwitter fakes the X API v1.1 friendships/show lookup for an app with
+// bearerToken. A user follows every target they are a member of.
+func NewTwitter(bearerToken string) *Server {
+	return newServer(func(s *Server, w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+bearerToken {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"errors": []map[string]interface{}{{"code": 89, "message": "Invalid or expired token."}}})
+			return
+		}
+		source, target := r.URL.Query().Get("source_id"), r.URL.Query().Get("target_id")
+		if r.URL.Path != "/1.1/friendships/show.json" || source == "" || target == "" {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"errors": []map[string]interface{}{{"code": 34, "message": "Sorry, that page does not exist."}}})
+			return
+		}
+		following := s.isMember(target, source)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"relationship": map[string]interface{}{
+				"source": map[string]interface{}{"id_str": source, "following": following},
+				"target": map[string]interface{}{"id_str": target, "followed_by": following},
+			},
+		})
+	})
+}
+
+// NewDiscord fakes the guild member lookup for a bot with botToken.
+func NewDiscord(botToken string) *Server {
+	return newServer(func(s *Server, w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bot "+botToken {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"code": 0, "message": "401: Unauthorized"})
+			return
+		}
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) != 4 || parts[0] != "guilds" || parts[2] != "members" {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"code": 0, "message": "404: Not Found"})
+			return
+		}
+		guildID, userID := parts[1], parts[3]
+		if !s.isMember(guildID, userID) {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"code": 10007, "message": "Unknown Member"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"user": map[string]string{"id": userID}})
+	})
+}
@@ -0,0 +1,57 @@
+package social
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TelegramClient checks channel membership through the Bot API. The bot must
+// be an administrator of the channel to see its members.
+type TelegramClient struct {
+	BaseURL string
+	Token   string
+	HTTP    HTTPClient
+}
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Result      struct {
+		Status   string `json:"status"`
+		IsMember bool   `json:"is_member"`
+	} `json:"result"`
+}
+
+// IsMember reports whether the Telegram user externalID is in the chat
+// target, given as @username or numeric ID.
+func (c *TelegramClient) IsMember(ctx context.Context, target, externalID string) (bool, error) {
+	query := url.Values{"chat_id": {target}, "user_id": {externalID}}
+	endpoint := strings.TrimSuffix(c.BaseURL, "/") + "/bot" + c.Token + "/getChatMember?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	var resp telegramResponse
+	status, err := getJSON(c.HTTP, req, &resp)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case status == http.StatusOK && resp.OK:
+	case status == http.StatusBadRequest && strings.Contains(strings.ToLower(resp.Description), "user not found"):
+		// Telegram answers this for IDs that never existed.
+		return false, nil
+	default:
+		return false, unexpectedStatus("telegram", status)
+	}
+
+	switch resp.Result.Status {
+	case "creator", "administrator", "member":
+		return true, nil
+	case "restricted":
+		return resp.Result.IsMember, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,121 @@
+package social
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// The hash of testLoginPayload, computed independently with
+// HMAC-SHA256(key = SHA-256(testBotToken), data-check-string).
+const (
+	testBotToken  = "123456:ABC-DEF1234ghIkl-zyx57W2v1u123ew11"
+	testLoginHash = "2a696ef9e5f272e12c5c81351ed138971184c830a4670919993280038b121f17"
+)
+
+var testAuthDate = time.Unix(1714557600, 0).UTC()
+
+// testLoginPayload is what the widget posts, with id and auth_date as JSON
+// numbers and hash replaced by hash.
+func testLoginPayload(t *testing.T, hash string) map[string]json.RawMessage {
+	t.Helper()
+	var payload map[string]json.RawMessage
+	body := `{"id":424242,"first_name":"Ada","username":"ada","auth_date":1714557600,"hash":"` + hash + `"}`
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestVerifyTelegramLogin(t *testing.T) {
+	login, err := VerifyTelegramLogin(testBotToken, testLoginPayload(t, testLoginHash), time.Hour, testAuthDate.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TelegramLogin{ID: "424242", Username: "ada", FirstName: "Ada", AuthDate: testAuthDate}
+	if *login != want {
+		t.Errorf("VerifyTelegramLogin() = %+v, want %+v", *login, want)
+	}
+}
+
+func TestVerifyTelegramLoginRejects(t *testing.T) {
+	now := testAuthDate.Add(time.Minute)
+	tests := []struct {
+		name    string
+		token   string
+		payload func(t *testing.T) map[string]json.RawMessage
+		now     time.Time
+		wantErr error
+	}{
+		{
+			name:    "expired auth_date",
+			token:   testBotToken,
+			payload: func(t *testing.T) map[string]json.RawMessage { return testLoginPayload(t, testLoginHash) },
+			now:     testAuthDate.Add(time.Hour + time.Second),
+			wantErr: ErrTelegramLoginExpired,
+		},
+		{
+			name:    "other bot",
+			token:   "654321:other",
+			payload: func(t *testing.T) map[string]json.RawMessage { return testLoginPayload(t, testLoginHash) },
+			now:     now,
+			wantErr: ErrTelegramLoginInvalid,
+		},
+		{
+			name:  "changed field",
+			token: testBotToken,
+			payload: func(t *testing.T) map[string]json.RawMessage {
+				p := testLoginPayload(t, testLoginHash)
+				p["username"] = json.RawMessage(`"eve"`)
+				return p
+			},
+			now:     now,
+			wantErr: ErrTelegramLoginInvalid,
+		},
+		{
+			name:  "added field",
+			token: testBotToken,
+			payload: func(t *testing.T) map[string]json.RawMessage {
+				p := testLoginPayload(t, testLoginHash)
+				p["photo_url"] = json.RawMessage(`"https://t.me/i/userpic/ada.jpg"`)
+				return p
+			},
+			now:     now,
+			wantErr: ErrTelegramLoginInvalid,
+		},
+		{
+			name:    "missing hash",
+			token:   testBotToken,
+			payload: func(t *testing.T) map[string]json.RawMessage { return testLoginPayload(t, "") },
+			now:     now,
+			wantErr: ErrTelegramLoginInvalid,
+		},
+		{
+			name:    "hash not hex",
+			token:   testBotToken,
+			payload: func(t *testing.T) map[string]json.RawMessage { return testLoginPayload(t, "not-a-hash") },
+			now:     now,
+			wantErr: ErrTelegramLoginInvalid,
+		},
+		{
+			name:  "object field",
+			token: testBotToken,
+			payload: func(t *testing.T) map[string]json.RawMessage {
+				p := testLoginPayload(t, testLoginHash)
+				p["first_name"] = json.RawMessage(`{"text":"Ada"}`)
+				return p
+			},
+			now:     now,
+			wantErr: ErrTelegramLoginInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifyTelegramLogin(tt.token, tt.payload(t), time.Hour, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package social
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TwitterClient checks follows with an app bearer token. The v2 API can only
+// list a user's follows page by page, so the relationship between the two
+// accounts is looked up through the v1.1 friendships/show endpoint instead,
+// one request however many accounts the user follows.
+type TwitterClient struct {
+	BaseURL     string
+	BearerToken string
+	HTTP        HTTPClient
+}
+
+type twitterFriendshipResponse struct {
+	Relationship *struct {
+		Source struct {
+			Following bool `json:"following"`
+		} `json:"source"`
+	} `json:"relationship"`
+}
+
+// IsMember reports whether the X user externalID follows the account with
+// the numeric ID target. ErrInconclusive is returned when X refuses to show
+// the relationship, as it does for suspended accounts.
+func (c *TwitterClient) IsMember(ctx context.Context, target, externalID string) (bool, error) {
+	query := url.Values{"source_id": {externalID}, "target_id": {target}}
+	endpoint := strings.TrimSuffix(c.BaseURL, "/") + "/1.1/friendships/show.json?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+
+	var resp twitterFriendshipResponse
+	status, err := getJSON(c.HTTP, req, &resp)
+	if err != nil {
+		return false, err
+	}
+	switch status {
+	case http.StatusOK:
+		if resp.Relationship == nil {
+			return false, inconclusive("twitter", "response carries no relationship")
+		}
+		return resp.Relationship.Source.Following, nil
+	case http.StatusNotFound:
+		return false, nil
+	case http.StatusForbidden:
+		return false, inconclusive("twitter", "relationship is not visible to the app")
+	}
+	return false, unexpectedStatus("twitter", status)
+}
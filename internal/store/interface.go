@@ -2,24 +2,79 @@ package store
 
 import (
 	"context"
+	"errors"
+	"strings"
 )
 
-type Database interface {
+// ErrSerializationFailure is wrapped around driver errors that are safe to
+// retry by re-running the whole transaction (serialization failures and
+// deadlocks).
+var ErrSerializationFailure = errors.New("serialization failure")
+
+// ErrUniqueViolation is wrapped around driver errors caused by a unique
+// constraint.
+var ErrUniqueViolation = errors.New("unique violation")
+
+// ErrCheckViolation is wrapped around driver errors caused by a CHECK
+// constraint.
+var ErrCheckViolation = errors.New("check violation")
+
+type IsolationLevel int
+
+const (
+	IsolationDefault IsolationLevel = iota
+	IsolationReadCommitted
+	IsolationRepeatableRead
+	IsolationSerializable
+)
+
+func ParseIsolationLevel(s string) (IsolationLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "default":
+		return IsolationDefault, nil
+	case "read committed", "read_committed":
+		return IsolationReadCommitted, nil
+	case "repeatable read", "repeatable_read":
+		return IsolationRepeatableRead, nil
+	case "serializable":
+		return IsolationSerializable, nil
+	}
+	return IsolationDefault, errors.New("unknown isolation level: " + s)
+}
+
+type TxOptions struct {
+	Isolation IsolationLevel
+	ReadOnly  bool
+}
+
+// Querier is the part of the API shared by Database and Transaction.
+type Querier interface {
 	Exec(ctx context.Context, query string, args ...interface{}) error
 	Query(ctx context.Context, query string, args ...interface{}) (Rows, error)
 	QueryRow(ctx context.Context, query string, args ...interface{}) Row
-	BeginTx(ctx context.Context) (Transaction, error)
+}
+
+// CountingExecer is implemented by queriers that can report how many rows an
+// Exec affected.
+type CountingExecer interface {
+	ExecCount(ctx context.Context, query string, args ...interface{}) (int64, error)
+}
+
+type Database interface {
+	Querier
+	BeginTx(ctx context.Context, opts *TxOptions) (Transaction, error)
 	Close() error
 	Ping(ctx context.Context) error
 	RunMigrations() error
+	// MigrationVersion returns the schema version last migrated to and
+	// whether that migration failed halfway.
+	MigrationVersion(ctx context.Context) (uint, bool, error)
 
 	Connect(ctx context.Context) error
 }
 
 type Transaction interface {
-	Exec(ctx context.Context, query string, args ...interface{}) error
-	Query(ctx context.Context, query string, args ...interface{}) (Rows, error)
-	QueryRow(ctx context.Context, query string, args ...interface{}) Row
+	Querier
 	Commit() error
 	Rollback() error
 }
@@ -33,4 +88,4 @@ type Rows interface {
 
 type Row interface {
 	Scan(dest ...interface{}) error
-}
\ No newline at end of file
+}
@@ -4,10 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"denet/internal/store"
+	"errors"
 	"fmt"
 	"log"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -58,6 +59,14 @@ func (p *PostgresDatabase) Close() error {
 	return nil
 }
 
+// Stats reports the connection pool statistics.
+func (p *PostgresDatabase) Stats() sql.DBStats {
+	if p.db == nil {
+		return sql.DBStats{}
+	}
+	return p.db.Stats()
+}
+
 func (p *PostgresDatabase) Ping(ctx context.Context) error {
 	if p.db == nil {
 		return fmt.Errorf("database not connected")
@@ -66,11 +75,21 @@ func (p *PostgresDatabase) Ping(ctx context.Context) error {
 }
 
 func (p *PostgresDatabase) Exec(ctx context.Context, query string, args ...interface{}) error {
+	_, err := p.ExecCount(ctx, query, args...)
+	return err
+}
+
+func (p *PostgresDatabase) ExecCount(ctx context.Context, query string, args ...interface{}) (int64, error) {
 	if p.db == nil {
-		return fmt.Errorf("database not connected")
+		return 0, fmt.Errorf("database not connected")
 	}
-	_, err := p.db.ExecContext(ctx, query, args...)
-	return err
+	result, err := p.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, wrapError(err)
+	}
+	// Statements without a row count, like SAVEPOINT, report 0.
+	n, _ := result.RowsAffected()
+	return n, nil
 }
 
 func (p *PostgresDatabase) Query(ctx context.Context, query string, args ...interface{}) (store.Rows, error) {
@@ -79,7 +98,7 @@ func (p *PostgresDatabase) Query(ctx context.Context, query string, args ...inte
 	}
 	rows, err := p.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	return &PostgresRows{rows: rows}, nil
 }
@@ -92,11 +111,11 @@ func (p *PostgresDatabase) QueryRow(ctx context.Context, query string, args ...i
 	return &PostgresRow{row: row}
 }
 
-func (p *PostgresDatabase) BeginTx(ctx context.Context) (store.Transaction, error) {
+func (p *PostgresDatabase) BeginTx(ctx context.Context, opts *store.TxOptions) (store.Transaction, error) {
 	if p.db == nil {
 		return nil, fmt.Errorf("database not connected")
 	}
-	tx, err := p.db.BeginTx(ctx, nil)
+	tx, err := p.db.BeginTx(ctx, txOptions(opts))
 	if err != nil {
 		return nil, err
 	}
@@ -124,15 +143,37 @@ func (p *PostgresDatabase) RunMigrations() error {
 	return nil
 }
 
+func (p *PostgresDatabase) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	if p.db == nil {
+		return 0, false, fmt.Errorf("database not connected")
+	}
+	var version uint
+	var dirty bool
+	err := p.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	return version, dirty, nil
+}
+
 func (pt *PostgresTransaction) Exec(ctx context.Context, query string, args ...interface{}) error {
-	_, err := pt.tx.ExecContext(ctx, query, args...)
+	_, err := pt.ExecCount(ctx, query, args...)
 	return err
 }
 
+func (pt *PostgresTransaction) ExecCount(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	result, err := pt.tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, wrapError(err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
 func (pt *PostgresTransaction) Query(ctx context.Context, query string, args ...interface{}) (store.Rows, error) {
 	rows, err := pt.tx.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	return &PostgresRows{rows: rows}, nil
 }
@@ -143,7 +184,7 @@ func (pt *PostgresTransaction) QueryRow(ctx context.Context, query string, args
 }
 
 func (pt *PostgresTransaction) Commit() error {
-	return pt.tx.Commit()
+	return wrapError(pt.tx.Commit())
 }
 
 func (pt *PostgresTransaction) Rollback() error {
@@ -163,12 +204,62 @@ func (pr *PostgresRows) Scan(dest ...interface{}) error {
 }
 
 func (pr *PostgresRows) Err() error {
-	return pr.rows.Err()
+	return wrapError(pr.rows.Err())
 }
 
 func (pr *PostgresRow) Scan(dest ...interface{}) error {
 	if pr.row == nil {
 		return fmt.Errorf("row is nil")
 	}
-	return pr.row.Scan(dest...)
-}
\ No newline at end of file
+	return wrapError(pr.row.Scan(dest...))
+}
+
+func txOptions(opts *store.TxOptions) *sql.TxOptions {
+	if opts == nil {
+		return nil
+	}
+	txOpts := &sql.TxOptions{ReadOnly: opts.ReadOnly}
+	switch opts.Isolation {
+	case store.IsolationReadCommitted:
+		txOpts.Isolation = sql.LevelReadCommitted
+	case store.IsolationRepeatableRead:
+		txOpts.Isolation = sql.LevelRepeatableRead
+	case store.IsolationSerializable:
+		txOpts.Isolation = sql.LevelSerializable
+	}
+	return txOpts
+}
+
+// wrapError maps PostgreSQL error codes the repositories care about onto
+// store sentinels: retryable errors (SQLSTATE 40001 and 40P01) become
+// store.ErrSerializationFailure, 23505 becomes store.ErrUniqueViolation and
+// 23514 becomes store.ErrCheckViolation. The driver error stays reachable
+// through errors.As.
+func wrapError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40001", "40P01":
+			return &driverError{sentinel: store.ErrSerializationFailure, err: err}
+		case "23505":
+			return &driverError{sentinel: store.ErrUniqueViolation, err: err}
+		case "23514":
+			return &driverError{sentinel: store.ErrCheckViolation, err: err}
+		}
+	}
+	return err
+}
+
+// driverError tags a driver error with the store sentinel it maps to.
+type driverError struct {
+	sentinel error
+	err      error
+}
+
+func (e *driverError) Error() string {
+	return e.sentinel.Error() + ": " + e.err.Error()
+}
+
+func (e *driverError) Unwrap() []error {
+	return []error{e.sentinel, e.err}
+}
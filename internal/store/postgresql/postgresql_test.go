@@ -0,0 +1,43 @@
+package postgresql
+
+import (
+	"denet/internal/store"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestWrapError(t *testing.T) {
+	tests := []struct {
+		code pq.ErrorCode
+		want error
+	}{
+		{"40001", store.ErrSerializationFailure},
+		{"40P01", store.ErrSerializationFailure},
+		{"23505", store.ErrUniqueViolation},
+		{"23514", store.ErrCheckViolation},
+		{"23503", nil},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.code), func(t *testing.T) {
+			err := wrapError(&pq.Error{Code: tt.code, Constraint: "users_balance_non_negative"})
+
+			for _, sentinel := range []error{store.ErrSerializationFailure, store.ErrUniqueViolation, store.ErrCheckViolation} {
+				if errors.Is(err, sentinel) != (sentinel == tt.want) {
+					t.Errorf("errors.Is(%v, %v) = %v", err, sentinel, !(sentinel == tt.want))
+				}
+			}
+			// The driver error stays reachable for callers that need the
+			// constraint or detail.
+			var pqErr *pq.Error
+			if !errors.As(err, &pqErr) || pqErr.Constraint != "users_balance_non_negative" {
+				t.Errorf("errors.As(%v) did not find the driver error", err)
+			}
+		})
+	}
+
+	if err := wrapError(nil); err != nil {
+		t.Errorf("wrapError(nil) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,175 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"denet/internal/store"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// rowsAffectedKey records how many rows an Exec changed; semconv only has a
+// key for rows returned.
+const rowsAffectedKey = attribute.Key("db.response.affected_rows")
+
+// Database traces every statement run on the wrapped database, and on the
+// transactions it begins, as a client span carrying the SQL text and row
+// count. Arguments are not recorded. Everything else passes through.
+type Database struct {
+	store.Database
+}
+
+func NewDatabase(db store.Database) store.Database {
+	return &Database{Database: db}
+}
+
+func (d *Database) Exec(ctx context.Context, query string, args ...interface{}) error {
+	return exec(ctx, d.Database, query, args)
+}
+
+func (d *Database) Query(ctx context.Context, query string, args ...interface{}) (store.Rows, error) {
+	return runQuery(ctx, d.Database, query, args)
+}
+
+func (d *Database) QueryRow(ctx context.Context, query string, args ...interface{}) store.Row {
+	return queryRow(ctx, d.Database, query, args)
+}
+
+func (d *Database) BeginTx(ctx context.Context, opts *store.TxOptions) (store.Transaction, error) {
+	_, span := startStatement(ctx, "BEGIN")
+	tx, err := d.Database.BeginTx(ctx, opts)
+	End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{Transaction: tx, ctx: ctx}, nil
+}
+
+// transaction keeps the context it was begun with, since Commit and Rollback
+// take none.
+type transaction struct {
+	store.Transaction
+	ctx context.Context
+}
+
+func (t *transaction) Exec(ctx context.Context, query string, args ...interface{}) error {
+	return exec(ctx, t.Transaction, query, args)
+}
+
+func (t *transaction) Query(ctx context.Context, query string, args ...interface{}) (store.Rows, error) {
+	return runQuery(ctx, t.Transaction, query, args)
+}
+
+func (t *transaction) QueryRow(ctx context.Context, query string, args ...interface{}) store.Row {
+	return queryRow(ctx, t.Transaction, query, args)
+}
+
+func (t *transaction) Commit() error {
+	_, span := startStatement(t.ctx, "COMMIT")
+	err := t.Transaction.Commit()
+	End(span, err)
+	return err
+}
+
+func (t *transaction) Rollback() error {
+	_, span := startStatement(t.ctx, "ROLLBACK")
+	err := t.Transaction.Rollback()
+	End(span, err)
+	return err
+}
+
+func exec(ctx context.Context, q store.Querier, query string, args []interface{}) error {
+	ctx, span := startStatement(ctx, query)
+	var err error
+	if counting, ok := q.(store.CountingExecer); ok {
+		var n int64
+		n, err = counting.ExecCount(ctx, query, args...)
+		span.SetAttributes(rowsAffectedKey.Int64(n))
+	} else {
+		err = q.Exec(ctx, query, args...)
+	}
+	End(span, err)
+	return err
+}
+
+func runQuery(ctx context.Context, q store.Querier, query string, args []interface{}) (store.Rows, error) {
+	ctx, span := startStatement(ctx, query)
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		End(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func queryRow(ctx context.Context, q store.Querier, query string, args []interface{}) store.Row {
+	ctx, span := startStatement(ctx, query)
+	return &tracedRow{Row: q.QueryRow(ctx, query, args...), span: span}
+}
+
+// tracedRows counts the rows read and ends the span on Close.
+type tracedRows struct {
+	store.Rows
+	span  trace.Span
+	count int
+	done  bool
+}
+
+func (r *tracedRows) Next() bool {
+	if r.Rows.Next() {
+		r.count++
+		return true
+	}
+	return false
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	if !r.done {
+		r.done = true
+		r.span.SetAttributes(semconv.DBResponseReturnedRows(r.count))
+		End(r.span, r.Rows.Err())
+	}
+	return err
+}
+
+// tracedRow ends the span on Scan. A missing row is a result, not an error.
+type tracedRow struct {
+	store.Row
+	span trace.Span
+}
+
+func (r *tracedRow) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	count := 1
+	if err != nil {
+		count = 0
+	}
+	r.span.SetAttributes(semconv.DBResponseReturnedRows(count))
+	if errors.Is(err, sql.ErrNoRows) {
+		End(r.span, nil)
+	} else {
+		End(r.span, err)
+	}
+	return err
+}
+
+// startStatement names the span after the statement's leading keyword, as in
+// "SELECT" or "UPDATE", so that spans group by operation.
+func startStatement(ctx context.Context, query string) (context.Context, trace.Span) {
+	text := strings.Join(strings.Fields(query), " ")
+	operation, _, _ := strings.Cut(text, " ")
+	operation = strings.ToUpper(operation)
+	return Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(text),
+		),
+	)
+}
@@ -0,0 +1,176 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"denet/internal/store"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record installs a provider recording every span into the returned exporter
+// for the duration of the test.
+func record(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), Config{ServiceName: "test", SampleRatio: 1})
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestStartEnd(t *testing.T) {
+	exporter := record(t)
+
+	ctx, parent := Start(context.Background(), "parent")
+	if fields := LogFields(ctx); len(fields) != 2 {
+		t.Errorf("LogFields() = %v, want trace and span IDs", fields)
+	}
+	_, child := Start(ctx, "child")
+	End(child, errors.New("boom"))
+	End(parent, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	childSpan, parentSpan := spans[0], spans[1]
+	if childSpan.Parent.SpanID() != parentSpan.SpanContext.SpanID() {
+		t.Error("child span is not parented to the span on ctx")
+	}
+	if childSpan.Status.Code != codes.Error || childSpan.Status.Description != "boom" || len(childSpan.Events) != 1 {
+		t.Errorf("failed span status = %+v with %d events, want an error with the recorded error", childSpan.Status, len(childSpan.Events))
+	}
+	if parentSpan.Status.Code != codes.Unset {
+		t.Errorf("successful span status = %+v, want unset", parentSpan.Status)
+	}
+
+	if fields := LogFields(context.Background()); fields != nil {
+		t.Errorf("LogFields() without a trace = %v, want none", fields)
+	}
+}
+
+// fakeDatabase answers every statement without a server.
+type fakeDatabase struct {
+	store.Database
+	execErr error
+	rowErr  error
+}
+
+func (d *fakeDatabase) Exec(ctx context.Context, query string, args ...interface{}) error {
+	return d.execErr
+}
+
+func (d *fakeDatabase) ExecCount(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return 3, d.execErr
+}
+
+func (d *fakeDatabase) Query(ctx context.Context, query string, args ...interface{}) (store.Rows, error) {
+	return &fakeRows{left: 2}, nil
+}
+
+func (d *fakeDatabase) QueryRow(ctx context.Context, query string, args ...interface{}) store.Row {
+	return fakeRow{err: d.rowErr}
+}
+
+func (d *fakeDatabase) BeginTx(ctx context.Context, opts *store.TxOptions) (store.Transaction, error) {
+	return &fakeTransaction{fakeDatabase: d}, nil
+}
+
+type fakeTransaction struct{ *fakeDatabase }
+
+func (t *fakeTransaction) Commit() error   { return nil }
+func (t *fakeTransaction) Rollback() error { return nil }
+
+type fakeRows struct{ left int }
+
+func (r *fakeRows) Next() bool {
+	r.left--
+	return r.left >= 0
+}
+func (r *fakeRows) Scan(dest ...interface{}) error { return nil }
+func (r *fakeRows) Close() error                   { return nil }
+func (r *fakeRows) Err() error                     { return nil }
+
+type fakeRow struct{ err error }
+
+func (r fakeRow) Scan(dest ...interface{}) error { return r.err }
+
+func TestDatabase(t *testing.T) {
+	exporter := record(t)
+	fake := &fakeDatabase{rowErr: sql.ErrNoRows}
+	db := NewDatabase(fake)
+
+	ctx, request := Start(context.Background(), "request")
+	db.Exec(ctx, "update users\n   set balance = $1", 10)
+	rows, _ := db.Query(ctx, "SELECT id FROM users")
+	for rows.Next() {
+	}
+	rows.Close()
+	db.QueryRow(ctx, "SELECT id FROM users WHERE id = $1", "missing").Scan()
+
+	tx, _ := db.BeginTx(ctx, nil)
+	tx.QueryRow(ctx, "SELECT 1").Scan()
+	tx.Commit()
+
+	fake.execErr = errors.New("deadlock detected")
+	db.Exec(ctx, "DELETE FROM tokens")
+	request.End()
+
+	spans := exporter.GetSpans()
+	want := []struct {
+		name   string
+		text   string
+		rows   attribute.Key
+		count  int64
+		failed bool
+	}{
+		{"UPDATE", "update users set balance = $1", rowsAffectedKey, 3, false},
+		{"SELECT", "SELECT id FROM users", "db.response.returned_rows", 2, false},
+		// A missing row is not a failure.
+		{"SELECT", "SELECT id FROM users WHERE id = $1", "db.response.returned_rows", 0, false},
+		{"BEGIN", "BEGIN", "", 0, false},
+		{"SELECT", "SELECT 1", "db.response.returned_rows", 0, false},
+		{"COMMIT", "COMMIT", "", 0, false},
+		{"DELETE", "DELETE FROM tokens", rowsAffectedKey, 3, true},
+	}
+	if len(spans) != len(want)+1 {
+		t.Fatalf("recorded %d spans, want %d", len(spans), len(want)+1)
+	}
+	requestID := spans[len(spans)-1].SpanContext.SpanID()
+
+	for i, w := range want {
+		span := spans[i]
+		attrs := attributes(span)
+		if span.Name != w.name || attrs["db.query.text"].AsString() != w.text {
+			t.Errorf("span %d = %s %q, want %s %q", i, span.Name, attrs["db.query.text"].AsString(), w.name, w.text)
+		}
+		if w.rows != "" && attrs[w.rows].AsInt64() != w.count {
+			t.Errorf("span %d %s = %d, want %d", i, w.rows, attrs[w.rows].AsInt64(), w.count)
+		}
+		if (span.Status.Code == codes.Error) != w.failed {
+			t.Errorf("span %d status = %+v, want failed %v", i, span.Status, w.failed)
+		}
+		if span.Parent.SpanID() != requestID {
+			t.Errorf("span %d is not parented to the request", i)
+		}
+	}
+}
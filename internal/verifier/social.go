@@ -0,0 +1,82 @@
+package verifier
+
+import (
+	"context"
+	"denet/internal/apperror"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/social"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrSocialAccountNotLinked  = apperror.New("social_account_not_linked", http.StatusBadRequest, "Link your account on this platform before submitting the task")
+	ErrVerificationUnavailable = apperror.New("verification_unavailable", http.StatusServiceUnavailable, "Task verification is temporarily unavailable, try again later")
+)
+
+// AccountLookup finds the platform account a user linked.
+type AccountLookup interface {
+	Get(ctx context.Context, userID string, provider model.SocialProvider) (*model.SocialAccount, error)
+}
+
+// Membership verifies social tasks: the user's linked account on provider must
+// be a member of the target named by configKey in the task's
+// verification_config. Tasks without a target, accounts whose owner never
+// proved holding them, since anyone can link the ID of a known member, and
+// platform answers that settle nothing wait for manual review.
+type Membership struct {
+	Provider  model.SocialProvider
+	ConfigKey string
+	Checker   social.MembershipChecker
+	Accounts  AccountLookup
+}
+
+func (m *Membership) Verify(ctx context.Context, submission Submission) (model.TaskVerdict, error) {
+	account, err := m.Accounts.Get(ctx, submission.UserID, m.Provider)
+	if errors.Is(err, repository.ErrSocialAccountNotFound) {
+		return model.TaskVerdict{}, ErrSocialAccountNotLinked.WithDetails("provider: " + string(m.Provider))
+	}
+	if err != nil {
+		return model.TaskVerdict{}, err
+	}
+
+	if !account.Verified {
+		return model.TaskVerdict{
+			Status: model.TaskSubmissionPending,
+			Note:   string(m.Provider) + " account " + account.ExternalID + " is not verified as the user's",
+		}, nil
+	}
+
+	var config map[string]interface{}
+	_ = json.Unmarshal(submission.Task.VerificationConfig, &config)
+	target, _ := config[m.ConfigKey].(string)
+	if target == "" {
+		return model.TaskVerdict{
+			Status: model.TaskSubmissionPending,
+			Note:   "task has no " + m.ConfigKey + " to check automatically",
+		}, nil
+	}
+
+	member, err := m.Checker.IsMember(ctx, target, account.ExternalID)
+	if errors.Is(err, social.ErrUnavailable) {
+		return model.TaskVerdict{}, ErrVerificationUnavailable.Wrap(err)
+	}
+	if errors.Is(err, social.ErrInconclusive) {
+		return model.TaskVerdict{
+			Status: model.TaskSubmissionPending,
+			Note:   err.Error(),
+		}, nil
+	}
+	if err != nil {
+		return model.TaskVerdict{}, err
+	}
+	if !member {
+		return model.TaskVerdict{
+			Status: model.TaskSubmissionRejected,
+			Note:   string(m.Provider) + " account " + account.ExternalID + " is not a member of " + target,
+		}, nil
+	}
+	return model.TaskVerdict{Status: model.TaskSubmissionApproved}, nil
+}
@@ -0,0 +1,62 @@
+package verifier
+
+import (
+	"context"
+	"denet/internal/model"
+	"denet/internal/repository"
+	"denet/internal/social"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type fakeAccounts map[string]*model.SocialAccount
+
+func (f fakeAccounts) Get(ctx context.Context, userID string, provider model.SocialProvider) (*model.SocialAccount, error) {
+	account, ok := f[userID]
+	if !ok {
+		return nil, repository.ErrSocialAccountNotFound
+	}
+	return account, nil
+}
+
+type fakeChecker struct {
+	member bool
+	err    error
+}
+
+func (f fakeChecker) IsMember(ctx context.Context, target, externalID string) (bool, error) {
+	return f.member, f.err
+}
+
+func TestMembershipVerify(t *testing.T) {
+	accounts := fakeAccounts{
+		"alice": {UserID: "alice", Provider: model.SocialTwitter, ExternalID: "42", Verified: true},
+		"bob":   {UserID: "bob", Provider: model.SocialTwitter, ExternalID: "7"},
+	}
+	task := &model.Task{ID: "follow", VerificationConfig: []byte(`{"target_user_id":"100"}`)}
+
+	tests := []struct {
+		name       string
+		userID     string
+		checker    fakeChecker
+		wantStatus model.TaskSubmissionStatus
+		wantErr    error
+	}{
+		{"member", "alice", fakeChecker{member: true}, model.TaskSubmissionApproved, nil},
+		{"not a member", "alice", fakeChecker{}, model.TaskSubmissionRejected, nil},
+		{"inconclusive", "alice", fakeChecker{err: fmt.Errorf("%w: twitter: suspended", social.ErrInconclusive)}, model.TaskSubmissionPending, nil},
+		{"platform down", "alice", fakeChecker{err: fmt.Errorf("%w: timeout", social.ErrUnavailable)}, "", ErrVerificationUnavailable},
+		{"unverified account", "bob", fakeChecker{member: true}, model.TaskSubmissionPending, nil},
+		{"no account", "carol", fakeChecker{member: true}, "", ErrSocialAccountNotLinked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Membership{Provider: model.SocialTwitter, ConfigKey: "target_user_id", Checker: tt.checker, Accounts: accounts}
+			verdict, err := m.Verify(context.Background(), Submission{UserID: tt.userID, Task: task})
+			if !errors.Is(err, tt.wantErr) || verdict.Status != tt.wantStatus {
+				t.Errorf("Verify() = %+v, %v, want %q, %v", verdict, err, tt.wantStatus, tt.wantErr)
+			}
+		})
+	}
+}
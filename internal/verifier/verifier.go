@@ -0,0 +1,79 @@
+package verifier
+
+import (
+	"context"
+	"denet/internal/model"
+	"sort"
+	"sync"
+)
+
+// Submission is a user's claim to have done a task.
+type Submission struct {
+	UserID string
+	Task   *model.Task
+	Proof  string
+}
+
+// TaskVerifier decides whether a submission earns the task's points. A
+// pending verdict leaves the submission to a moderator. An error means no
+// decision could be made and the user should try again.
+type TaskVerifier interface {
+	Verify(ctx context.Context, submission Submission) (model.TaskVerdict, error)
+}
+
+// Func adapts a function to TaskVerifier.
+type Func func(ctx context.Context, submission Submission) (model.TaskVerdict, error)
+
+func (f Func) Verify(ctx context.Context, submission Submission) (model.TaskVerdict, error) {
+	return f(ctx, submission)
+}
+
+// Auto approves every submission.
+var Auto = Func(func(context.Context, Submission) (model.TaskVerdict, error) {
+	return model.TaskVerdict{Status: model.TaskSubmissionApproved}, nil
+})
+
+// Manual queues every submission for review.
+var Manual = Func(func(context.Context, Submission) (model.TaskVerdict, error) {
+	return model.TaskVerdict{Status: model.TaskSubmissionPending}, nil
+})
+
+// Registry maps a task's verification type to its verifier.
+type Registry struct {
+	mu        sync.RWMutex
+	verifiers map[string]TaskVerifier
+}
+
+// NewRegistry returns a registry holding the auto and manual verifiers.
+func NewRegistry() *Registry {
+	r := &Registry{verifiers: make(map[string]TaskVerifier)}
+	r.Register(model.VerificationAuto, Auto)
+	r.Register(model.VerificationManual, Manual)
+	return r
+}
+
+// Register adds or replaces the verifier for kind.
+func (r *Registry) Register(kind string, v TaskVerifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.verifiers[kind] = v
+}
+
+func (r *Registry) Get(kind string) (TaskVerifier, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.verifiers[kind]
+	return v, ok
+}
+
+// Kinds returns the registered verification types in sorted order.
+func (r *Registry) Kinds() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	kinds := make([]string, 0, len(r.verifiers))
+	for kind := range r.verifiers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
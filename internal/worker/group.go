@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// Group starts background jobs together and stops them together. Jobs must
+// return once their context is cancelled.
+type Group struct {
+	jobs   []func(ctx context.Context)
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// Add registers a job to run on Start.
+func (g *Group) Add(job func(ctx context.Context)) {
+	g.jobs = append(g.jobs, job)
+}
+
+// Start runs every job in its own goroutine.
+func (g *Group) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	g.cancel = cancel
+	for _, job := range g.jobs {
+		g.done.Add(1)
+		go func(job func(ctx context.Context)) {
+			defer g.done.Done()
+			job(ctx)
+		}(job)
+	}
+}
+
+// Stop cancels the jobs and waits for them to return, or for ctx to expire,
+// in which case ctx's error is returned and the jobs are left to finish on
+// their own.
+func (g *Group) Stop(ctx context.Context) error {
+	if g.cancel == nil {
+		return nil
+	}
+	g.cancel()
+	stopped := make(chan struct{})
+	go func() {
+		g.done.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"context"
+	"denet/internal/logging"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Periodic runs a job at a fixed interval until its context is cancelled.
+// A failed run is logged and retried on the next tick. Interval must be
+// positive.
+type Periodic struct {
+	Name     string
+	Interval time.Duration
+	Job      func(ctx context.Context) error
+	Logger   *zap.Logger
+	// RunOnStart runs the job once before the first tick.
+	RunOnStart bool
+
+	mu      sync.Mutex
+	running bool
+	lastErr error
+}
+
+var errNotRunning = errors.New("worker is not running")
+
+// Run executes the job on every tick, and right away when RunOnStart is set.
+// It blocks until ctx is done.
+func (p *Periodic) Run(ctx context.Context) {
+	ctx = logging.WithLogger(ctx, p.Logger.With(zap.String("worker", p.Name)))
+	p.setRunning(true)
+	defer p.setRunning(false)
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	if p.RunOnStart {
+		p.run(ctx)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.run(ctx)
+		}
+	}
+}
+
+func (p *Periodic) run(ctx context.Context) {
+	err := p.Job(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+	p.mu.Lock()
+	p.lastErr = err
+	p.mu.Unlock()
+	if err != nil {
+		logging.FromContext(ctx).Error("Background job failed", zap.Error(err))
+	}
+}
+
+// Check is a health check: it fails if the worker is not running or its
+// last run failed.
+func (p *Periodic) Check(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.running {
+		return errNotRunning
+	}
+	if p.lastErr != nil {
+		return fmt.Errorf("last run failed: %w", p.lastErr)
+	}
+	return nil
+}
+
+func (p *Periodic) setRunning(running bool) {
+	p.mu.Lock()
+	p.running = running
+	p.mu.Unlock()
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_point_transactions_user_created;
+ALTER TABLE users ALTER COLUMN balance DROP NOT NULL;
+DROP TABLE IF EXISTS point_transactions;
@@ -0,0 +1,22 @@
+CREATE TABLE point_transactions (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    delta INTEGER NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    source_type VARCHAR(20) NOT NULL,
+    source_id VARCHAR(36),
+    idempotency_key VARCHAR(255) UNIQUE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Existing balances become opening entries so that SUM(delta) matches users.balance.
+INSERT INTO point_transactions (id, user_id, delta, reason, source_type, idempotency_key)
+SELECT gen_random_uuid()::text, id, balance, 'opening balance', 'admin', 'opening-balance:' || id
+FROM users
+WHERE COALESCE(balance, 0) <> 0;
+
+UPDATE users SET balance = 0 WHERE balance IS NULL;
+ALTER TABLE users ALTER COLUMN balance SET NOT NULL;
+
+CREATE INDEX idx_point_transactions_user_created ON point_transactions(user_id, created_at DESC, id DESC);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_tasks_visible;
+ALTER TABLE tasks DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS published_at;
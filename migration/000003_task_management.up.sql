@@ -0,0 +1,7 @@
+ALTER TABLE tasks ADD COLUMN published_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN archived_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE tasks SET published_at = created_at, updated_at = created_at;
+
+CREATE INDEX idx_tasks_visible ON tasks(published_at) WHERE archived_at IS NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_role_audit_log_user_id;
+DROP TABLE IF EXISTS role_audit_log;
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
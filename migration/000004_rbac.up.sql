@@ -0,0 +1,49 @@
+CREATE TABLE roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT,
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE role_permissions (
+    role_name VARCHAR(50) NOT NULL,
+    permission VARCHAR(100) NOT NULL,
+    PRIMARY KEY (role_name, permission),
+    FOREIGN KEY (role_name) REFERENCES roles(name) ON DELETE CASCADE
+);
+
+CREATE TABLE user_roles (
+    user_id VARCHAR(36) NOT NULL,
+    role_name VARCHAR(50) NOT NULL,
+    granted_by VARCHAR(36),
+    granted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_name),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (role_name) REFERENCES roles(name) ON DELETE CASCADE
+);
+
+CREATE TABLE role_audit_log (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    role_name VARCHAR(50) NOT NULL,
+    action VARCHAR(10) NOT NULL,
+    actor_id VARCHAR(36),
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO roles (name, description, is_system) VALUES
+('user', 'Regular user', TRUE),
+('moderator', 'Can view other users', TRUE),
+('admin', 'Full access', TRUE);
+
+INSERT INTO role_permissions (role_name, permission) VALUES
+('moderator', 'users:read'),
+('admin', 'users:read'),
+('admin', 'tasks:manage'),
+('admin', 'roles:manage');
+
+INSERT INTO user_roles (user_id, role_name)
+SELECT id, 'user' FROM users;
+
+CREATE INDEX idx_role_audit_log_user_id ON role_audit_log(user_id, created_at DESC);
@@ -0,0 +1,7 @@
+DELETE FROM role_permissions WHERE permission = 'referrals:manage';
+DROP TABLE IF EXISTS referral_reward_rules;
+DROP TRIGGER IF EXISTS users_referrer_immutable ON users;
+DROP FUNCTION IF EXISTS prevent_referrer_change();
+DROP INDEX IF EXISTS idx_users_referrer_id;
+DROP INDEX IF EXISTS idx_users_referral_code;
+ALTER TABLE users DROP COLUMN IF EXISTS referral_code;
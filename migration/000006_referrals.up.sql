@@ -0,0 +1,42 @@
+ALTER TABLE users ADD COLUMN referral_code VARCHAR(32);
+UPDATE users SET referral_code = upper(substr(md5(random()::text || id), 1, 8));
+ALTER TABLE users ALTER COLUMN referral_code SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_users_referral_code ON users(lower(referral_code));
+CREATE INDEX idx_users_referrer_id ON users(referrer_id);
+
+-- Once set, referrer_id can only go back to NULL through ON DELETE SET NULL.
+CREATE FUNCTION prevent_referrer_change() RETURNS trigger AS $$
+BEGIN
+    IF OLD.referrer_id IS NOT NULL AND NEW.referrer_id IS NOT NULL AND NEW.referrer_id <> OLD.referrer_id THEN
+        RAISE EXCEPTION 'referrer already set';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_referrer_immutable
+    BEFORE UPDATE OF referrer_id ON users
+    FOR EACH ROW EXECUTE FUNCTION prevent_referrer_change();
+
+CREATE TABLE referral_reward_rules (
+    id VARCHAR(36) PRIMARY KEY,
+    event VARCHAR(20) NOT NULL,
+    level INTEGER NOT NULL DEFAULT 1,
+    milestone_points INTEGER,
+    points INTEGER NOT NULL DEFAULT 0,
+    percent NUMERIC(5, 2) NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (event IN ('signup', 'milestone', 'earning')),
+    CHECK (level >= 1),
+    CHECK (event <> 'milestone' OR milestone_points IS NOT NULL)
+);
+
+INSERT INTO referral_reward_rules (id, event, level, milestone_points, points, percent) VALUES
+('1', 'signup', 1, NULL, 100, 0),
+('2', 'milestone', 1, 500, 50, 0),
+('3', 'earning', 1, NULL, 0, 10),
+('4', 'earning', 2, NULL, 0, 5);
+
+INSERT INTO role_permissions (role_name, permission) VALUES ('admin', 'referrals:manage');
@@ -0,0 +1,6 @@
+DELETE FROM role_permissions WHERE permission = 'seasons:manage';
+DROP INDEX IF EXISTS idx_point_transactions_created_at;
+DROP INDEX IF EXISTS idx_season_results_rank;
+DROP INDEX IF EXISTS idx_seasons_ends_at;
+DROP TABLE IF EXISTS season_results;
+DROP TABLE IF EXISTS seasons;
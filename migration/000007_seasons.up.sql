@@ -0,0 +1,25 @@
+CREATE TABLE seasons (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    archived_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE TABLE season_results (
+    season_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    username VARCHAR(100) NOT NULL,
+    points INTEGER NOT NULL,
+    rank INTEGER NOT NULL,
+    PRIMARY KEY (season_id, user_id),
+    FOREIGN KEY (season_id) REFERENCES seasons(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_seasons_ends_at ON seasons(ends_at) WHERE archived_at IS NULL;
+CREATE INDEX idx_season_results_rank ON season_results(season_id, rank);
+CREATE INDEX idx_point_transactions_created_at ON point_transactions(created_at);
+
+INSERT INTO role_permissions (role_name, permission) VALUES ('admin', 'seasons:manage');
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_leaderboard;
+ALTER TABLE users DROP COLUMN IF EXISTS balance_reached_at;
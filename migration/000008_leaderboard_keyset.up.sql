@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN balance_reached_at TIMESTAMP;
+
+UPDATE users SET balance_reached_at = COALESCE(
+    (SELECT MAX(pt.created_at) FROM point_transactions pt WHERE pt.user_id = users.id),
+    users.created_at,
+    CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ALTER COLUMN balance_reached_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE users ALTER COLUMN balance_reached_at SET NOT NULL;
+
+CREATE INDEX idx_users_leaderboard ON users(balance DESC, balance_reached_at, id);
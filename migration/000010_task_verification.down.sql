@@ -0,0 +1,18 @@
+DELETE FROM role_permissions WHERE permission = 'tasks:review';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_balance_non_negative;
+DROP INDEX IF EXISTS idx_user_tasks_status;
+
+ALTER TABLE user_tasks ADD COLUMN completed BOOLEAN DEFAULT FALSE;
+UPDATE user_tasks SET completed = (status = 'approved');
+CREATE INDEX idx_user_tasks_completed ON user_tasks(completed);
+
+ALTER TABLE user_tasks DROP CONSTRAINT IF EXISTS user_tasks_status_check;
+ALTER TABLE user_tasks DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE user_tasks DROP COLUMN IF EXISTS reviewed_at;
+ALTER TABLE user_tasks DROP COLUMN IF EXISTS reviewed_by;
+ALTER TABLE user_tasks DROP COLUMN IF EXISTS review_note;
+ALTER TABLE user_tasks DROP COLUMN IF EXISTS proof;
+ALTER TABLE user_tasks DROP COLUMN IF EXISTS status;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS verification_config;
+ALTER TABLE tasks DROP COLUMN IF EXISTS verification_type;
@@ -0,0 +1,27 @@
+ALTER TABLE tasks ADD COLUMN verification_type VARCHAR(32) NOT NULL DEFAULT 'auto';
+ALTER TABLE tasks ADD COLUMN verification_config JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE user_tasks ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'pending';
+ALTER TABLE user_tasks ADD COLUMN proof TEXT;
+ALTER TABLE user_tasks ADD COLUMN review_note TEXT;
+ALTER TABLE user_tasks ADD COLUMN reviewed_by VARCHAR(36);
+ALTER TABLE user_tasks ADD COLUMN reviewed_at TIMESTAMP;
+ALTER TABLE user_tasks ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE user_tasks SET status = CASE WHEN completed THEN 'approved' ELSE 'pending' END, updated_at = created_at;
+
+ALTER TABLE user_tasks ADD CONSTRAINT user_tasks_status_check
+    CHECK (status IN ('pending', 'approved', 'rejected', 'revoked'));
+
+DROP INDEX IF EXISTS idx_user_tasks_completed;
+ALTER TABLE user_tasks DROP COLUMN completed;
+
+CREATE INDEX idx_user_tasks_status ON user_tasks(status, updated_at, id);
+
+-- Revoking an approval takes its points back, but never more than the user
+-- still holds.
+ALTER TABLE users ADD CONSTRAINT users_balance_non_negative CHECK (balance >= 0);
+
+INSERT INTO role_permissions (role_name, permission) VALUES
+('moderator', 'tasks:review'),
+('admin', 'tasks:review');
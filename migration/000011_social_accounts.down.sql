@@ -0,0 +1,2 @@
+UPDATE tasks SET verification_type = 'auto' WHERE verification_type IN ('telegram', 'twitter', 'discord');
+DROP TABLE IF EXISTS social_accounts;
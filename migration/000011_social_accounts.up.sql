@@ -0,0 +1,20 @@
+CREATE TABLE social_accounts (
+    user_id VARCHAR(36) NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    external_id VARCHAR(64) NOT NULL,
+    handle VARCHAR(64),
+    -- Set when the platform confirmed the user holds the account; tasks of
+    -- unverified accounts go to manual review.
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    linked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, provider),
+    UNIQUE (provider, external_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- The seeded social tasks are checked against their platform from now on.
+-- Until an admin sets the channel, account or guild to check in their
+-- verification_config, submissions wait for manual review.
+UPDATE tasks SET verification_type = 'telegram' WHERE id = '2';
+UPDATE tasks SET verification_type = 'twitter' WHERE id = '3';
+UPDATE tasks SET verification_type = 'discord' WHERE id = '4';
@@ -0,0 +1,5 @@
+-- Telegram-only users get placeholder credentials they cannot sign in with.
+UPDATE users SET email = id || '@users.invalid' WHERE email IS NULL;
+UPDATE users SET password_hash = '' WHERE password_hash IS NULL;
+ALTER TABLE users ALTER COLUMN password_hash SET NOT NULL;
+ALTER TABLE users ALTER COLUMN email SET NOT NULL;
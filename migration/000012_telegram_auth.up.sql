@@ -0,0 +1,3 @@
+-- Users signing in with Telegram have neither an email nor a password.
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN password_hash DROP NOT NULL;
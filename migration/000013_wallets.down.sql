@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_wallet_nonces_expires_at;
+DROP TABLE IF EXISTS wallet_nonces;
+DROP INDEX IF EXISTS idx_wallets_primary;
+DROP INDEX IF EXISTS idx_wallets_user_id;
+DROP TABLE IF EXISTS wallets;
@@ -0,0 +1,21 @@
+CREATE TABLE wallets (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    address VARCHAR(42) NOT NULL UNIQUE,
+    chain_id BIGINT NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    verified_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_wallets_user_id ON wallets(user_id, created_at);
+CREATE UNIQUE INDEX idx_wallets_primary ON wallets(user_id) WHERE is_primary;
+
+-- Nonces handed out for Sign-In with Ethereum messages; each is used once.
+CREATE TABLE wallet_nonces (
+    nonce VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_wallet_nonces_expires_at ON wallet_nonces(expires_at);
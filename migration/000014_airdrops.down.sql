@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE permission = 'airdrops:manage';
+DROP INDEX IF EXISTS idx_airdrops_snapshot_at;
+DROP TABLE IF EXISTS airdrop_claims;
+DROP TABLE IF EXISTS airdrops;
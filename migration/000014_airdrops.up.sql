@@ -0,0 +1,27 @@
+CREATE TABLE airdrops (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    snapshot_at TIMESTAMP NOT NULL,
+    units_per_point NUMERIC(78, 0) NOT NULL,
+    merkle_root VARCHAR(66) NOT NULL,
+    total_amount NUMERIC(78, 0) NOT NULL,
+    recipients INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One row per recipient; proof is the JSON array of sibling hashes.
+CREATE TABLE airdrop_claims (
+    airdrop_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    address VARCHAR(42) NOT NULL,
+    points BIGINT NOT NULL,
+    amount NUMERIC(78, 0) NOT NULL,
+    proof JSONB NOT NULL,
+    PRIMARY KEY (airdrop_id, user_id),
+    FOREIGN KEY (airdrop_id) REFERENCES airdrops(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_airdrops_snapshot_at ON airdrops(snapshot_at DESC, created_at DESC);
+
+INSERT INTO role_permissions (role_name, permission) VALUES ('admin', 'airdrops:manage');
@@ -0,0 +1,6 @@
+DELETE FROM role_permissions WHERE permission = 'rewards:manage';
+DROP INDEX IF EXISTS idx_reward_orders_status;
+DROP INDEX IF EXISTS idx_reward_orders_user_reward;
+DROP INDEX IF EXISTS idx_reward_orders_user;
+DROP TABLE IF EXISTS reward_orders;
+DROP TABLE IF EXISTS rewards;
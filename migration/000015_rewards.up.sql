@@ -0,0 +1,39 @@
+CREATE TABLE rewards (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    cost INTEGER NOT NULL,
+    stock INTEGER,
+    per_user_limit INTEGER,
+    available_from TIMESTAMP,
+    available_until TIMESTAMP,
+    archived_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (cost > 0),
+    CHECK (stock >= 0),
+    CHECK (per_user_limit > 0),
+    CHECK (available_until > available_from)
+);
+
+CREATE TABLE reward_orders (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    reward_id VARCHAR(36) NOT NULL,
+    cost INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    note TEXT,
+    handled_by VARCHAR(36),
+    handled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (reward_id) REFERENCES rewards(id),
+    CHECK (status IN ('pending', 'fulfilled', 'cancelled'))
+);
+
+CREATE INDEX idx_reward_orders_user ON reward_orders(user_id, created_at DESC, id DESC);
+CREATE INDEX idx_reward_orders_user_reward ON reward_orders(user_id, reward_id) WHERE status <> 'cancelled';
+CREATE INDEX idx_reward_orders_status ON reward_orders(status, created_at, id);
+
+INSERT INTO role_permissions (role_name, permission) VALUES ('admin', 'rewards:manage');
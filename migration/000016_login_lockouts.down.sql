@@ -0,0 +1,2 @@
+DELETE FROM role_permissions WHERE permission = 'users:unlock';
+DROP TABLE IF EXISTS login_lockouts;
@@ -0,0 +1,13 @@
+CREATE TABLE login_lockouts (
+    user_id VARCHAR(36) PRIMARY KEY,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP,
+    lockouts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (failed_attempts >= 0),
+    CHECK (lockouts >= 0)
+);
+
+INSERT INTO role_permissions (role_name, permission) VALUES ('admin', 'users:unlock');